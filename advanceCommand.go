@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+var (
+	errNoStateProofs            = errors.New("no state proofs given, use -fixtures or pass state proof response files")
+	errStateProofIntervalGap    = errors.New("state proof attests to an interval later than the next expected interval")
+	errStateProofIntervalLength = errors.New("state proof does not attest to exactly one interval")
+)
+
+// stateProofSource is a state proof response, along with a description of where it was taken from.
+type stateProofSource struct {
+	source   string
+	response models.StateProof
+}
+
+// advancedInterval describes the outcome of ingesting a single state proof.
+type advancedInterval struct {
+	Source             string `json:"source"`
+	FirstAttestedRound uint64 `json:"first_attested_round"`
+	LastAttestedRound  uint64 `json:"last_attested_round"`
+	// Outcome is either "ingested", or "skipped" for state proofs attesting to intervals the oracle already ingested.
+	Outcome string `json:"outcome"`
+}
+
+// advanceResult describes the state proofs ingested by the advance subcommand, and the oracle's resulting state.
+type advanceResult struct {
+	Intervals []advancedInterval `json:"intervals"`
+	// Error describes the state proof that failed to be ingested, if any.
+	Error  string        `json:"error,omitempty"`
+	Status *statusResult `json:"status"`
+}
+
+func (r *advanceResult) writeText(output io.Writer) {
+	for _, interval := range r.Intervals {
+		fmt.Fprintf(output, "%s: rounds %d-%d %s\n", interval.Source, interval.FirstAttestedRound, interval.LastAttestedRound,
+			interval.Outcome)
+	}
+	r.Status.writeText(output)
+}
+
+// readStateProofResponseFile reads a state proof response, whose format is determined by its file extension.
+// Parameters:
+// stateProofFile - the path of the state proof response.
+func readStateProofResponseFile(stateProofFile string) (models.StateProof, error) {
+	var stateProofResponse models.StateProof
+	format, err := encodedassets.GetEncodingFormat(stateProofFile)
+	if err != nil {
+		return models.StateProof{}, fmt.Errorf("failed to read state proof %s: %w", stateProofFile, err)
+	}
+
+	encodedResponse, err := os.ReadFile(stateProofFile)
+	if err != nil {
+		return models.StateProof{}, err
+	}
+
+	err = encodedassets.DecodeResponse(encodedResponse, format, &stateProofResponse)
+	if err != nil {
+		return models.StateProof{}, fmt.Errorf("failed to decode state proof %s: %w", stateProofFile, err)
+	}
+
+	return stateProofResponse, nil
+}
+
+// advanceOracle ingests the given state proofs in order. State proofs attesting to intervals the oracle already
+// ingested are skipped, which allows ingesting the same source repeatedly as it grows. It returns the outcome of
+// every state proof processed, even if ingesting a later state proof failed.
+// Parameters:
+// oracleInstance - the oracle to advance.
+// stateProofs - the state proofs to ingest, ordered by round.
+func advanceOracle(oracleInstance *oracle.Oracle, stateProofs []stateProofSource) ([]advancedInterval, error) {
+	history := oracleInstance.BlockIntervalCommitmentHistory
+
+	intervals := make([]advancedInterval, 0, len(stateProofs))
+	for _, stateProof := range stateProofs {
+		message := stateProof.response.Message
+		interval := advancedInterval{
+			Source:             stateProof.source,
+			FirstAttestedRound: message.Firstattestedround,
+			LastAttestedRound:  message.Lastattestedround,
+		}
+
+		if message.Lastattestedround-message.Firstattestedround+1 != history.IntervalSize {
+			return intervals, fmt.Errorf("%s: %w", stateProof.source, errStateProofIntervalLength)
+		}
+
+		// The oracle assumes every state proof attests to the interval following the previous one's, so we make sure
+		// of it before advancing.
+		nextFirstAttestedRound := history.FirstAttestedRound + history.NextInterval*history.IntervalSize
+		if message.Firstattestedround < nextFirstAttestedRound {
+			interval.Outcome = "skipped"
+			intervals = append(intervals, interval)
+			continue
+		}
+		if message.Firstattestedround > nextFirstAttestedRound {
+			return intervals, fmt.Errorf("%s: %w", stateProof.source, errStateProofIntervalGap)
+		}
+
+		stateProofMessage, decodedStateProof, err := encodedassets.ParseStateProofResponse(stateProof.response)
+		if err != nil {
+			return intervals, fmt.Errorf("%s: %w", stateProof.source, err)
+		}
+
+		err = oracleInstance.AdvanceState(decodedStateProof, stateProofMessage)
+		if err != nil {
+			return intervals, fmt.Errorf("%s: %w", stateProof.source, err)
+		}
+
+		interval.Outcome = "ingested"
+		intervals = append(intervals, interval)
+	}
+
+	return intervals, nil
+}
+
+// setupAdvanceCommand sets up the advance subcommand, which advances the oracle's state using the state proofs in a
+// fixture bundle, and using state proof response files given as arguments, in that order.
+func setupAdvanceCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	flags.StringVar(&config.FixturesFile, "fixtures", "", "path of a fixture bundle whose state proofs to ingest")
+
+	return func(arguments []string) (commandResult, error) {
+		if config.FixturesFile == "" && len(arguments) == 0 {
+			return nil, errNoStateProofs
+		}
+
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var stateProofs []stateProofSource
+		if config.FixturesFile != "" {
+			fixtureBundle, err := loadFixtureBundle(config.FixturesFile)
+			if err != nil {
+				return nil, err
+			}
+
+			for i, stateProofResponse := range fixtureBundle.StateProofs {
+				stateProofs = append(stateProofs, stateProofSource{
+					source:   fmt.Sprintf("%s#state_proofs[%d]", config.FixturesFile, i),
+					response: stateProofResponse,
+				})
+			}
+		}
+
+		for _, stateProofFile := range arguments {
+			stateProofResponse, err := readStateProofResponseFile(stateProofFile)
+			if err != nil {
+				return nil, err
+			}
+
+			stateProofs = append(stateProofs, stateProofSource{source: stateProofFile, response: stateProofResponse})
+		}
+
+		intervals, advanceErr := advanceOracle(oracleInstance, stateProofs)
+
+		// We save the state even if a state proof failed, so that the ones ingested before it are kept.
+		err = saveOracle(config, oracleInstance)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &advanceResult{Intervals: intervals, Status: getStatus(config, oracleInstance)}
+		if advanceErr != nil {
+			result.Error = advanceErr.Error()
+		}
+
+		return result, advanceErr
+	}
+}
@@ -0,0 +1,324 @@
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// AttestationVersion is the version of the attestation format produced by this package. Attestations of any other
+// version are rejected, so that changes to the format are never silently misinterpreted.
+const AttestationVersion = 1
+
+// KeySetVersion is the version of the key set format.
+const KeySetVersion = 1
+
+// VerifierVersion is the version of the transaction verification logic attestations are produced with. It must be
+// incremented whenever the rules a transaction is verified by change, so that consumers can tell which rules an
+// attestation vouches for.
+const VerifierVersion = 1
+
+// AttestationPrefix is prepended to the canonical msgpack encoded attestation when signing it, so that signatures
+// over attestations can never be mistaken for signatures over anything else.
+var AttestationPrefix = []byte("AT")
+
+var (
+	ErrUnsupportedAttestationVersion = errors.New("attestation version is unsupported")
+	ErrUnsupportedKeySetVersion      = errors.New("key set version is unsupported")
+	ErrUnknownKey                    = errors.New("attestation was signed by a key missing from the key set")
+	ErrKeyNotValidForRound           = errors.New("attestation key is not valid for the attested round")
+	ErrInvalidSignature              = errors.New("attestation signature is invalid")
+	ErrInvalidPublicKey              = errors.New("public key is not a valid ed25519 public key")
+	ErrKeyIDMismatch                 = errors.New("key ID does not match the public key")
+	ErrDuplicateKeyID                = errors.New("key set holds several keys with the same ID")
+)
+
+// Attestation is a statement by a light client operator that a transaction occurred, as established by verifying it
+// using the operator's Oracle.
+type Attestation struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// Version is the version of the attestation format.
+	Version uint64 `codec:"v"`
+	// Network is the hash of the genesis block of the network the transaction was confirmed on.
+	Network types.Digest `codec:"net"`
+	// Round is the round in which the transaction was confirmed.
+	Round types.Round `codec:"rnd"`
+	// TransactionHash is the transaction's Sha256 hash, see MatchesTransaction.
+	TransactionHash types.Digest `codec:"txh"`
+	// Interval is the index of the interval the round belongs to.
+	Interval uint64 `codec:"int"`
+	// IntervalCommitment is the block interval commitment the transaction was verified against.
+	IntervalCommitment types.Digest `codec:"ic"`
+	// VerifierVersion is the version of the verification logic the transaction was verified with.
+	VerifierVersion uint64 `codec:"vv"`
+	// KeyID identifies the key the attestation is signed with, see GetKeyID.
+	KeyID string `codec:"kid"`
+}
+
+// MatchesTransaction returns whether the attestation attests to the given transaction.
+// Attestations identify transactions by their Sha256 hash, Sha256("TX" || msgpack(transaction)), rather than by
+// their ID, which is the base32 encoding of Sha512_256("TX" || msgpack(transaction)). The hash is what the transaction
+// proof commits to, so an attestation cannot vouch for an ID, which cannot be derived from the hash. Consumers looking
+// up a transaction by its ID must obtain the transaction, check that crypto.TransactionIDString returns the ID for it,
+// and then check that the attestation matches it.
+// Parameters:
+// transaction - the transaction, including its genesis ID and hash.
+func (a *Attestation) MatchesTransaction(transaction types.Transaction) bool {
+	return a.TransactionHash == transactionverifier.GetTransactionHash(transaction) &&
+		a.Network == transaction.GenesisHash
+}
+
+// SignedAttestation is an Attestation along with its operator's signature. It has canonical msgpack and JSON
+// encodings, which makes it suitable as a wire format.
+type SignedAttestation struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	Attestation Attestation `codec:"att"`
+	// Signature is the ed25519 signature of AttestationPrefix || msgpack(Attestation).
+	Signature []byte `codec:"sig"`
+}
+
+// PublishedKey is an operator's public key, along with the rounds it may attest to.
+type PublishedKey struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// KeyID identifies the key, see GetKeyID.
+	KeyID string `codec:"id"`
+	// PublicKey is the ed25519 public key.
+	PublicKey []byte `codec:"pk"`
+	// FirstRound is the first round the key may attest to.
+	FirstRound types.Round `codec:"first"`
+	// LastRound is the last round the key may attest to, or 0 if the key has not been rotated out yet.
+	LastRound types.Round `codec:"last"`
+}
+
+// KeySet is the set of keys an operator publishes, which attestations are verified against. Keys are rotated by
+// publishing a new key whose FirstRound follows the previous key's LastRound, so that attestations signed by the
+// previous key remain verifiable. A compromised key is revoked by removing it from the set altogether.
+type KeySet struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// Version is the version of the key set format.
+	Version uint64         `codec:"v"`
+	Keys    []PublishedKey `codec:"keys"`
+}
+
+// GetKeyID returns the ID identifying a public key, which is the hex encoding of the first 8 bytes of
+// Sha256(publicKey).
+// Parameters:
+// publicKey - the ed25519 public key.
+func GetKeyID(publicKey ed25519.PublicKey) string {
+	keyHash := sha256.Sum256(publicKey)
+	return hex.EncodeToString(keyHash[:8])
+}
+
+// getSigningData returns the data an attestation's signature is computed over, which is of the form
+// "AT" || msgpack(attestation).
+// Parameters:
+// attestation - the attestation to sign or verify.
+func getSigningData(attestation *Attestation) []byte {
+	encodedAttestation := msgpack.Encode(attestation)
+
+	signingData := make([]byte, 0, len(AttestationPrefix)+len(encodedAttestation))
+	signingData = append(signingData, AttestationPrefix...)
+	return append(signingData, encodedAttestation...)
+}
+
+// Attester produces signed attestations of transactions it verifies using an Oracle.
+type Attester struct {
+	oracle     *oracle.Oracle
+	signingKey ed25519.PrivateKey
+	keyID      string
+}
+
+// InitializeAttester initializes an Attester using an Oracle and the operator's signing key.
+// Parameters:
+// oracleInstance - the Oracle used for verification.
+// signingKey - the operator's ed25519 private key. Its public key must be published in the operator's KeySet.
+func InitializeAttester(oracleInstance *oracle.Oracle, signingKey ed25519.PrivateKey) *Attester {
+	return &Attester{
+		oracle:     oracleInstance,
+		signingKey: signingKey,
+		keyID:      GetKeyID(signingKey.Public().(ed25519.PublicKey)),
+	}
+}
+
+// KeyID returns the ID of the Attester's signing key.
+func (a *Attester) KeyID() string {
+	return a.keyID
+}
+
+// PublicKey returns the Attester's public key.
+func (a *Attester) PublicKey() ed25519.PublicKey {
+	return a.signingKey.Public().(ed25519.PublicKey)
+}
+
+// Attest verifies an inclusion proof bundle's transaction using the Oracle and, if verification succeeds, returns a
+// signed attestation of the transaction.
+// Parameters:
+// bundle - the bundle proving the transaction's occurrence.
+func (a *Attester) Attest(bundle *inclusionbundle.InclusionProofBundle) (*SignedAttestation, error) {
+	err := bundle.Verify(a.oracle)
+	if err != nil {
+		return nil, err
+	}
+
+	// The bundle was verified, so the Oracle holds the commitment covering its round.
+	interval, err := a.oracle.BlockIntervalCommitmentHistory.GetCoveringInterval(bundle.Round)
+	if err != nil {
+		return nil, err
+	}
+
+	intervalCommitment, err := a.oracle.GetStateProofCommitment(bundle.Round)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation := Attestation{
+		Version:            AttestationVersion,
+		Network:            bundle.GenesisHash,
+		Round:              bundle.Round,
+		TransactionHash:    bundle.TransactionHash,
+		Interval:           interval,
+		IntervalCommitment: intervalCommitment,
+		VerifierVersion:    VerifierVersion,
+		KeyID:              a.keyID,
+	}
+
+	return &SignedAttestation{
+		Attestation: attestation,
+		Signature:   ed25519.Sign(a.signingKey, getSigningData(&attestation)),
+	}, nil
+}
+
+// VerifyAttestation verifies that an attestation is signed by a key in the operator's key set that is valid for the
+// attested round. It does not require an Oracle. Callers must still check that the attestation's fields, e.g. its
+// network, round and transaction hash, are the ones they expect.
+// Parameters:
+// signedAttestation - the attestation to verify.
+// keySet - the key set published by the operator.
+func VerifyAttestation(signedAttestation *SignedAttestation, keySet *KeySet) error {
+	attestation := &signedAttestation.Attestation
+	if attestation.Version != AttestationVersion {
+		return ErrUnsupportedAttestationVersion
+	}
+	if keySet.Version != KeySetVersion {
+		return ErrUnsupportedKeySetVersion
+	}
+
+	for _, key := range keySet.Keys {
+		if key.KeyID != attestation.KeyID {
+			continue
+		}
+
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return ErrInvalidPublicKey
+		}
+		// We make sure the key set is consistent, so that a key is never used under another key's ID.
+		if GetKeyID(key.PublicKey) != key.KeyID {
+			return ErrKeyIDMismatch
+		}
+
+		if attestation.Round < key.FirstRound || (key.LastRound != 0 && attestation.Round > key.LastRound) {
+			return ErrKeyNotValidForRound
+		}
+
+		if !ed25519.Verify(key.PublicKey, getSigningData(attestation), signedAttestation.Signature) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	}
+
+	return ErrUnknownKey
+}
+
+// EncodeMsgpack returns the signed attestation's canonical msgpack encoding.
+func (a *SignedAttestation) EncodeMsgpack() []byte {
+	return msgpack.Encode(a)
+}
+
+// EncodeJSON returns the signed attestation's canonical JSON encoding. Byte fields are encoded using base64.
+func (a *SignedAttestation) EncodeJSON() []byte {
+	return json.Encode(a)
+}
+
+// DecodeMsgpack decodes a signed attestation from its msgpack encoding. Unknown versions are rejected.
+// Parameters:
+// encodedAttestation - the msgpack encoded signed attestation.
+func DecodeMsgpack(encodedAttestation []byte) (*SignedAttestation, error) {
+	var signedAttestation SignedAttestation
+	err := msgpack.Decode(encodedAttestation, &signedAttestation)
+	if err != nil {
+		return nil, err
+	}
+
+	if signedAttestation.Attestation.Version != AttestationVersion {
+		return nil, ErrUnsupportedAttestationVersion
+	}
+
+	return &signedAttestation, nil
+}
+
+// DecodeJSON decodes a signed attestation from its JSON encoding. Unknown versions are rejected.
+// Parameters:
+// encodedAttestation - the JSON encoded signed attestation.
+func DecodeJSON(encodedAttestation []byte) (*SignedAttestation, error) {
+	var signedAttestation SignedAttestation
+	err := json.Decode(encodedAttestation, &signedAttestation)
+	if err != nil {
+		return nil, err
+	}
+
+	if signedAttestation.Attestation.Version != AttestationVersion {
+		return nil, ErrUnsupportedAttestationVersion
+	}
+
+	return &signedAttestation, nil
+}
+
+// EncodeJSON returns the key set's JSON encoding, which is the format operators publish it in.
+func (s *KeySet) EncodeJSON() []byte {
+	return json.Encode(s)
+}
+
+// DecodeKeySet decodes a key set from its JSON encoding, and checks that it is well formed.
+// Parameters:
+// encodedKeySet - the JSON encoded key set.
+func DecodeKeySet(encodedKeySet []byte) (*KeySet, error) {
+	var keySet KeySet
+	err := json.Decode(encodedKeySet, &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	if keySet.Version != KeySetVersion {
+		return nil, ErrUnsupportedKeySetVersion
+	}
+
+	keyIDs := make(map[string]bool)
+	for _, key := range keySet.Keys {
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPublicKey
+		}
+		if GetKeyID(key.PublicKey) != key.KeyID {
+			return nil, ErrKeyIDMismatch
+		}
+		if keyIDs[key.KeyID] {
+			return nil, ErrDuplicateKeyID
+		}
+		keyIDs[key.KeyID] = true
+	}
+
+	return &keySet, nil
+}
@@ -0,0 +1,416 @@
+package attestation
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// testAttester is an Attester whose oracle was advanced using the fixture bundle's state proof, along with a bundle
+// it can attest to and a key set holding its key.
+type testAttester struct {
+	*Attester
+	bundle *inclusionbundle.InclusionProofBundle
+	keySet *KeySet
+}
+
+func initializeTestAttester(t *testing.T) testAttester {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound,
+		fixtureBundle.Network.IntervalSize, fixtureBundle.Genesis.VotersCommitment,
+		fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	message, stateProof, err := encodedassets.ParseStateProofResponse(fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = oracleInstance.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attester := InitializeAttester(oracleInstance, generateSigningKey(t))
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		return testAttester{
+			Attester: attester,
+			bundle: inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID,
+				transactionCase.TransactionProofResponse, transactionCase.LightBlockHeaderProofResponse,
+				transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed),
+			keySet: &KeySet{
+				Version: KeySetVersion,
+				Keys:    []PublishedKey{getPublishedKey(attester.PublicKey(), 0, 0)},
+			},
+		}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return testAttester{}
+}
+
+func generateSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey
+}
+
+// getPublishedKey returns a PublishedKey for a public key, valid for the given rounds.
+func getPublishedKey(publicKey ed25519.PublicKey, firstRound types.Round, lastRound types.Round) PublishedKey {
+	return PublishedKey{KeyID: GetKeyID(publicKey), PublicKey: publicKey, FirstRound: firstRound, LastRound: lastRound}
+}
+
+// attest attests to the test bundle, failing the test if attesting fails.
+func (a testAttester) attest(t *testing.T) *SignedAttestation {
+	t.Helper()
+
+	signedAttestation, err := a.Attest(a.bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signedAttestation
+}
+
+func TestAttestAndVerify(t *testing.T) {
+	attester := initializeTestAttester(t)
+	signedAttestation := attester.attest(t)
+
+	attestation := signedAttestation.Attestation
+	if attestation.Version != AttestationVersion || attestation.VerifierVersion != VerifierVersion ||
+		attestation.KeyID != attester.KeyID() || attestation.Network != attester.bundle.GenesisHash ||
+		attestation.Round != attester.bundle.Round || attestation.TransactionHash != attester.bundle.TransactionHash ||
+		attestation.Interval != 0 {
+		t.Fatalf("unexpected attestation: %+v", attestation)
+	}
+
+	err := VerifyAttestation(signedAttestation, attester.keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAttestRequiresVerification(t *testing.T) {
+	attester := initializeTestAttester(t)
+	tampered := *attester.bundle
+	tampered.Seed[0] ^= 1
+
+	_, err := attester.Attest(&tampered)
+	if !errors.Is(err, transactionverifier.ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", transactionverifier.ErrRootMismatch, err)
+	}
+}
+
+func TestMatchesTransaction(t *testing.T) {
+	genesisHash := types.Digest{1, 2, 3}
+	transaction := types.Transaction{
+		Type: types.PaymentTx,
+		Header: types.Header{
+			Sender:      types.Address{1},
+			Fee:         1000,
+			FirstValid:  1,
+			LastValid:   1001,
+			GenesisID:   "testnet-v1.0",
+			GenesisHash: genesisHash,
+		},
+		PaymentTxnFields: types.PaymentTxnFields{Receiver: types.Address{2}, Amount: 5},
+	}
+
+	// The transaction's hash and ID only differ in their hash function.
+	encodedTransaction := append([]byte("TX"), msgpack.Encode(transaction)...)
+	transactionHash := transactionverifier.GetTransactionHash(transaction)
+	if transactionHash != sha256.Sum256(encodedTransaction) {
+		t.Fatalf("expected %x, got %x", sha256.Sum256(encodedTransaction), transactionHash)
+	}
+	transactionIDHash := sha512.Sum512_256(encodedTransaction)
+	expectedTransactionID := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(transactionIDHash[:])
+	if crypto.TransactionIDString(transaction) != expectedTransactionID {
+		t.Fatalf("expected %s, got %s", expectedTransactionID, crypto.TransactionIDString(transaction))
+	}
+
+	// The transaction is confirmed in the first round of an interval the oracle holds the commitment of.
+	transactionProofBuilder, err := transactionverifier.BuildTransactionProofs(transactionverifier.Sha256HashType,
+		[]transactionverifier.BlockTransaction{{TransactionHash: transactionHash, StibHash: types.Digest{4}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactionProof, err := transactionProofBuilder.GetTransactionProof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lightBlockHeaders := make([]types.LightBlockHeader, 8)
+	for i := range lightBlockHeaders {
+		lightBlockHeaders[i] = types.LightBlockHeader{RoundNumber: types.Round(9 + i), GenesisHash: genesisHash}
+	}
+	lightBlockHeaders[0].Sha256TxnCommitment = transactionProofBuilder.Root()
+	lightBlockHeaderProofBuilder, err := transactionverifier.BuildLightBlockHeaderProofs(lightBlockHeaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lightBlockHeaderProof, err := lightBlockHeaderProofBuilder.GetLightBlockHeaderProof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(9, 8, nil, 0, 10)
+	oracleInstance.BlockIntervalCommitmentHistory.InsertCommitment(lightBlockHeaderProofBuilder.Root())
+	bundle := inclusionbundle.InitializeInclusionProofBundle(transactionHash, transactionProof, lightBlockHeaderProof,
+		9, genesisHash, types.Seed{})
+	signedAttestation, err := InitializeAttester(oracleInstance, generateSigningKey(t)).Attest(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestation := signedAttestation.Attestation
+
+	otherNetwork := transaction
+	otherNetwork.GenesisHash = types.Digest{4, 5, 6}
+	otherAmount := transaction
+	otherAmount.Amount++
+	otherNote := transaction
+	otherNote.Note = []byte{1}
+
+	testCases := map[string]struct {
+		transaction types.Transaction
+		expected    bool
+	}{
+		"attested transaction": {transaction, true},
+		"other network":        {otherNetwork, false},
+		"other amount":         {otherAmount, false},
+		"other note":           {otherNote, false},
+	}
+
+	for name, testCase := range testCases {
+		if attestation.MatchesTransaction(testCase.transaction) != testCase.expected {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expected, !testCase.expected)
+		}
+	}
+}
+
+func TestVerifyAttestationRejectsTampering(t *testing.T) {
+	attester := initializeTestAttester(t)
+
+	testCases := map[string]func(signed *SignedAttestation){
+		"round":            func(signed *SignedAttestation) { signed.Attestation.Round++ },
+		"transaction hash": func(signed *SignedAttestation) { signed.Attestation.TransactionHash[0] ^= 1 },
+		"network":          func(signed *SignedAttestation) { signed.Attestation.Network[0] ^= 1 },
+		"interval":         func(signed *SignedAttestation) { signed.Attestation.Interval++ },
+		"interval commitment": func(signed *SignedAttestation) {
+			signed.Attestation.IntervalCommitment[0] ^= 1
+		},
+		"verifier version": func(signed *SignedAttestation) { signed.Attestation.VerifierVersion++ },
+		"signature":        func(signed *SignedAttestation) { signed.Signature[0] ^= 1 },
+	}
+
+	for name, tamper := range testCases {
+		t.Run(name, func(t *testing.T) {
+			signedAttestation := attester.attest(t)
+			tamper(signedAttestation)
+
+			err := VerifyAttestation(signedAttestation, attester.keySet)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationKeyErrors(t *testing.T) {
+	attester := initializeTestAttester(t)
+	otherPublicKey := generateSigningKey(t).Public().(ed25519.PublicKey)
+	round := attester.bundle.Round
+
+	testCases := map[string]struct {
+		keySet      KeySet
+		keyID       string
+		expectedErr error
+	}{
+		"unknown key": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{getPublishedKey(otherPublicKey, 0, 0)}},
+			"", ErrUnknownKey},
+		"revoked key": {KeySet{Version: KeySetVersion}, "", ErrUnknownKey},
+		"key signed under another key's ID": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{
+			getPublishedKey(otherPublicKey, 0, 0)}}, GetKeyID(otherPublicKey), ErrInvalidSignature},
+		"key valid from a later round": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{
+			getPublishedKey(attester.PublicKey(), round+1, 0)}}, "", ErrKeyNotValidForRound},
+		"key rotated out before the round": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{
+			getPublishedKey(attester.PublicKey(), 0, round-1)}}, "", ErrKeyNotValidForRound},
+		"unsupported key set version": {KeySet{Version: KeySetVersion + 1, Keys: attester.keySet.Keys}, "",
+			ErrUnsupportedKeySetVersion},
+		"malformed public key": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{{KeyID: attester.KeyID(),
+			PublicKey: attester.PublicKey()[1:]}}}, "", ErrInvalidPublicKey},
+		"key ID mismatch": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{{KeyID: attester.KeyID(),
+			PublicKey: otherPublicKey}}}, "", ErrKeyIDMismatch},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			signedAttestation := attester.attest(t)
+			if testCase.keyID != "" {
+				signedAttestation.Attestation.KeyID = testCase.keyID
+			}
+
+			err := VerifyAttestation(signedAttestation, &testCase.keySet)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationAcrossKeyRotation(t *testing.T) {
+	attester := initializeTestAttester(t)
+	round := attester.bundle.Round
+	rotatedAttester := InitializeAttester(attester.oracle, generateSigningKey(t))
+
+	// The previous key remains valid for the rounds it attested to before being rotated out.
+	keySet := &KeySet{
+		Version: KeySetVersion,
+		Keys: []PublishedKey{
+			getPublishedKey(attester.PublicKey(), 0, round),
+			getPublishedKey(rotatedAttester.PublicKey(), round+1, 0),
+		},
+	}
+
+	err := VerifyAttestation(attester.attest(t), keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedAttestation, err := rotatedAttester.Attest(attester.bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyAttestation(rotatedAttestation, keySet)
+	if !errors.Is(err, ErrKeyNotValidForRound) {
+		t.Fatalf("expected %v, got %v", ErrKeyNotValidForRound, err)
+	}
+}
+
+func TestAttestationVersion(t *testing.T) {
+	attester := initializeTestAttester(t)
+	signedAttestation := attester.attest(t)
+	signedAttestation.Attestation.Version++
+
+	err := VerifyAttestation(signedAttestation, attester.keySet)
+	if !errors.Is(err, ErrUnsupportedAttestationVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedAttestationVersion, err)
+	}
+
+	_, err = DecodeMsgpack(signedAttestation.EncodeMsgpack())
+	if !errors.Is(err, ErrUnsupportedAttestationVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedAttestationVersion, err)
+	}
+	_, err = DecodeJSON(signedAttestation.EncodeJSON())
+	if !errors.Is(err, ErrUnsupportedAttestationVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedAttestationVersion, err)
+	}
+}
+
+func TestEncodingRoundTrips(t *testing.T) {
+	attester := initializeTestAttester(t)
+	signedAttestation := attester.attest(t)
+
+	decodedFromMsgpack, err := DecodeMsgpack(signedAttestation.EncodeMsgpack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedFromJSON, err := DecodeJSON(signedAttestation.EncodeJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decoded := range []*SignedAttestation{decodedFromMsgpack, decodedFromJSON} {
+		if !reflect.DeepEqual(decoded, signedAttestation) {
+			t.Fatalf("expected %+v, got %+v", signedAttestation, decoded)
+		}
+		err = VerifyAttestation(decoded, attester.keySet)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = DecodeMsgpack([]byte{0xc1})
+	if err == nil {
+		t.Fatal("expected malformed msgpack to be rejected")
+	}
+	_, err = DecodeJSON([]byte("{"))
+	if err == nil {
+		t.Fatal("expected malformed JSON to be rejected")
+	}
+
+	decodedKeySet, err := DecodeKeySet(attester.keySet.EncodeJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decodedKeySet, attester.keySet) {
+		t.Fatalf("expected %+v, got %+v", attester.keySet, decodedKeySet)
+	}
+}
+
+func TestDecodeKeySetErrors(t *testing.T) {
+	attester := initializeTestAttester(t)
+	publishedKey := attester.keySet.Keys[0]
+	otherPublicKey := generateSigningKey(t).Public().(ed25519.PublicKey)
+
+	testCases := map[string]struct {
+		keySet      KeySet
+		expectedErr error
+	}{
+		"unsupported version": {KeySet{Version: KeySetVersion + 1}, ErrUnsupportedKeySetVersion},
+		"malformed public key": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{{KeyID: publishedKey.KeyID,
+			PublicKey: publishedKey.PublicKey[1:]}}}, ErrInvalidPublicKey},
+		"key ID mismatch": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{{KeyID: publishedKey.KeyID,
+			PublicKey: otherPublicKey}}}, ErrKeyIDMismatch},
+		"duplicate key ID": {KeySet{Version: KeySetVersion, Keys: []PublishedKey{publishedKey, publishedKey}},
+			ErrDuplicateKeyID},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeKeySet(json.Encode(&testCase.keySet))
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+
+	_, err := DecodeKeySet([]byte("{"))
+	if err == nil {
+		t.Fatal("expected a malformed key set to be rejected")
+	}
+}
+
+func TestGetKeyID(t *testing.T) {
+	attester := initializeTestAttester(t)
+	keyID := GetKeyID(attester.PublicKey())
+	if len(keyID) != 16 || keyID != attester.KeyID() {
+		t.Fatalf("expected a 16 character key ID matching the attester's, got %q", keyID)
+	}
+	if GetKeyID(generateSigningKey(t).Public().(ed25519.PublicKey)) == keyID {
+		t.Fatal("expected distinct keys to have distinct key IDs")
+	}
+}
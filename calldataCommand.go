@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/almog-t/light-client-poc/evmcalldata"
+)
+
+// encodedCall describes the calldata exported for a single inclusion proof bundle. Byte fields are 0x prefixed hex,
+// the format EVM tooling expects, rather than base64.
+type encodedCall struct {
+	Source                   string `json:"source"`
+	Round                    uint64 `json:"round"`
+	IntervalIndex            uint64 `json:"interval_index"`
+	Calldata                 string `json:"calldata"`
+	TransactionCommitment    string `json:"transaction_commitment"`
+	LightBlockHeaderPreimage string `json:"light_block_header_preimage"`
+}
+
+// calldataResult describes the calldata exported by the calldata subcommand.
+type calldataResult struct {
+	Signature string        `json:"signature"`
+	Selector  string        `json:"selector"`
+	Calls     []encodedCall `json:"calls"`
+}
+
+func (r *calldataResult) writeText(output io.Writer) {
+	fmt.Fprintf(output, "Function: %s (selector %s)\n", r.Signature, r.Selector)
+	for _, call := range r.Calls {
+		fmt.Fprintf(output, "%s (round %d, interval %d):\n", call.Source, call.Round, call.IntervalIndex)
+		fmt.Fprintf(output, "  calldata: %s\n", call.Calldata)
+		fmt.Fprintf(output, "  transaction commitment: %s\n", call.TransactionCommitment)
+		fmt.Fprintf(output, "  light block header preimage: %s\n", call.LightBlockHeaderPreimage)
+	}
+}
+
+// encodeHex encodes bytes as 0x prefixed hex.
+func encodeHex(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
+
+// setupCalldataCommand sets up the calldata subcommand, which exports the inclusion proof bundles given as arguments as
+// ABI encoded calldata for an on-chain verifier, see evmcalldata.VerifyTransactionSignature. Interval indices are
+// computed using the oracle's parameters. The bundles are not verified.
+func setupCalldataCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	return func(arguments []string) (commandResult, error) {
+		if len(arguments) == 0 {
+			return nil, errNoBundles
+		}
+
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		history := oracleInstance.BlockIntervalCommitmentHistory
+		selector := evmcalldata.GetFunctionSelector(evmcalldata.VerifyTransactionSignature)
+		result := &calldataResult{Signature: evmcalldata.VerifyTransactionSignature, Selector: encodeHex(selector[:])}
+		for _, bundleFile := range arguments {
+			bundle, err := readInclusionProofBundle(bundleFile)
+			if err != nil {
+				return nil, err
+			}
+
+			call, err := evmcalldata.EncodeInclusionProofBundle(bundle, history.FirstAttestedRound, history.IntervalSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode inclusion proof bundle %s: %w", bundleFile, err)
+			}
+
+			result.Calls = append(result.Calls, encodedCall{
+				Source:                   bundleFile,
+				Round:                    uint64(bundle.Round),
+				IntervalIndex:            call.IntervalIndex,
+				Calldata:                 encodeHex(call.Calldata),
+				TransactionCommitment:    encodeHex(call.TransactionCommitment[:]),
+				LightBlockHeaderPreimage: encodeHex(call.LightBlockHeaderPreimage),
+			})
+		}
+
+		return result, nil
+	}
+}
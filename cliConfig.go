@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+var (
+	errNoStateFile = errors.New("no oracle state file given, use -state or a config file")
+)
+
+// cliConfig holds the options shared by the subcommands. Every option can be set using a flag, or using a JSON config
+// file given with -config. Flags take precedence over the config file.
+type cliConfig struct {
+	// ConfigFile is the path of the config file. It can only be set using a flag.
+	ConfigFile string
+	// StateFile is the path of the file holding the oracle's state.
+	StateFile string
+	// FixturesFile is the path of a fixture bundle, see encodedassets/fixtureBundle.go.
+	FixturesFile string
+	// Capacity is the maximum number of commitments the oracle holds.
+	Capacity uint64
+	// JSONOutput makes subcommands write machine-readable JSON instead of text.
+	JSONOutput bool
+	// AlgodAddress is the address of the algod compatible REST API state proofs are retrieved from.
+	AlgodAddress string
+	// AlgodToken is the API token of the algod REST API. It is best kept in the config file rather than in a flag.
+	AlgodToken string
+	// RelayerToken is the bearer token relayers must present to push state proofs to the verification server.
+	RelayerToken string
+	// NullifiersFile is the path of the file holding the nullifiers of consumed transactions, see nullifierstore.
+	NullifiersFile string
+	// AttestationKeyFile is the path of the file holding the base64 encoded ed25519 seed attestations are signed with.
+	AttestationKeyFile string
+	// AttestationKeySetFile is the path of the key set published for attestations to be verified against.
+	AttestationKeySetFile string
+}
+
+// cliConfigFile is the format of the config file. Options missing from the file leave their flags' values intact.
+type cliConfigFile struct {
+	StateFile             *string `json:"state"`
+	FixturesFile          *string `json:"fixtures"`
+	Capacity              *uint64 `json:"capacity"`
+	JSONOutput            *bool   `json:"json"`
+	AlgodAddress          *string `json:"algod_address"`
+	AlgodToken            *string `json:"algod_token"`
+	RelayerToken          *string `json:"relayer_token"`
+	NullifiersFile        *string `json:"nullifiers"`
+	AttestationKeyFile    *string `json:"attestation_key"`
+	AttestationKeySetFile *string `json:"attestation_key_set"`
+}
+
+// registerCommonFlags registers the flags every subcommand supports.
+// Parameters:
+// flags - the subcommand's flag set.
+// config - the config the flags are parsed into.
+func registerCommonFlags(flags *flag.FlagSet, config *cliConfig) {
+	flags.StringVar(&config.ConfigFile, "config", "", "path of a JSON config file holding default values for flags")
+	flags.StringVar(&config.StateFile, "state", "oracle_state.json", "path of the oracle state file")
+	flags.BoolVar(&config.JSONOutput, "json", false, "write machine-readable JSON output")
+}
+
+// applyConfigFile reads the config file given with -config, if any, and uses its values for every option registered
+// by the subcommand but not set using a flag.
+// Parameters:
+// flags - the subcommand's parsed flag set.
+// config - the config the flags were parsed into.
+func applyConfigFile(flags *flag.FlagSet, config *cliConfig) error {
+	if config.ConfigFile == "" {
+		return nil
+	}
+
+	encodedConfig, err := os.ReadFile(config.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encodedConfig))
+	decoder.DisallowUnknownFields()
+
+	var fileConfig cliConfigFile
+	err = decoder.Decode(&fileConfig)
+	if err != nil {
+		return fmt.Errorf("failed to decode config file %s: %w", config.ConfigFile, err)
+	}
+
+	setFlags := make(map[string]bool)
+	flags.Visit(func(setFlag *flag.Flag) {
+		setFlags[setFlag.Name] = true
+	})
+
+	// We only apply options the subcommand registered, so that a single config file can serve every subcommand.
+	applies := func(name string) bool {
+		return flags.Lookup(name) != nil && !setFlags[name]
+	}
+
+	if fileConfig.StateFile != nil && applies("state") {
+		config.StateFile = *fileConfig.StateFile
+	}
+	if fileConfig.FixturesFile != nil && applies("fixtures") {
+		config.FixturesFile = *fileConfig.FixturesFile
+	}
+	if fileConfig.Capacity != nil && applies("capacity") {
+		config.Capacity = *fileConfig.Capacity
+	}
+	if fileConfig.JSONOutput != nil && applies("json") {
+		config.JSONOutput = *fileConfig.JSONOutput
+	}
+	if fileConfig.AlgodAddress != nil && applies("algod-address") {
+		config.AlgodAddress = *fileConfig.AlgodAddress
+	}
+	if fileConfig.AlgodToken != nil && applies("algod-token") {
+		config.AlgodToken = *fileConfig.AlgodToken
+	}
+	if fileConfig.RelayerToken != nil && applies("relayer-token") {
+		config.RelayerToken = *fileConfig.RelayerToken
+	}
+	if fileConfig.NullifiersFile != nil && applies("nullifiers") {
+		config.NullifiersFile = *fileConfig.NullifiersFile
+	}
+	if fileConfig.AttestationKeyFile != nil && applies("attestation-key") {
+		config.AttestationKeyFile = *fileConfig.AttestationKeyFile
+	}
+	if fileConfig.AttestationKeySetFile != nil && applies("attestation-key-set") {
+		config.AttestationKeySetFile = *fileConfig.AttestationKeySetFile
+	}
+
+	return nil
+}
+
+// loadOracle loads the oracle from its state file.
+// Parameters:
+// config - the subcommand's config, holding the state file's path.
+func loadOracle(config *cliConfig) (*oracle.Oracle, error) {
+	if config.StateFile == "" {
+		return nil, errNoStateFile
+	}
+
+	encodedState, err := os.ReadFile(config.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oracle state: %w", err)
+	}
+
+	oracleInstance, err := oracle.DecodeOracle(encodedState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode oracle state %s: %w", config.StateFile, err)
+	}
+
+	return oracleInstance, nil
+}
+
+// saveOracle saves the oracle to its state file. The file is replaced atomically, so that an interrupted save never
+// leaves a corrupted state behind.
+// Parameters:
+// config - the subcommand's config, holding the state file's path.
+// oracleInstance - the oracle to save.
+func saveOracle(config *cliConfig, oracleInstance *oracle.Oracle) error {
+	if config.StateFile == "" {
+		return errNoStateFile
+	}
+
+	encodedState, err := oracleInstance.EncodeState()
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(config.StateFile, append(encodedState, '\n'))
+}
+
+// writeFileAtomically writes data to a temporary file in the same directory as the given path, and renames it over
+// the given path.
+// Parameters:
+// path - the path of the file to write.
+// data - the data to write.
+func writeFileAtomically(path string, data []byte) error {
+	temporaryFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file fails harmlessly once it has been renamed.
+	defer os.Remove(temporaryFile.Name())
+
+	_, err = temporaryFile.Write(data)
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	// The data must reach the disk before the rename, or a crash may leave an empty file at the given path.
+	err = temporaryFile.Sync()
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	err = temporaryFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryFile.Name(), path)
+}
+
+// commandResult is the result of a subcommand, which can be written as either text or JSON.
+type commandResult interface {
+	// writeText writes the result in a human-readable format.
+	writeText(output io.Writer)
+}
+
+// writeResult writes a subcommand's result in the format chosen by the config.
+// Parameters:
+// output - the writer to write the result to.
+// config - the subcommand's config.
+// result - the result to write.
+func writeResult(output io.Writer, config *cliConfig, result commandResult) error {
+	if !config.JSONOutput {
+		result.writeText(output)
+		return nil
+	}
+
+	encodedResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(output, "%s\n", encodedResult)
+	return err
+}
+
+// writeError writes a subcommand's error in the format chosen by the config.
+// Parameters:
+// output - the writer to write the error to.
+// config - the subcommand's config.
+// commandErr - the error to write.
+func writeError(output io.Writer, config *cliConfig, commandErr error) {
+	if !config.JSONOutput {
+		fmt.Fprintf(output, "Error: %s\n", commandErr)
+		return
+	}
+
+	encodedError, err := json.MarshalIndent(struct {
+		Error string `json:"error"`
+	}{commandErr.Error()}, "", "  ")
+	if err != nil {
+		fmt.Fprintf(output, "Error: %s\n", commandErr)
+		return
+	}
+
+	fmt.Fprintf(output, "%s\n", encodedError)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+)
+
+const testFixturesFile = "encodedassets/fixturebundle/fixtures.json"
+
+// runTestCommand runs a subcommand as runCommand does, and returns its result rather than writing it.
+// Parameters:
+// name - the name of the subcommand.
+// arguments - the arguments following the subcommand's name.
+func runTestCommand(t *testing.T, name string, arguments ...string) (commandResult, error) {
+	t.Helper()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	var config cliConfig
+	registerCommonFlags(flags, &config)
+	run := commands[name].setup(flags, &config)
+
+	err := flags.Parse(arguments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = applyConfigFile(flags, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return run(flags.Args())
+}
+
+// mustRunTestCommand runs a subcommand, failing the test if the subcommand fails.
+func mustRunTestCommand(t *testing.T, name string, arguments ...string) commandResult {
+	t.Helper()
+
+	result, err := runTestCommand(t, name, arguments...)
+	if err != nil {
+		t.Fatalf("%s %v: %v", name, arguments, err)
+	}
+	return result
+}
+
+// writeTestInclusionProofBundle writes the fixture bundle's valid transaction case as an inclusion proof bundle, with
+// its seed altered if tamper is true, and returns the bundle file's path.
+func writeTestInclusionProofBundle(t *testing.T, tamper bool) string {
+	t.Helper()
+
+	fixtureBundle, _ := loadAdvancedFixtureOracle(t)
+	for _, fixtureCase := range fixtureBundle.TransactionCases {
+		if fixtureCase.ExpectedError != "" {
+			continue
+		}
+
+		if tamper {
+			fixtureCase.Seed[0] ^= 1
+		}
+
+		bundle := inclusionbundle.InitializeInclusionProofBundle(fixtureCase.TransactionID,
+			fixtureCase.TransactionProofResponse, fixtureCase.LightBlockHeaderProofResponse, fixtureCase.Round,
+			fixtureCase.GenesisHash, fixtureCase.Seed)
+		bundleFile := filepath.Join(t.TempDir(), "bundle.msgp")
+		err := os.WriteFile(bundleFile, bundle.EncodeMsgpack(), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bundleFile
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return ""
+}
+
+func TestCommandsLifecycle(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+
+	status := mustRunTestCommand(t, "init", "-state", stateFile, "-fixtures", testFixturesFile).(*statusResult)
+	if status.CoveredRounds != nil || status.NextInterval != 0 || status.NextIntervalRounds != (roundRange{9, 16}) {
+		t.Fatalf("unexpected status after init: %+v", status)
+	}
+
+	advance := mustRunTestCommand(t, "advance", "-state", stateFile, "-fixtures", testFixturesFile).(*advanceResult)
+	if len(advance.Intervals) != 1 || advance.Intervals[0].Outcome != "ingested" {
+		t.Fatalf("unexpected intervals: %+v", advance.Intervals)
+	}
+
+	// Ingesting the same state proofs again skips them.
+	advance = mustRunTestCommand(t, "advance", "-state", stateFile, "-fixtures", testFixturesFile).(*advanceResult)
+	if len(advance.Intervals) != 1 || advance.Intervals[0].Outcome != "skipped" {
+		t.Fatalf("unexpected intervals: %+v", advance.Intervals)
+	}
+
+	status = mustRunTestCommand(t, "status", "-state", stateFile).(*statusResult)
+	if status.CoveredRounds == nil || *status.CoveredRounds != (roundRange{9, 16}) || status.NextInterval != 1 {
+		t.Fatalf("unexpected status after advance: %+v", status)
+	}
+
+	verify := mustRunTestCommand(t, "verify", "-state", stateFile, "-fixtures", testFixturesFile,
+		writeTestInclusionProofBundle(t, false)).(*verifyResult)
+	if !verify.Passed || len(verify.Verifications) < 2 {
+		t.Fatalf("unexpected verifications: %+v", verify.Verifications)
+	}
+
+	// A checkpoint initializes an oracle in the same state.
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	export := mustRunTestCommand(t, "export", "-state", stateFile, "-output", checkpointFile).(*exportResult)
+	if export.Commitments != 1 || export.NextInterval != 1 {
+		t.Fatalf("unexpected export result: %+v", export)
+	}
+
+	checkpointStateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+	checkpointStatus := mustRunTestCommand(t, "init", "-state", checkpointStateFile, "-checkpoint",
+		checkpointFile).(*statusResult)
+	checkpointStatus.StateFile = stateFile
+	if !reflect.DeepEqual(checkpointStatus, status) {
+		t.Fatalf("expected the checkpoint's status to be %+v, got %+v", status, checkpointStatus)
+	}
+}
+
+func TestVerifyCommandReportsFailure(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+	mustRunTestCommand(t, "init", "-state", stateFile, "-fixtures", testFixturesFile)
+	mustRunTestCommand(t, "advance", "-state", stateFile, "-fixtures", testFixturesFile)
+
+	result, err := runTestCommand(t, "verify", "-state", stateFile, writeTestInclusionProofBundle(t, true))
+	if !errors.Is(err, errVerificationFailed) {
+		t.Fatalf("expected %v, got %v", errVerificationFailed, err)
+	}
+
+	// The result describes the failure, so that it is written along with the error.
+	verify := result.(*verifyResult)
+	if verify.Passed || len(verify.Verifications) != 1 || verify.Verifications[0].Error == "" {
+		t.Fatalf("unexpected verifications: %+v", verify.Verifications)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	existingStateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+	mustRunTestCommand(t, "init", "-state", existingStateFile, "-fixtures", testFixturesFile)
+	missingStateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+
+	testCases := map[string]struct {
+		name        string
+		arguments   []string
+		expectedErr error
+	}{
+		"init without genesis": {"init", []string{"-state", missingStateFile}, errAmbiguousGenesis},
+		"init with two sources": {"init", []string{"-state", missingStateFile, "-fixtures", testFixturesFile,
+			"-voters-commitment", "AA=="}, errAmbiguousGenesis},
+		"init over existing state": {"init", []string{"-state", existingStateFile, "-fixtures", testFixturesFile},
+			errStateFileExists},
+		"init with incomplete genesis": {"init", []string{"-state", missingStateFile, "-voters-commitment", "AA=="},
+			errIncompleteGenesis},
+		"advance without state proofs": {"advance", []string{"-state", existingStateFile}, errNoStateProofs},
+		"advance without state": {"advance", []string{"-state", missingStateFile, "-fixtures", testFixturesFile},
+			fs.ErrNotExist},
+		"verify without bundles":    {"verify", []string{"-state", existingStateFile}, errNoBundles},
+		"status without state":      {"status", []string{"-state", missingStateFile}, fs.ErrNotExist},
+		"status without state file": {"status", []string{"-state", ""}, errNoStateFile},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := runTestCommand(t, testCase.name, testCase.arguments...)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	directory := t.TempDir()
+	configStateFile := filepath.Join(directory, "config_state.json")
+	configFile := filepath.Join(directory, "config.json")
+	encodedConfig, err := json.Marshal(map[string]interface{}{
+		"state":    configStateFile,
+		"fixtures": testFixturesFile,
+		"json":     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(configFile, encodedConfig, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options are taken from the config file, unless set using a flag.
+	status := mustRunTestCommand(t, "init", "-config", configFile).(*statusResult)
+	if status.StateFile != configStateFile {
+		t.Fatalf("expected the state file %s, got %s", configStateFile, status.StateFile)
+	}
+
+	flagStateFile := filepath.Join(directory, "flag_state.json")
+	status = mustRunTestCommand(t, "init", "-config", configFile, "-state", flagStateFile).(*statusResult)
+	if status.StateFile != flagStateFile {
+		t.Fatalf("expected the state file %s, got %s", flagStateFile, status.StateFile)
+	}
+
+	err = os.WriteFile(configFile, []byte(`{"unknown": true}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runTestCommand(t, "status", "-config", configFile)
+	if err == nil {
+		t.Fatal("expected a config file holding an unknown option to be rejected")
+	}
+}
+
+func TestWriteResultAsJSON(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "oracle_state.json")
+	status := mustRunTestCommand(t, "init", "-state", stateFile, "-fixtures", testFixturesFile).(*statusResult)
+
+	var output bytes.Buffer
+	err := writeResult(&output, &cliConfig{JSONOutput: true}, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decodedStatus statusResult
+	err = json.Unmarshal(output.Bytes(), &decodedStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decodedStatus, status) {
+		t.Fatalf("expected %+v, got %+v", status, decodedStatus)
+	}
+
+	output.Reset()
+	writeError(&output, &cliConfig{JSONOutput: true}, errNoStateFile)
+	var decodedError struct {
+		Error string `json:"error"`
+	}
+	err = json.Unmarshal(output.Bytes(), &decodedError)
+	if err != nil || decodedError.Error != errNoStateFile.Error() {
+		t.Fatalf("expected the error %q, got %q (%v)", errNoStateFile, output.String(), err)
+	}
+}
@@ -0,0 +1,147 @@
+//go:build ignore
+
+// This program generates adversarial_cases.json by tampering with the transaction verification assets. Each case
+// changes a single input and records the error verifying it must produce. Run it from the repository's root using
+// go run encodedassets/adversarialverification/generate.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+)
+
+// adversarialCase is a tampered transaction verification case, along with the name of the error that verifying it
+// must produce. encodedassets.ConvertDirectoryLayout decodes the cases using the same field names.
+type adversarialCase struct {
+	Name                          string                          `json:"name"`
+	ExpectedError                 string                          `json:"expected_error"`
+	GenesisHash                   types.Digest                    `json:"genesis_hash"`
+	Round                         types.Round                     `json:"round"`
+	Seed                          types.Seed                      `json:"seed"`
+	TransactionID                 types.Digest                    `json:"transaction_id"`
+	TransactionProofResponse      models.TransactionProofResponse `json:"transaction_proof_response"`
+	LightBlockHeaderProofResponse models.LightBlockHeaderProof    `json:"light_block_header_proof_response"`
+}
+
+// cloneCase returns a copy of the given case that shares no slices with it, so that tampering with the copy leaves
+// the original intact.
+func cloneCase(original adversarialCase) adversarialCase {
+	clone := original
+	clone.TransactionProofResponse.Proof = append([]byte{}, original.TransactionProofResponse.Proof...)
+	clone.TransactionProofResponse.Stibhash = append([]byte{}, original.TransactionProofResponse.Stibhash...)
+	clone.LightBlockHeaderProofResponse.Proof = append([]byte{}, original.LightBlockHeaderProofResponse.Proof...)
+	return clone
+}
+
+func main() {
+	genesisHash, round, seed, transactionHash, transactionProofResponse, lightBlockHeaderProofResponse, err :=
+		encodedassets.GetParsedTypesData("encodedassets/transactionverification/", encodedassets.JSONFormat)
+	if err != nil {
+		fmt.Printf("Failed to parse assets needed for transaction verification: %s\n", err)
+		os.Exit(1)
+	}
+
+	validCase := adversarialCase{
+		GenesisHash:                   genesisHash,
+		Round:                         round,
+		Seed:                          seed,
+		TransactionID:                 transactionHash,
+		TransactionProofResponse:      transactionProofResponse,
+		LightBlockHeaderProofResponse: lightBlockHeaderProofResponse,
+	}
+
+	// Each tampering function receives a copy of the valid case and changes a single input.
+	tamperings := []struct {
+		name          string
+		expectedError string
+		tamper        func(c *adversarialCase)
+	}{
+		{"wrong stib hash", "ErrRootMismatch", func(c *adversarialCase) {
+			c.TransactionProofResponse.Stibhash[0] ^= 1
+		}},
+		{"wrong transaction id", "ErrRootMismatch", func(c *adversarialCase) {
+			c.TransactionID[0] ^= 1
+		}},
+		{"swapped light block header proof sibling order", "ErrRootMismatch", func(c *adversarialCase) {
+			proof := c.LightBlockHeaderProofResponse.Proof
+			var firstSibling [32]byte
+			copy(firstSibling[:], proof[:32])
+			copy(proof[:32], proof[32:64])
+			copy(proof[32:64], firstSibling[:])
+		}},
+		{"truncated transaction proof", "ErrProofLengthTreeDepthMismatch", func(c *adversarialCase) {
+			c.TransactionProofResponse.Proof = c.TransactionProofResponse.Proof[:len(c.TransactionProofResponse.Proof)-1]
+		}},
+		{"truncated light block header proof", "ErrProofLengthTreeDepthMismatch", func(c *adversarialCase) {
+			c.LightBlockHeaderProofResponse.Proof = c.LightBlockHeaderProofResponse.Proof[:len(c.LightBlockHeaderProofResponse.Proof)-32]
+		}},
+		{"wrong round inside the interval", "ErrLightBlockHeaderIndexMismatch", func(c *adversarialCase) {
+			c.Round++
+		}},
+		{"wrong round after the latest interval", "ErrNoStateProofForRound", func(c *adversarialCase) {
+			c.Round += 8
+		}},
+		{"wrong round before the first attested round", "ErrTooEarlyRoundRequested", func(c *adversarialCase) {
+			c.Round--
+		}},
+		{"wrong seed", "ErrRootMismatch", func(c *adversarialCase) {
+			c.Seed[0] ^= 1
+		}},
+		{"wrong genesis hash", "ErrRootMismatch", func(c *adversarialCase) {
+			c.GenesisHash[0] ^= 1
+		}},
+		{"transaction index beyond depth", "ErrIndexDepthMismatch", func(c *adversarialCase) {
+			c.TransactionProofResponse.Idx = 1 << c.TransactionProofResponse.Treedepth
+		}},
+		{"light block header index beyond depth", "ErrLightBlockHeaderIndexMismatch", func(c *adversarialCase) {
+			c.LightBlockHeaderProofResponse.Index = 1 << c.LightBlockHeaderProofResponse.Treedepth
+		}},
+		{"wrong light block header tree depth", "ErrLightBlockHeaderTreeDepthMismatch", func(c *adversarialCase) {
+			c.LightBlockHeaderProofResponse.Treedepth++
+		}},
+		{"wrong hash type", "ErrUnsupportedHashFunction", func(c *adversarialCase) {
+			c.TransactionProofResponse.Hashtype = "sha512_256"
+		}},
+		{"unknown hash type", "ErrUnsupportedHashFunction", func(c *adversarialCase) {
+			c.TransactionProofResponse.Hashtype = "md5"
+		}},
+	}
+
+	adversarialCases := make([]adversarialCase, 0, len(tamperings))
+	for _, tampering := range tamperings {
+		tamperedCase := cloneCase(validCase)
+		tamperedCase.Name = tampering.name
+		tamperedCase.ExpectedError = tampering.expectedError
+		tampering.tamper(&tamperedCase)
+		adversarialCases = append(adversarialCases, tamperedCase)
+	}
+
+	// Each case is written on its own line, which keeps the file readable despite digests being encoded as arrays.
+	encodedCases := []byte("[\n")
+	for i, tamperedCase := range adversarialCases {
+		encodedCase, err := json.Marshal(tamperedCase)
+		if err != nil {
+			fmt.Printf("Failed to encode adversarial case %q: %s\n", tamperedCase.Name, err)
+			os.Exit(1)
+		}
+
+		encodedCases = append(encodedCases, encodedCase...)
+		if i < len(adversarialCases)-1 {
+			encodedCases = append(encodedCases, ',')
+		}
+		encodedCases = append(encodedCases, '\n')
+	}
+	encodedCases = append(encodedCases, "]\n"...)
+
+	err = os.WriteFile("encodedassets/adversarialverification/adversarial_cases.json", encodedCases, 0644)
+	if err != nil {
+		fmt.Printf("Failed to write adversarial cases: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,107 +1,71 @@
 package encodedassets
 
 import (
-	"encoding/json"
 	"os"
-	"path/filepath"
 
 	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
-	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
 	"github.com/algorand/go-algorand-sdk/types"
 
 	"github.com/algorand/go-stateproof-verification/stateproof"
 	"github.com/algorand/go-stateproof-verification/stateproofcrypto"
 )
 
-// These functions take the encoded assets, committed as examples, and parse them.
+// These functions take the encoded assets, committed as examples, and parse them. They read the assets from a
+// directory, and are kept for convenience. See assetLoader.go for loading assets from an fs.FS or an io.Reader.
 
-func decodeFromFile(encodedPath string, target interface{}) error {
-	encodedData, err := os.ReadFile(encodedPath)
-	if err != nil {
-		return err
-	}
-
-	err = json.Unmarshal(encodedData, target)
-	return err
-}
-
-func GetParsedTypesData(typesDataPath string) (types.Digest, types.Round, types.Seed, types.Digest, models.TransactionProofResponse,
+// GetParsedTypesData parses the data required for verifying a transaction. The proof responses are read in the given
+// format, while the remaining values are always JSON encoded.
+func GetParsedTypesData(typesDataPath string, format EncodingFormat) (types.Digest, types.Round, types.Seed, types.Digest, models.TransactionProofResponse,
 	models.LightBlockHeaderProof, error) {
-	var genesisHash types.Digest
-	err := decodeFromFile(filepath.Join(typesDataPath, "genesis_hash.txt"), &genesisHash)
-	if err != nil {
-		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
-	}
-
-	var round types.Round
-	err = decodeFromFile(filepath.Join(typesDataPath, "round.txt"), &round)
-	if err != nil {
-		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
-	}
-
-	var seed types.Seed
-	err = decodeFromFile(filepath.Join(typesDataPath, "seed.txt"), &seed)
-	if err != nil {
-		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
-	}
-
-	var transactionId types.Digest
-	err = decodeFromFile(filepath.Join(typesDataPath, "transaction_id.txt"), &transactionId)
+	data, err := LoadTransactionVerificationData(os.DirFS(typesDataPath), format)
 	if err != nil {
 		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
 	}
 
-	var transactionProofResponse models.TransactionProofResponse
-	err = decodeFromFile(filepath.Join(typesDataPath, "transaction_proof_response.json"), &transactionProofResponse)
-	if err != nil {
-		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
-	}
-
-	var lightBlockHeaderProof models.LightBlockHeaderProof
-	err = decodeFromFile(filepath.Join(typesDataPath, "light_block_header_proof_response.json"), &lightBlockHeaderProof)
-	if err != nil {
-		return types.Digest{}, 0, types.Seed{}, types.Digest{}, models.TransactionProofResponse{}, models.LightBlockHeaderProof{}, err
-	}
-
-	return genesisHash, round, seed, transactionId, transactionProofResponse,
-		lightBlockHeaderProof, nil
+	return data.GenesisHash, data.Round, data.Seed, data.TransactionID, data.TransactionProofResponse,
+		data.LightBlockHeaderProofResponse, nil
 }
 
 func GetParsedGenesisData(genesisDataPath string) (stateproofcrypto.GenericDigest, uint64, error) {
-	genesisVotersCommitment := stateproofcrypto.GenericDigest{}
-	err := decodeFromFile(filepath.Join(genesisDataPath, "genesis_voters_commitment.txt"), &genesisVotersCommitment)
+	data, err := LoadGenesisData(os.DirFS(genesisDataPath))
 	if err != nil {
 		return stateproofcrypto.GenericDigest{}, 0, err
 	}
 
-	genesisVotersLnProvenWeight := uint64(0)
-	err = decodeFromFile(filepath.Join(genesisDataPath, "genesis_voters_ln_proven_weight.txt"), &genesisVotersLnProvenWeight)
-	if err != nil {
-		return stateproofcrypto.GenericDigest{}, 0, err
-	}
-
-	return genesisVotersCommitment, genesisVotersLnProvenWeight, nil
+	return data.VotersCommitment, data.VotersLnProvenWeight, nil
 }
 
-func GetParsedStateProofAdvancmentData(stateProofVerificationDataPath string) (types.Message,
+// GetParsedStateProofAdvancmentData parses the state proof message and state proof required for advancing the
+// Oracle's state. The message is read in the given format.
+func GetParsedStateProofAdvancmentData(stateProofVerificationDataPath string, format EncodingFormat) (types.Message,
 	*stateproof.StateProof, error) {
-	stateProofMessage := types.Message{}
-	err := decodeFromFile(filepath.Join(stateProofVerificationDataPath, "state_proof_message.json"), &stateProofMessage)
+	data, err := LoadStateProofAdvancementData(os.DirFS(stateProofVerificationDataPath), format)
 	if err != nil {
 		return types.Message{}, nil, err
 	}
 
-	var msgPackedStateProof []byte
-	err = decodeFromFile(filepath.Join(stateProofVerificationDataPath, "state_proof.txt"), &msgPackedStateProof)
+	return data.Message, data.StateProof, nil
+}
+
+// GetParsedStateProofResponse parses a state proof response, as returned by an Algorand node when queried using
+// GetStateProof, into the state proof message and state proof required for advancing the Oracle's state.
+func GetParsedStateProofResponse(stateProofVerificationDataPath string, format EncodingFormat) (types.Message,
+	*stateproof.StateProof, error) {
+	data, err := LoadStateProofResponse(os.DirFS(stateProofVerificationDataPath), format)
 	if err != nil {
 		return types.Message{}, nil, err
 	}
 
-	var stateProof stateproof.StateProof
-	err = msgpack.Decode(msgPackedStateProof, &stateProof)
+	return data.Message, data.StateProof, nil
+}
+
+// ParseStateProofResponse converts a state proof response, as returned by an Algorand node when queried using
+// GetStateProof, into the state proof message and state proof required for advancing the Oracle's state.
+func ParseStateProofResponse(stateProofResponse models.StateProof) (types.Message, *stateproof.StateProof, error) {
+	data, err := convertStateProofResponse(stateProofResponse, "StateProofResponse")
 	if err != nil {
 		return types.Message{}, nil, err
 	}
 
-	return stateProofMessage, &stateProof, nil
+	return data.Message, data.StateProof, nil
 }
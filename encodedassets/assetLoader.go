@@ -0,0 +1,280 @@
+package encodedassets
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproof"
+	"github.com/algorand/go-stateproof-verification/stateproofcrypto"
+)
+
+// These functions load the assets from any fs.FS, such as an embed.FS or an in-memory fixture, or decode single
+// assets from an io.Reader, such as a network stream. Every error names the file and field that failed to decode.
+
+// TransactionVerificationData holds the data required for verifying a transaction.
+type TransactionVerificationData struct {
+	// GenesisHash is the hash of the genesis block.
+	GenesisHash types.Digest
+	// Round is the round in which the transaction was confirmed.
+	Round types.Round
+	// Seed is the sortition seed of the block in which the transaction was confirmed.
+	Seed types.Seed
+	// TransactionID is the result of invoking Sha256 on the canonical msgpack encoded transaction.
+	TransactionID types.Digest
+	// TransactionProofResponse is the response returned by an Algorand node when queried using GetTransactionProof.
+	TransactionProofResponse models.TransactionProofResponse
+	// LightBlockHeaderProofResponse is the response returned by an Algorand node when queried using GetLightBlockHeaderProof.
+	LightBlockHeaderProofResponse models.LightBlockHeaderProof
+}
+
+// GenesisData holds the data required for initializing the Oracle.
+type GenesisData struct {
+	// VotersCommitment is the vector commitment root of the genesis voters.
+	VotersCommitment stateproofcrypto.GenericDigest
+	// VotersLnProvenWeight is the natural log of the genesis voters' proven weight, with 16 bits of precision.
+	VotersLnProvenWeight uint64
+}
+
+// StateProofAdvancementData holds the data required for advancing the Oracle's state.
+type StateProofAdvancementData struct {
+	// Message is the state proof message the state proof attests to.
+	Message types.Message
+	// StateProof is the state proof attesting to Message.
+	StateProof *stateproof.StateProof
+}
+
+// decodeAsset decodes a single asset file from the given file system.
+// Parameters:
+// fsys - the file system holding the asset.
+// fileName - the name of the asset file.
+// fieldName - the name of the field the asset is decoded into, used to describe errors.
+// format - the format the asset is encoded in.
+// target - a pointer to the value to decode the asset into.
+func decodeAsset(fsys fs.FS, fileName string, fieldName string, format EncodingFormat, target interface{}) error {
+	encodedData, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read %s for %s: %w", fileName, fieldName, err)
+	}
+
+	err = DecodeResponse(encodedData, format, target)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s from %s: %w", fieldName, fileName, err)
+	}
+
+	return nil
+}
+
+// decodeStateProof decodes a state proof from its msgpack encoding.
+// Parameters:
+// msgPackedStateProof - the msgpack encoded state proof.
+// source - the name of the asset the state proof was taken from, used to describe errors.
+func decodeStateProof(msgPackedStateProof []byte, source string) (*stateproof.StateProof, error) {
+	var stateProof stateproof.StateProof
+	err := msgpack.Decode(msgPackedStateProof, &stateProof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode StateProof from %s: %w", source, err)
+	}
+
+	return &stateProof, nil
+}
+
+// LoadTransactionVerificationData loads the data required for verifying a transaction. The proof responses are read
+// in the given format, while the remaining values are always JSON encoded.
+// Parameters:
+// fsys - the file system holding the assets at its root.
+// format - the format the proof responses are encoded in.
+func LoadTransactionVerificationData(fsys fs.FS, format EncodingFormat) (*TransactionVerificationData, error) {
+	// We reject unknown formats before reading, as they have no file extension.
+	if format.Extension() == "" {
+		return nil, ErrUnknownEncodingFormat
+	}
+
+	var data TransactionVerificationData
+	assets := []struct {
+		fileName  string
+		fieldName string
+		format    EncodingFormat
+		target    interface{}
+	}{
+		{"genesis_hash.txt", "GenesisHash", JSONFormat, &data.GenesisHash},
+		{"round.txt", "Round", JSONFormat, &data.Round},
+		{"seed.txt", "Seed", JSONFormat, &data.Seed},
+		{"transaction_id.txt", "TransactionID", JSONFormat, &data.TransactionID},
+		{"transaction_proof_response" + format.Extension(), "TransactionProofResponse", format, &data.TransactionProofResponse},
+		{"light_block_header_proof_response" + format.Extension(), "LightBlockHeaderProofResponse", format, &data.LightBlockHeaderProofResponse},
+	}
+
+	for _, asset := range assets {
+		err := decodeAsset(fsys, asset.fileName, asset.fieldName, asset.format, asset.target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &data, nil
+}
+
+// LoadGenesisData loads the data required for initializing the Oracle.
+// Parameters:
+// fsys - the file system holding the assets at its root.
+func LoadGenesisData(fsys fs.FS) (*GenesisData, error) {
+	var data GenesisData
+	err := decodeAsset(fsys, "genesis_voters_commitment.txt", "VotersCommitment", JSONFormat, &data.VotersCommitment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodeAsset(fsys, "genesis_voters_ln_proven_weight.txt", "VotersLnProvenWeight", JSONFormat, &data.VotersLnProvenWeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// LoadStateProofAdvancementData loads the state proof message and state proof required for advancing the Oracle's
+// state. The message is read in the given format.
+// Parameters:
+// fsys - the file system holding the assets at its root.
+// format - the format the state proof message is encoded in.
+func LoadStateProofAdvancementData(fsys fs.FS, format EncodingFormat) (*StateProofAdvancementData, error) {
+	// We reject unknown formats before reading, as they have no file extension.
+	if format.Extension() == "" {
+		return nil, ErrUnknownEncodingFormat
+	}
+
+	var data StateProofAdvancementData
+	messageFileName := "state_proof_message" + format.Extension()
+	err := decodeAsset(fsys, messageFileName, "Message", format, &data.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	// The state proof is committed as its msgpack encoding, itself encoded as a JSON string.
+	var msgPackedStateProof []byte
+	err = decodeAsset(fsys, "state_proof.txt", "StateProof", JSONFormat, &msgPackedStateProof)
+	if err != nil {
+		return nil, err
+	}
+
+	data.StateProof, err = decodeStateProof(msgPackedStateProof, "state_proof.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// LoadStateProofResponse loads a state proof response, as returned by an Algorand node when queried using
+// GetStateProof, and converts it into the data required for advancing the Oracle's state.
+// Parameters:
+// fsys - the file system holding the assets at its root.
+// format - the format the state proof response is encoded in.
+func LoadStateProofResponse(fsys fs.FS, format EncodingFormat) (*StateProofAdvancementData, error) {
+	// We reject unknown formats before reading, as they have no file extension.
+	if format.Extension() == "" {
+		return nil, ErrUnknownEncodingFormat
+	}
+
+	var stateProofResponse models.StateProof
+	fileName := "state_proof_response" + format.Extension()
+	err := decodeAsset(fsys, fileName, "StateProofResponse", format, &stateProofResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertStateProofResponse(stateProofResponse, fileName)
+}
+
+// convertStateProofResponse converts a state proof response into the data required for advancing the Oracle's state.
+// Parameters:
+// stateProofResponse - the response returned by an Algorand node when queried using GetStateProof.
+// source - the name of the asset the response was taken from, used to describe errors.
+func convertStateProofResponse(stateProofResponse models.StateProof, source string) (*StateProofAdvancementData, error) {
+	stateProof, err := decodeStateProof(stateProofResponse.Stateproof, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateProofAdvancementData{
+		Message: types.Message{
+			BlockHeadersCommitment: stateProofResponse.Message.Blockheaderscommitment,
+			VotersCommitment:       stateProofResponse.Message.Voterscommitment,
+			LnProvenWeight:         stateProofResponse.Message.Lnprovenweight,
+			FirstAttestedRound:     stateProofResponse.Message.Firstattestedround,
+			LastAttestedRound:      stateProofResponse.Message.Lastattestedround,
+		},
+		StateProof: stateProof,
+	}, nil
+}
+
+// ReadResponse decodes an API response, returned by an Algorand node in the given format, from the given reader.
+// Parameters:
+// reader - the reader to read the encoded response from. It is read until EOF.
+// format - the format the response is encoded in.
+// fieldName - the name of the value being decoded, used to describe errors.
+// target - a pointer to the value to decode the response into.
+func ReadResponse(reader io.Reader, format EncodingFormat, fieldName string, target interface{}) error {
+	encodedData, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", fieldName, err)
+	}
+
+	err = DecodeResponse(encodedData, format, target)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", fieldName, err)
+	}
+
+	return nil
+}
+
+// ReadTransactionProofResponse decodes a response returned by an Algorand node when queried using GetTransactionProof.
+// Parameters:
+// reader - the reader to read the encoded response from. It is read until EOF.
+// format - the format the response is encoded in.
+func ReadTransactionProofResponse(reader io.Reader, format EncodingFormat) (models.TransactionProofResponse, error) {
+	var transactionProofResponse models.TransactionProofResponse
+	err := ReadResponse(reader, format, "TransactionProofResponse", &transactionProofResponse)
+	return transactionProofResponse, err
+}
+
+// ReadLightBlockHeaderProofResponse decodes a response returned by an Algorand node when queried using
+// GetLightBlockHeaderProof.
+// Parameters:
+// reader - the reader to read the encoded response from. It is read until EOF.
+// format - the format the response is encoded in.
+func ReadLightBlockHeaderProofResponse(reader io.Reader, format EncodingFormat) (models.LightBlockHeaderProof, error) {
+	var lightBlockHeaderProofResponse models.LightBlockHeaderProof
+	err := ReadResponse(reader, format, "LightBlockHeaderProofResponse", &lightBlockHeaderProofResponse)
+	return lightBlockHeaderProofResponse, err
+}
+
+// ReadStateProofMessage decodes a state proof message.
+// Parameters:
+// reader - the reader to read the encoded message from. It is read until EOF.
+// format - the format the message is encoded in.
+func ReadStateProofMessage(reader io.Reader, format EncodingFormat) (types.Message, error) {
+	var stateProofMessage types.Message
+	err := ReadResponse(reader, format, "Message", &stateProofMessage)
+	return stateProofMessage, err
+}
+
+// ReadStateProofResponse decodes a response returned by an Algorand node when queried using GetStateProof, and
+// converts it into the data required for advancing the Oracle's state.
+// Parameters:
+// reader - the reader to read the encoded response from. It is read until EOF.
+// format - the format the response is encoded in.
+func ReadStateProofResponse(reader io.Reader, format EncodingFormat) (*StateProofAdvancementData, error) {
+	var stateProofResponse models.StateProof
+	err := ReadResponse(reader, format, "StateProofResponse", &stateProofResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertStateProofResponse(stateProofResponse, "StateProofResponse")
+}
@@ -0,0 +1,179 @@
+package encodedassets
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// copyToMapFS copies the files of a directory into an in-memory file system, so that they can be altered.
+func copyToMapFS(t *testing.T, directory string) fstest.MapFS {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		data, err := os.ReadFile(directory + "/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[entry.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	return fsys
+}
+
+func TestLoadTransactionVerificationData(t *testing.T) {
+	data, err := LoadTransactionVerificationData(copyToMapFS(t, "transactionverification"), JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Round != 9 || data.TransactionProofResponse.Hashtype != "sha256" ||
+		data.LightBlockHeaderProofResponse.Treedepth != 3 {
+		t.Fatalf("unexpected transaction verification data: %+v", data)
+	}
+
+	// The directory-based function returns the same values.
+	genesisHash, round, seed, transactionID, transactionProofResponse, lightBlockHeaderProofResponse, err :=
+		GetParsedTypesData("transactionverification", JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesisHash != data.GenesisHash || round != data.Round || seed != data.Seed ||
+		transactionID != data.TransactionID ||
+		!bytes.Equal(transactionProofResponse.Proof, data.TransactionProofResponse.Proof) ||
+		!bytes.Equal(lightBlockHeaderProofResponse.Proof, data.LightBlockHeaderProofResponse.Proof) {
+		t.Fatal("GetParsedTypesData returned different values")
+	}
+}
+
+func TestLoadGenesisAndStateProofData(t *testing.T) {
+	genesisData, err := LoadGenesisData(copyToMapFS(t, "genesis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(genesisData.VotersCommitment) != votersCommitmentSize || genesisData.VotersLnProvenWeight == 0 {
+		t.Fatalf("unexpected genesis data: %+v", genesisData)
+	}
+
+	for _, format := range []EncodingFormat{JSONFormat, MsgpackFormat} {
+		advancementData, err := LoadStateProofAdvancementData(copyToMapFS(t, "stateproofverification"), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if advancementData.Message.FirstAttestedRound != 9 || advancementData.StateProof == nil {
+			t.Fatalf("unexpected state proof advancement data: %+v", advancementData.Message)
+		}
+	}
+}
+
+func TestLoadErrorsNameFileAndField(t *testing.T) {
+	testCases := []struct {
+		name      string
+		alter     func(fsys fstest.MapFS)
+		load      func(fsys fs.FS) error
+		fileName  string
+		fieldName string
+	}{
+		{
+			name:  "missing proof response",
+			alter: func(fsys fstest.MapFS) { delete(fsys, "transaction_proof_response.msgp") },
+			load: func(fsys fs.FS) error {
+				_, err := LoadTransactionVerificationData(fsys, MsgpackFormat)
+				return err
+			},
+			fileName:  "transaction_proof_response.msgp",
+			fieldName: "TransactionProofResponse",
+		},
+		{
+			name:  "malformed round",
+			alter: func(fsys fstest.MapFS) { fsys["round.txt"] = &fstest.MapFile{Data: []byte(`"nine"`)} },
+			load: func(fsys fs.FS) error {
+				_, err := LoadTransactionVerificationData(fsys, JSONFormat)
+				return err
+			},
+			fileName:  "round.txt",
+			fieldName: "Round",
+		},
+		{
+			name:  "malformed state proof",
+			alter: func(fsys fstest.MapFS) { fsys["state_proof.txt"] = &fstest.MapFile{Data: []byte(`"AAAA"`)} },
+			load: func(fsys fs.FS) error {
+				_, err := LoadStateProofAdvancementData(fsys, JSONFormat)
+				return err
+			},
+			fileName:  "state_proof.txt",
+			fieldName: "StateProof",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			directory := "transactionverification"
+			if testCase.fileName == "state_proof.txt" {
+				directory = "stateproofverification"
+			}
+
+			fsys := copyToMapFS(t, directory)
+			testCase.alter(fsys)
+			err := testCase.load(fsys)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), testCase.fileName) || !strings.Contains(err.Error(), testCase.fieldName) {
+				t.Fatalf("expected the error to name %s and %s, got %v", testCase.fileName, testCase.fieldName, err)
+			}
+		})
+	}
+
+	_, err := LoadTransactionVerificationData(fstest.MapFS{}, EncodingFormat(-1))
+	if !errors.Is(err, ErrUnknownEncodingFormat) {
+		t.Fatalf("expected %v, got %v", ErrUnknownEncodingFormat, err)
+	}
+}
+
+func TestReadResponses(t *testing.T) {
+	for _, format := range []EncodingFormat{JSONFormat, MsgpackFormat} {
+		transactionProofFile, err := os.Open("transactionverification/transaction_proof_response" + format.Extension())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer transactionProofFile.Close()
+
+		transactionProofResponse, err := ReadTransactionProofResponse(transactionProofFile, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transactionProofResponse.Treedepth != 1 || len(transactionProofResponse.Proof) != 32 {
+			t.Fatalf("unexpected transaction proof response: %+v", transactionProofResponse)
+		}
+
+		stateProofFile, err := os.Open("stateproofverification/state_proof_response" + format.Extension())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateProofFile.Close()
+
+		advancementData, err := ReadStateProofResponse(stateProofFile, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if advancementData.Message.LastAttestedRound != 16 || advancementData.StateProof == nil {
+			t.Fatalf("unexpected state proof response: %+v", advancementData.Message)
+		}
+	}
+
+	_, err := ReadLightBlockHeaderProofResponse(strings.NewReader("{"), JSONFormat)
+	if err == nil || !strings.Contains(err.Error(), "LightBlockHeaderProofResponse") {
+		t.Fatalf("expected the error to name LightBlockHeaderProofResponse, got %v", err)
+	}
+}
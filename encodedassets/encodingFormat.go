@@ -0,0 +1,68 @@
+package encodedassets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+)
+
+// EncodingFormat is the format in which an Algorand node encodes its API responses.
+type EncodingFormat int
+
+const (
+	// JSONFormat is the format algod responds in by default.
+	JSONFormat EncodingFormat = iota
+	// MsgpackFormat is the format algod responds in when queried with format=msgpack.
+	MsgpackFormat
+)
+
+var (
+	ErrUnknownEncodingFormat = errors.New("encoding format is unknown")
+)
+
+// Extension returns the file extension of assets encoded in the format, including the leading dot.
+func (f EncodingFormat) Extension() string {
+	switch f {
+	case JSONFormat:
+		return ".json"
+	case MsgpackFormat:
+		return ".msgp"
+	default:
+		return ""
+	}
+}
+
+// GetEncodingFormat returns the format of an encoded file, determined by the file's extension.
+// Parameters:
+// fileName - the name of the encoded file.
+func GetEncodingFormat(fileName string) (EncodingFormat, error) {
+	switch path.Ext(fileName) {
+	case JSONFormat.Extension():
+		return JSONFormat, nil
+	case MsgpackFormat.Extension():
+		return MsgpackFormat, nil
+	default:
+		return 0, ErrUnknownEncodingFormat
+	}
+}
+
+// DecodeResponse decodes an API response returned by an Algorand node in the given format. JSON responses are keyed by
+// the models' json tags, and msgpack responses use the canonical msgpack encoding. As with the SDK's own REST client,
+// unknown fields are ignored in both formats, so that responses of newer nodes can still be decoded.
+// Parameters:
+// encodedData - the encoded response.
+// format - the format the response is encoded in.
+// target - a pointer to the value to decode the response into.
+func DecodeResponse(encodedData []byte, format EncodingFormat, target interface{}) error {
+	switch format {
+	case JSONFormat:
+		return json.Unmarshal(encodedData, target)
+	case MsgpackFormat:
+		return msgpack.NewLenientDecoder(bytes.NewReader(encodedData)).Decode(target)
+	default:
+		return ErrUnknownEncodingFormat
+	}
+}
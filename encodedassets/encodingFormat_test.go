@@ -0,0 +1,142 @@
+package encodedassets
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// pairedAssetTypes maps the names of assets recorded in both formats, without their extension, to a function
+// returning a pointer to the type they decode into.
+var pairedAssetTypes = map[string]func() interface{}{
+	"transaction_proof_response":        func() interface{} { return &models.TransactionProofResponse{} },
+	"light_block_header_proof_response": func() interface{} { return &models.LightBlockHeaderProof{} },
+	"state_proof_message":               func() interface{} { return &types.Message{} },
+	"state_proof_response":              func() interface{} { return &models.StateProof{} },
+}
+
+func TestEncodingFormatPairsDecodeEqually(t *testing.T) {
+	fsys := os.DirFS(".")
+	jsonAssets, err := fs.Glob(fsys, "*/*"+JSONFormat.Extension())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := 0
+	for _, jsonAsset := range jsonAssets {
+		msgpackAsset := strings.TrimSuffix(jsonAsset, JSONFormat.Extension()) + MsgpackFormat.Extension()
+		if _, err := fs.Stat(fsys, msgpackAsset); err != nil {
+			continue
+		}
+
+		pairs++
+		t.Run(jsonAsset, func(t *testing.T) {
+			newTarget, exists := pairedAssetTypes[strings.TrimSuffix(path.Base(jsonAsset), JSONFormat.Extension())]
+			if !exists {
+				t.Fatalf("no type is known for %s", jsonAsset)
+			}
+
+			jsonTarget, msgpackTarget := newTarget(), newTarget()
+			err := decodeAsset(fsys, jsonAsset, "JSON", JSONFormat, jsonTarget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = decodeAsset(fsys, msgpackAsset, "msgpack", MsgpackFormat, msgpackTarget)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reflect.DeepEqual(jsonTarget, newTarget()) {
+				t.Fatalf("%s decoded into a zero value", jsonAsset)
+			}
+			if !reflect.DeepEqual(jsonTarget, msgpackTarget) {
+				t.Fatalf("%s and %s decode differently:\n%+v\n%+v", jsonAsset, msgpackAsset, jsonTarget, msgpackTarget)
+			}
+		})
+	}
+
+	if pairs != len(pairedAssetTypes) {
+		t.Fatalf("expected %d asset pairs, found %d", len(pairedAssetTypes), pairs)
+	}
+}
+
+func TestLoadersDecodeFormatsEqually(t *testing.T) {
+	jsonData, err := LoadTransactionVerificationData(os.DirFS("transactionverification"), JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgpackData, err := LoadTransactionVerificationData(os.DirFS("transactionverification"), MsgpackFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jsonData, msgpackData) {
+		t.Fatalf("transaction verification data decodes differently:\n%+v\n%+v", jsonData, msgpackData)
+	}
+
+	jsonStateProof, err := LoadStateProofResponse(os.DirFS("stateproofverification"), JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgpackStateProof, err := LoadStateProofResponse(os.DirFS("stateproofverification"), MsgpackFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jsonStateProof, msgpackStateProof) {
+		t.Fatal("state proof responses decode differently")
+	}
+}
+
+func TestDecodeResponseIgnoresUnknownFields(t *testing.T) {
+	expected := models.LightBlockHeaderProof{Index: 3, Proof: []byte{1, 2, 3}, Treedepth: 2}
+
+	// Both encodings hold the proof's fields along with a field added by a newer node, in a map of 4 fields.
+	encodedResponses := map[EncodingFormat][]byte{
+		JSONFormat: []byte(`{"index":3,"proof":"AQID","treedepth":2,"added":true}`),
+		MsgpackFormat: append(append([]byte{0x84}, msgpack.Encode(expected)[1:]...),
+			0xa5, 'a', 'd', 'd', 'e', 'd', 0xc3),
+	}
+
+	for format, encodedResponse := range encodedResponses {
+		var decoded models.LightBlockHeaderProof
+		err := DecodeResponse(encodedResponse, format, &decoded)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Fatalf("format %d: expected %+v, got %+v", format, expected, decoded)
+		}
+	}
+}
+
+func TestGetEncodingFormat(t *testing.T) {
+	testCases := []struct {
+		fileName       string
+		expectedFormat EncodingFormat
+		expectedErr    error
+	}{
+		{"response.json", JSONFormat, nil},
+		{"dir.msgp/response.msgp", MsgpackFormat, nil},
+		{"response.msgpack", 0, ErrUnknownEncodingFormat},
+		{"response", 0, ErrUnknownEncodingFormat},
+	}
+
+	for _, testCase := range testCases {
+		format, err := GetEncodingFormat(testCase.fileName)
+		if err != testCase.expectedErr || format != testCase.expectedFormat {
+			t.Fatalf("%s: expected (%v, %v), got (%v, %v)", testCase.fileName, testCase.expectedFormat,
+				testCase.expectedErr, format, err)
+		}
+	}
+
+	err := DecodeResponse([]byte("{}"), EncodingFormat(-1), &models.StateProof{})
+	if err != ErrUnknownEncodingFormat {
+		t.Fatalf("expected %v, got %v", ErrUnknownEncodingFormat, err)
+	}
+}
@@ -0,0 +1,431 @@
+package encodedassets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproof"
+)
+
+// FixtureBundleVersion is the version of the fixture bundle format produced by this package. Bundles of any other
+// version are rejected.
+const FixtureBundleVersion = 1
+
+const (
+	// votersCommitmentSize is the size of a voters commitment, which is a Sumhash digest.
+	votersCommitmentSize = 64
+	// blockHeadersCommitmentSize is the size of a block headers commitment, which is a Sha256 digest.
+	blockHeadersCommitmentSize = 32
+)
+
+var (
+	ErrInvalidFixtureBundle = errors.New("fixture bundle violates its schema")
+)
+
+// ExpectedErrorNames are the names a transaction case's ExpectedError may hold. Each is the name of an error exported
+// by either the oracle or the transactionverifier package.
+var ExpectedErrorNames = []string{
+	"ErrTooEarlyRoundRequested",
+	"ErrNoStateProofForRound",
+	"ErrUnsupportedHashFunction",
+	"ErrProofLengthTreeDepthMismatch",
+	"ErrRootMismatch",
+	"ErrInvalidTreeDepth",
+	"ErrIndexDepthMismatch",
+	"ErrLightBlockHeaderTreeDepthMismatch",
+	"ErrLightBlockHeaderIndexMismatch",
+}
+
+// FixtureBundle holds every asset needed to demonstrate the light client in a single, self-describing file: the
+// genesis data, the network parameters, a sequence of state proofs and any number of transaction verification cases.
+// Bundles can be encoded using either JSON or msgpack.
+type FixtureBundle struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// Version is the version of the bundle format.
+	Version uint64 `codec:"version"`
+	// Genesis is the data required for initializing the Oracle.
+	Genesis FixtureGenesis `codec:"genesis"`
+	// Network holds the network's state proof parameters.
+	Network FixtureNetwork `codec:"network"`
+	// StateProofs are responses returned by an Algorand node when queried using GetStateProof, ordered by round. Each
+	// state proof must attest to the interval following the previous one's.
+	StateProofs []models.StateProof `codec:"state_proofs"`
+	// TransactionCases are transactions to verify using the Oracle's state, after it is advanced using StateProofs.
+	TransactionCases []FixtureTransactionCase `codec:"transaction_cases"`
+}
+
+// FixtureGenesis holds the data required for initializing the Oracle.
+type FixtureGenesis struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// VotersCommitment is the vector commitment root of the genesis voters.
+	VotersCommitment []byte `codec:"voters_commitment"`
+	// VotersLnProvenWeight is the natural log of the genesis voters' proven weight, with 16 bits of precision.
+	VotersLnProvenWeight uint64 `codec:"voters_ln_proven_weight"`
+}
+
+// FixtureNetwork holds the network's state proof parameters.
+type FixtureNetwork struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// FirstAttestedRound is the first round to which a state proof message attests.
+	FirstAttestedRound uint64 `codec:"first_attested_round"`
+	// IntervalSize is the number of rounds each state proof message attests to.
+	IntervalSize uint64 `codec:"interval_size"`
+}
+
+// FixtureTransactionCase is a transaction to verify, along with the name of the error verifying it must produce.
+type FixtureTransactionCase struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// Name identifies the case. It must be unique within the bundle.
+	Name string `codec:"name"`
+	// ExpectedError is the name of the error verifying the case must produce. It is empty if verification must succeed.
+	ExpectedError string `codec:"expected_error"`
+	// GenesisHash is the hash of the genesis block.
+	GenesisHash types.Digest `codec:"genesis_hash"`
+	// Round is the round in which the transaction was confirmed.
+	Round types.Round `codec:"round"`
+	// Seed is the sortition seed of the block in which the transaction was confirmed.
+	Seed types.Seed `codec:"seed"`
+	// TransactionID is the result of invoking Sha256 on the canonical msgpack encoded transaction.
+	TransactionID types.Digest `codec:"transaction_id"`
+	// TransactionProofResponse is the response returned by an Algorand node when queried using GetTransactionProof.
+	TransactionProofResponse models.TransactionProofResponse `codec:"transaction_proof_response"`
+	// LightBlockHeaderProofResponse is the response returned by an Algorand node when queried using GetLightBlockHeaderProof.
+	LightBlockHeaderProofResponse models.LightBlockHeaderProof `codec:"light_block_header_proof_response"`
+}
+
+// SchemaViolation describes a single way in which a fixture bundle violates its schema.
+type SchemaViolation struct {
+	// Path locates the offending value, e.g. state_proofs[1].Message.FirstAttestedRound.
+	Path string
+	// Description describes the violation.
+	Description string
+}
+
+func (v SchemaViolation) Error() string {
+	return v.Path + ": " + v.Description
+}
+
+// SchemaViolations is the error returned for a bundle violating its schema. It holds every violation found, rather
+// than only the first.
+type SchemaViolations []SchemaViolation
+
+func (v SchemaViolations) Error() string {
+	descriptions := make([]string, len(v))
+	for i, violation := range v {
+		descriptions[i] = violation.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidFixtureBundle, strings.Join(descriptions, "; "))
+}
+
+// Is allows callers to check for schema violations using errors.Is(err, ErrInvalidFixtureBundle).
+func (v SchemaViolations) Is(target error) bool {
+	return target == ErrInvalidFixtureBundle
+}
+
+// Validate checks the bundle against its schema, and returns every violation found. Violations are structural: a
+// transaction case expected to fail verification may hold any proof, as long as the proof is well formed.
+func (b *FixtureBundle) Validate() SchemaViolations {
+	var violations SchemaViolations
+	report := func(path string, format string, args ...interface{}) {
+		violations = append(violations, SchemaViolation{Path: path, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if b.Version != FixtureBundleVersion {
+		report("version", "must be %d, got %d", FixtureBundleVersion, b.Version)
+	}
+
+	if len(b.Genesis.VotersCommitment) != votersCommitmentSize {
+		report("genesis.voters_commitment", "must be %d bytes long, got %d", votersCommitmentSize, len(b.Genesis.VotersCommitment))
+	}
+
+	if b.Genesis.VotersLnProvenWeight == 0 {
+		report("genesis.voters_ln_proven_weight", "must be positive")
+	}
+
+	if b.Network.IntervalSize == 0 {
+		report("network.interval_size", "must be positive")
+	}
+
+	// Each state proof must attest to the interval following the previous one's, starting at the first attested round.
+	expectedFirstAttestedRound := b.Network.FirstAttestedRound
+	for i, stateProofResponse := range b.StateProofs {
+		path := fmt.Sprintf("state_proofs[%d]", i)
+		message := stateProofResponse.Message
+
+		if len(message.Blockheaderscommitment) != blockHeadersCommitmentSize {
+			report(path+".Message.BlockHeadersCommitment", "must be %d bytes long, got %d", blockHeadersCommitmentSize,
+				len(message.Blockheaderscommitment))
+		}
+
+		if len(message.Voterscommitment) != votersCommitmentSize {
+			report(path+".Message.VotersCommitment", "must be %d bytes long, got %d", votersCommitmentSize,
+				len(message.Voterscommitment))
+		}
+
+		if message.Firstattestedround != expectedFirstAttestedRound {
+			report(path+".Message.FirstAttestedRound", "must be %d, got %d", expectedFirstAttestedRound, message.Firstattestedround)
+		}
+
+		expectedLastAttestedRound := expectedFirstAttestedRound + b.Network.IntervalSize - 1
+		if message.Lastattestedround != expectedLastAttestedRound {
+			report(path+".Message.LastAttestedRound", "must be %d, got %d", expectedLastAttestedRound, message.Lastattestedround)
+		}
+
+		var decodedStateProof stateproof.StateProof
+		err := msgpack.Decode(stateProofResponse.Stateproof, &decodedStateProof)
+		if err != nil {
+			report(path+".StateProof", "must be a msgpack encoded state proof: %s", err)
+		}
+
+		expectedFirstAttestedRound += b.Network.IntervalSize
+	}
+
+	caseIndices := make(map[string]int)
+	for i, transactionCase := range b.TransactionCases {
+		path := fmt.Sprintf("transaction_cases[%d]", i)
+
+		if transactionCase.Name == "" {
+			report(path+".name", "must not be empty")
+		} else if firstIndex, exists := caseIndices[transactionCase.Name]; exists {
+			report(path+".name", "must be unique, %q is also the name of transaction_cases[%d]", transactionCase.Name, firstIndex)
+		} else {
+			caseIndices[transactionCase.Name] = i
+		}
+
+		if transactionCase.ExpectedError != "" && !isExpectedErrorName(transactionCase.ExpectedError) {
+			report(path+".expected_error", "must be empty or one of %s, got %q", strings.Join(ExpectedErrorNames, ", "),
+				transactionCase.ExpectedError)
+		}
+
+		if len(transactionCase.TransactionProofResponse.Stibhash) != len(types.Digest{}) {
+			report(path+".transaction_proof_response.stibhash", "must be %d bytes long, got %d", len(types.Digest{}),
+				len(transactionCase.TransactionProofResponse.Stibhash))
+		}
+	}
+
+	return violations
+}
+
+// isExpectedErrorName returns true if the name is one of ExpectedErrorNames.
+// Parameters:
+// name - the name to look up.
+func isExpectedErrorName(name string) bool {
+	for _, expectedErrorName := range ExpectedErrorNames {
+		if name == expectedErrorName {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeFixtureBundle encodes the bundle in the given format. JSON bundles are indented, and encode byte fields using
+// base64.
+// Parameters:
+// bundle - the bundle to encode.
+// format - the format to encode the bundle in.
+func EncodeFixtureBundle(bundle *FixtureBundle, format EncodingFormat) ([]byte, error) {
+	switch format {
+	case JSONFormat:
+		return json.Encode(bundle), nil
+	case MsgpackFormat:
+		return msgpack.Encode(bundle), nil
+	default:
+		return nil, ErrUnknownEncodingFormat
+	}
+}
+
+// DecodeFixtureBundle decodes a bundle encoded in the given format and validates it. Unknown fields are rejected. If
+// the bundle violates its schema, the returned error is a SchemaViolations holding every violation.
+// Parameters:
+// encodedBundle - the encoded bundle.
+// format - the format the bundle is encoded in.
+func DecodeFixtureBundle(encodedBundle []byte, format EncodingFormat) (*FixtureBundle, error) {
+	var bundle FixtureBundle
+	var err error
+	switch format {
+	case JSONFormat:
+		err = json.Decode(encodedBundle, &bundle)
+	case MsgpackFormat:
+		err = msgpack.Decode(encodedBundle, &bundle)
+	default:
+		return nil, ErrUnknownEncodingFormat
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFixtureBundle, err)
+	}
+
+	violations := bundle.Validate()
+	if len(violations) != 0 {
+		return nil, violations
+	}
+
+	return &bundle, nil
+}
+
+// ReadFixtureBundle reads a bundle encoded in the given format from the given reader, and validates it.
+// Parameters:
+// reader - the reader to read the encoded bundle from. It is read until EOF.
+// format - the format the bundle is encoded in.
+func ReadFixtureBundle(reader io.Reader, format EncodingFormat) (*FixtureBundle, error) {
+	encodedBundle, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture bundle: %w", err)
+	}
+
+	return DecodeFixtureBundle(encodedBundle, format)
+}
+
+// LoadFixtureBundle loads a bundle from the given file system, and validates it. The bundle's format is determined by
+// its file extension.
+// Parameters:
+// fsys - the file system holding the bundle.
+// fileName - the name of the bundle file.
+func LoadFixtureBundle(fsys fs.FS, fileName string) (*FixtureBundle, error) {
+	format, err := GetEncodingFormat(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedBundle, err := fs.ReadFile(fsys, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture bundle %s: %w", fileName, err)
+	}
+
+	bundle, err := DecodeFixtureBundle(encodedBundle, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixture bundle %s: %w", fileName, err)
+	}
+
+	return bundle, nil
+}
+
+// adversarialCase is the encoding of the tampered transaction verification cases in the directory layout's
+// adversarialverification/adversarial_cases.json, which is written by adversarialverification/generate.go.
+// ConvertDirectoryLayout converts each of them into a FixtureTransactionCase.
+type adversarialCase struct {
+	Name                          string                          `json:"name"`
+	ExpectedError                 string                          `json:"expected_error"`
+	GenesisHash                   types.Digest                    `json:"genesis_hash"`
+	Round                         types.Round                     `json:"round"`
+	Seed                          types.Seed                      `json:"seed"`
+	TransactionID                 types.Digest                    `json:"transaction_id"`
+	TransactionProofResponse      models.TransactionProofResponse `json:"transaction_proof_response"`
+	LightBlockHeaderProofResponse models.LightBlockHeaderProof    `json:"light_block_header_proof_response"`
+}
+
+// ConvertDirectoryLayout converts the assets in the directory layout, where every value is held by its own file, into
+// a single bundle. The network parameters are derived from the state proof message.
+// Parameters:
+// fsys - a file system holding the genesis, stateproofverification, transactionverification and
+// adversarialverification directories at its root.
+// format - the format the proof responses and state proof message are encoded in.
+func ConvertDirectoryLayout(fsys fs.FS, format EncodingFormat) (*FixtureBundle, error) {
+	subdirectory := func(name string) (fs.FS, error) {
+		subdirectoryFS, err := fs.Sub(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		return subdirectoryFS, nil
+	}
+
+	genesisFS, err := subdirectory("genesis")
+	if err != nil {
+		return nil, err
+	}
+	genesisData, err := LoadGenesisData(genesisFS)
+	if err != nil {
+		return nil, err
+	}
+
+	stateProofFS, err := subdirectory("stateproofverification")
+	if err != nil {
+		return nil, err
+	}
+	stateProofData, err := LoadStateProofAdvancementData(stateProofFS, format)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionFS, err := subdirectory("transactionverification")
+	if err != nil {
+		return nil, err
+	}
+	transactionData, err := LoadTransactionVerificationData(transactionFS, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var adversarialCases []adversarialCase
+	err = decodeAsset(fsys, "adversarialverification/adversarial_cases.json", "AdversarialCases", JSONFormat, &adversarialCases)
+	if err != nil {
+		return nil, err
+	}
+
+	message := stateProofData.Message
+	bundle := &FixtureBundle{
+		Version: FixtureBundleVersion,
+		Genesis: FixtureGenesis{
+			VotersCommitment:     genesisData.VotersCommitment,
+			VotersLnProvenWeight: genesisData.VotersLnProvenWeight,
+		},
+		Network: FixtureNetwork{
+			FirstAttestedRound: message.FirstAttestedRound,
+			IntervalSize:       message.LastAttestedRound - message.FirstAttestedRound + 1,
+		},
+		StateProofs: []models.StateProof{
+			{
+				Message: models.StateProofMessage{
+					Blockheaderscommitment: message.BlockHeadersCommitment,
+					Firstattestedround:     message.FirstAttestedRound,
+					Lastattestedround:      message.LastAttestedRound,
+					Lnprovenweight:         message.LnProvenWeight,
+					Voterscommitment:       message.VotersCommitment,
+				},
+				Stateproof: msgpack.Encode(stateProofData.StateProof),
+			},
+		},
+	}
+
+	bundle.TransactionCases = append(bundle.TransactionCases, FixtureTransactionCase{
+		Name:                          "valid transaction",
+		GenesisHash:                   transactionData.GenesisHash,
+		Round:                         transactionData.Round,
+		Seed:                          transactionData.Seed,
+		TransactionID:                 transactionData.TransactionID,
+		TransactionProofResponse:      transactionData.TransactionProofResponse,
+		LightBlockHeaderProofResponse: transactionData.LightBlockHeaderProofResponse,
+	})
+
+	for _, encodedCase := range adversarialCases {
+		bundle.TransactionCases = append(bundle.TransactionCases, FixtureTransactionCase{
+			Name:                          encodedCase.Name,
+			ExpectedError:                 encodedCase.ExpectedError,
+			GenesisHash:                   encodedCase.GenesisHash,
+			Round:                         encodedCase.Round,
+			Seed:                          encodedCase.Seed,
+			TransactionID:                 encodedCase.TransactionID,
+			TransactionProofResponse:      encodedCase.TransactionProofResponse,
+			LightBlockHeaderProofResponse: encodedCase.LightBlockHeaderProofResponse,
+		})
+	}
+
+	violations := bundle.Validate()
+	if len(violations) != 0 {
+		return nil, violations
+	}
+
+	return bundle, nil
+}
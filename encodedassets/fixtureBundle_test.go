@@ -0,0 +1,160 @@
+package encodedassets
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func loadTestFixtureBundle(t *testing.T) *FixtureBundle {
+	t.Helper()
+
+	fixtureBundle, err := LoadFixtureBundle(os.DirFS("fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtureBundle
+}
+
+func TestValidateRejectsUnknownExpectedError(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	fixtureBundle.TransactionCases[1].ExpectedError = "ErrUnknown"
+
+	violations := fixtureBundle.Validate()
+	if len(violations) != 1 || violations[0].Path != "transaction_cases[1].expected_error" {
+		t.Fatalf("expected a single violation of transaction_cases[1].expected_error, got %v", violations)
+	}
+	if !errors.Is(violations, ErrInvalidFixtureBundle) {
+		t.Fatalf("expected %v, got %v", ErrInvalidFixtureBundle, violations)
+	}
+}
+
+func TestFixtureBundleRoundTrip(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+
+	for _, format := range []EncodingFormat{JSONFormat, MsgpackFormat} {
+		encodedBundle, err := EncodeFixtureBundle(fixtureBundle, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decodedBundle, err := ReadFixtureBundle(bytes.NewReader(encodedBundle), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decodedBundle, fixtureBundle) {
+			t.Fatalf("%s: decoded bundle differs from the encoded one", format.Extension())
+		}
+	}
+
+	_, err := EncodeFixtureBundle(fixtureBundle, EncodingFormat(-1))
+	if !errors.Is(err, ErrUnknownEncodingFormat) {
+		t.Fatalf("expected %v, got %v", ErrUnknownEncodingFormat, err)
+	}
+	_, err = DecodeFixtureBundle(nil, EncodingFormat(-1))
+	if !errors.Is(err, ErrUnknownEncodingFormat) {
+		t.Fatalf("expected %v, got %v", ErrUnknownEncodingFormat, err)
+	}
+}
+
+func TestConvertDirectoryLayoutMatchesBundle(t *testing.T) {
+	// The bundle was written by fixturebundle/convert.go, so converting the directory layout must reproduce it.
+	for _, format := range []EncodingFormat{JSONFormat, MsgpackFormat} {
+		convertedBundle, err := ConvertDirectoryLayout(os.DirFS("."), format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(convertedBundle, loadTestFixtureBundle(t)) {
+			t.Fatalf("%s: converted bundle differs from fixtures.json", format.Extension())
+		}
+	}
+
+	fsys := copyToMapFS(t, "genesis")
+	_, err := ConvertDirectoryLayout(fsys, JSONFormat)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	fixtureBundle.Version = FixtureBundleVersion + 1
+	fixtureBundle.Genesis.VotersCommitment = fixtureBundle.Genesis.VotersCommitment[1:]
+	fixtureBundle.Genesis.VotersLnProvenWeight = 0
+	fixtureBundle.StateProofs[0].Message.Firstattestedround++
+	fixtureBundle.StateProofs[0].Stateproof = []byte{0xc1}
+	fixtureBundle.TransactionCases[1].Name = fixtureBundle.TransactionCases[0].Name
+	fixtureBundle.TransactionCases[2].Name = ""
+	fixtureBundle.TransactionCases[0].TransactionProofResponse.Stibhash = nil
+
+	expectedPaths := []string{
+		"version",
+		"genesis.voters_commitment",
+		"genesis.voters_ln_proven_weight",
+		"state_proofs[0].Message.FirstAttestedRound",
+		"state_proofs[0].StateProof",
+		"transaction_cases[0].transaction_proof_response.stibhash",
+		"transaction_cases[1].name",
+		"transaction_cases[2].name",
+	}
+
+	violations := fixtureBundle.Validate()
+	if len(violations) != len(expectedPaths) {
+		t.Fatalf("expected %d violations, got %v", len(expectedPaths), violations)
+	}
+	for i, violation := range violations {
+		if violation.Path != expectedPaths[i] {
+			t.Fatalf("expected violation %d to be of %s, got %v", i, expectedPaths[i], violation)
+		}
+	}
+
+	// Decoding an invalid bundle returns every violation.
+	encodedBundle, err := EncodeFixtureBundle(fixtureBundle, JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DecodeFixtureBundle(encodedBundle, JSONFormat)
+	var decodeViolations SchemaViolations
+	if !errors.As(err, &decodeViolations) || len(decodeViolations) != len(expectedPaths) {
+		t.Fatalf("expected %d schema violations, got %v", len(expectedPaths), err)
+	}
+}
+
+func TestLoadFixtureBundleErrors(t *testing.T) {
+	testCases := map[string]struct {
+		fsys        fstest.MapFS
+		fileName    string
+		expectedErr error
+	}{
+		"missing file": {fstest.MapFS{}, "fixtures.json", fs.ErrNotExist},
+		"unknown extension": {fstest.MapFS{"fixtures.yaml": &fstest.MapFile{}}, "fixtures.yaml",
+			ErrUnknownEncodingFormat},
+		"malformed json": {fstest.MapFS{"fixtures.json": &fstest.MapFile{Data: []byte("{")}}, "fixtures.json",
+			ErrInvalidFixtureBundle},
+	}
+
+	// A valid bundle holding an unknown field is rejected when decoded, rather than by its validation.
+	encodedBundle, err := os.ReadFile("fixturebundle/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedBundle = append([]byte(`{"unknown": 1,`), bytes.TrimPrefix(bytes.TrimSpace(encodedBundle), []byte("{"))...)
+	_, err = DecodeFixtureBundle(encodedBundle, JSONFormat)
+	var violations SchemaViolations
+	if !errors.Is(err, ErrInvalidFixtureBundle) || errors.As(err, &violations) {
+		t.Fatalf("expected a decoding error, got %v", err)
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFixtureBundle(testCase.fsys, testCase.fileName)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+//go:build ignore
+
+// This program converts the assets in the directory layout into a single fixture bundle, fixtures.json, and validates
+// the result. Run it from the repository's root using
+// go run encodedassets/fixturebundle/convert.go
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+)
+
+func main() {
+	bundle, err := encodedassets.ConvertDirectoryLayout(os.DirFS("encodedassets"), encodedassets.JSONFormat)
+	if err != nil {
+		fmt.Printf("Failed to convert the assets into a fixture bundle: %s\n", err)
+		os.Exit(1)
+	}
+
+	encodedBundle, err := encodedassets.EncodeFixtureBundle(bundle, encodedassets.JSONFormat)
+	if err != nil {
+		fmt.Printf("Failed to encode the fixture bundle: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile("encodedassets/fixturebundle/fixtures.json", append(encodedBundle, '\n'), 0644)
+	if err != nil {
+		fmt.Printf("Failed to write the fixture bundle: %s\n", err)
+		os.Exit(1)
+	}
+
+	// We make sure the written bundle loads and validates exactly as any other bundle would.
+	_, err = encodedassets.LoadFixtureBundle(os.DirFS("encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		fmt.Printf("Failed to load the written fixture bundle: %s\n", err)
+		os.Exit(1)
+	}
+}
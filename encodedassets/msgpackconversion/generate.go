@@ -0,0 +1,89 @@
+//go:build ignore
+
+// This program generates the msgpack encoded assets, along with the state proof response assets, from the JSON
+// encoded assets. algod encodes msgpack responses canonically, so the generated assets are identical to the ones algod
+// would have returned. Run it from the repository's root using
+// go run encodedassets/msgpackconversion/generate.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+)
+
+// writeAsset writes the given value to the given path, without an extension, in the given format.
+func writeAsset(assetPath string, format encodedassets.EncodingFormat, value interface{}) error {
+	var encodedValue []byte
+	switch format {
+	case encodedassets.JSONFormat:
+		var err error
+		encodedValue, err = json.Marshal(value)
+		if err != nil {
+			return err
+		}
+	case encodedassets.MsgpackFormat:
+		encodedValue = msgpack.Encode(value)
+	default:
+		return encodedassets.ErrUnknownEncodingFormat
+	}
+
+	return os.WriteFile(assetPath+format.Extension(), encodedValue, 0644)
+}
+
+func main() {
+	transactionVerificationPath := "encodedassets/transactionverification/"
+	stateProofVerificationPath := "encodedassets/stateproofverification/"
+
+	_, _, _, _, transactionProofResponse, lightBlockHeaderProofResponse, err :=
+		encodedassets.GetParsedTypesData(transactionVerificationPath, encodedassets.JSONFormat)
+	if err != nil {
+		fmt.Printf("Failed to parse assets needed for transaction verification: %s\n", err)
+		os.Exit(1)
+	}
+
+	stateProofMessage, stateProof, err :=
+		encodedassets.GetParsedStateProofAdvancmentData(stateProofVerificationPath, encodedassets.JSONFormat)
+	if err != nil {
+		fmt.Printf("Failed to parse assets needed for oracle state advancement: %s\n", err)
+		os.Exit(1)
+	}
+
+	// The state proof response is assembled exactly as algod assembles it when queried using GetStateProof.
+	stateProofResponse := models.StateProof{
+		Message: models.StateProofMessage{
+			Blockheaderscommitment: stateProofMessage.BlockHeadersCommitment,
+			Firstattestedround:     stateProofMessage.FirstAttestedRound,
+			Lastattestedround:      stateProofMessage.LastAttestedRound,
+			Lnprovenweight:         stateProofMessage.LnProvenWeight,
+			Voterscommitment:       stateProofMessage.VotersCommitment,
+		},
+		Stateproof: msgpack.Encode(stateProof),
+	}
+
+	assets := []struct {
+		path   string
+		format encodedassets.EncodingFormat
+		value  interface{}
+	}{
+		{filepath.Join(transactionVerificationPath, "transaction_proof_response"), encodedassets.MsgpackFormat, transactionProofResponse},
+		{filepath.Join(transactionVerificationPath, "light_block_header_proof_response"), encodedassets.MsgpackFormat, lightBlockHeaderProofResponse},
+		{filepath.Join(stateProofVerificationPath, "state_proof_message"), encodedassets.MsgpackFormat, stateProofMessage},
+		{filepath.Join(stateProofVerificationPath, "state_proof_response"), encodedassets.JSONFormat, stateProofResponse},
+		{filepath.Join(stateProofVerificationPath, "state_proof_response"), encodedassets.MsgpackFormat, stateProofResponse},
+	}
+
+	for _, asset := range assets {
+		err = writeAsset(asset.path, asset.format, asset.value)
+		if err != nil {
+			fmt.Printf("Failed to write %s%s: %s\n", asset.path, asset.format.Extension(), err)
+			os.Exit(1)
+		}
+	}
+}
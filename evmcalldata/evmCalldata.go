@@ -0,0 +1,185 @@
+package evmcalldata
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// VerifyTransactionSignature is the signature of the on-chain verifier's function, whose calldata is produced by
+// EncodeVerifyTransaction. Its parameters, in order, are:
+// transactionHash - Sha256 of the canonical msgpack encoded transaction.
+// stibHash - Sha256 of the canonical msgpack encoded transaction as it's saved in the block.
+// transactionIndex, transactionTreeDepth, transactionProof - the transaction's vector commitment index, tree depth and
+// sibling hashes, ordered from the leaf up.
+// lightBlockHeaderIndex, lightBlockHeaderTreeDepth, lightBlockHeaderProof - the same, for the light block header.
+// round, genesisHash, seed - the light block header's fields, other than the transaction commitment, which is computed.
+// intervalIndex - the index of the interval whose block interval commitment the contract verifies against.
+const VerifyTransactionSignature = "verifyTransaction(bytes32,bytes32,uint64,uint64,bytes32[],uint64,uint64,bytes32[]," +
+	"uint64,bytes32,bytes32,uint64)"
+
+var (
+	ErrInvalidStibHashLength = errors.New("stib hash is not 32 bytes long")
+)
+
+// wordSize is the size of an ABI word, in which every static value is encoded.
+const wordSize = 32
+
+// VerifyTransactionCall holds everything an on-chain verifier needs in order to verify a transaction.
+type VerifyTransactionCall struct {
+	// Calldata is the ABI encoded call to VerifyTransactionSignature, starting with the function's selector.
+	Calldata []byte
+	// IntervalIndex is the index of the interval the contract verifies against.
+	IntervalIndex uint64
+	// TransactionCommitment is the root computed from the transaction proof, i.e. the light block header's
+	// Sha256TxnCommitment.
+	TransactionCommitment types.Digest
+	// LightBlockHeaderPreimage is the data the contract must hash using sha256 to compute the light block header's
+	// leaf, which is of the form "B256" || msgpack(lightBlockHeader). See transactionverifier.GetLightBlockHeaderPreimage.
+	LightBlockHeaderPreimage []byte
+}
+
+// GetFunctionSelector returns the selector of a Solidity function, which is the first 4 bytes of the Keccak256 of its
+// signature.
+// Parameters:
+// signature - the function's canonical signature, e.g. VerifyTransactionSignature.
+func GetFunctionSelector(signature string) [4]byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(signature))
+
+	var selector [4]byte
+	copy(selector[:], hasher.Sum(nil))
+	return selector
+}
+
+// abiArgument is an ABI encoded argument. Static arguments are encoded in place, while dynamic arguments are encoded
+// after every argument's head, which holds their offset instead.
+type abiArgument struct {
+	encoding []byte
+	dynamic  bool
+}
+
+// encodeUint64 encodes a uint64 as a uint64 ABI argument, which is left padded to a word.
+func encodeUint64(value uint64) abiArgument {
+	encoding := make([]byte, wordSize)
+	binary.BigEndian.PutUint64(encoding[wordSize-8:], value)
+	return abiArgument{encoding: encoding}
+}
+
+// encodeBytes32 encodes a 32 byte value as a bytes32 ABI argument.
+func encodeBytes32(value [32]byte) abiArgument {
+	return abiArgument{encoding: append([]byte{}, value[:]...)}
+}
+
+// encodeBytes32Array encodes a concatenation of 32 byte values as a bytes32[] ABI argument, which is its length in
+// elements followed by the elements. The proof's length must be a multiple of 32.
+func encodeBytes32Array(proof []byte) abiArgument {
+	encoding := encodeUint64(uint64(len(proof) / wordSize)).encoding
+	return abiArgument{encoding: append(encoding, proof...), dynamic: true}
+}
+
+// encodeArguments encodes the arguments of a call, following the function's selector.
+// Parameters:
+// arguments - the ABI encoded arguments, in the order of the function's parameters.
+func encodeArguments(arguments []abiArgument) []byte {
+	heads := make([]byte, 0, len(arguments)*wordSize)
+	var tails []byte
+	for _, argument := range arguments {
+		if !argument.dynamic {
+			heads = append(heads, argument.encoding...)
+			continue
+		}
+
+		// Offsets are counted from the start of the arguments, not including the selector.
+		heads = append(heads, encodeUint64(uint64(len(arguments)*wordSize+len(tails))).encoding...)
+		tails = append(tails, argument.encoding...)
+	}
+
+	return append(heads, tails...)
+}
+
+// EncodeVerifyTransaction encodes the calldata of a call to VerifyTransactionSignature, verifying a sha256 hashed
+// transaction, and the light block header preimage the contract hashes. It fails on proofs that can never pass
+// verification, e.g. proofs whose lengths do not match their tree depths, but does not verify the transaction.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// confirmedRound - the round in which the given transaction was confirmed.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block associated with the light block header.
+// intervalIndex - the index of the interval the confirmed round belongs to.
+func EncodeVerifyTransaction(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest,
+	seed types.Seed, intervalIndex uint64) (*VerifyTransactionCall, error) {
+	// bytes32[] arguments must consist of whole words, and the contract expects one word for every level.
+	// We divide rather than multiply, so that huge tree depths cannot overflow into a match.
+	if len(transactionProofResponse.Proof)%wordSize != 0 ||
+		uint64(len(transactionProofResponse.Proof)/wordSize) != transactionProofResponse.Treedepth ||
+		len(lightBlockHeaderProofResponse.Proof)%wordSize != 0 ||
+		uint64(len(lightBlockHeaderProofResponse.Proof)/wordSize) != lightBlockHeaderProofResponse.Treedepth {
+		return nil, transactionverifier.ErrProofLengthTreeDepthMismatch
+	}
+
+	var stibHash types.Digest
+	if len(transactionProofResponse.Stibhash) != len(stibHash) {
+		return nil, ErrInvalidStibHashLength
+	}
+	copy(stibHash[:], transactionProofResponse.Stibhash)
+
+	transactionCommitment, err := transactionverifier.ComputeTransactionCommitment(transactionHash,
+		transactionProofResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := GetFunctionSelector(VerifyTransactionSignature)
+	calldata := append(selector[:], encodeArguments([]abiArgument{
+		encodeBytes32(transactionHash),
+		encodeBytes32(stibHash),
+		encodeUint64(transactionProofResponse.Idx),
+		encodeUint64(transactionProofResponse.Treedepth),
+		encodeBytes32Array(transactionProofResponse.Proof),
+		encodeUint64(lightBlockHeaderProofResponse.Index),
+		encodeUint64(lightBlockHeaderProofResponse.Treedepth),
+		encodeBytes32Array(lightBlockHeaderProofResponse.Proof),
+		encodeUint64(uint64(confirmedRound)),
+		encodeBytes32(genesisHash),
+		encodeBytes32(seed),
+		encodeUint64(intervalIndex),
+	})...)
+
+	return &VerifyTransactionCall{
+		Calldata:              calldata,
+		IntervalIndex:         intervalIndex,
+		TransactionCommitment: transactionCommitment,
+		LightBlockHeaderPreimage: transactionverifier.GetLightBlockHeaderPreimage(confirmedRound,
+			transactionCommitment, genesisHash, seed),
+	}, nil
+}
+
+// EncodeInclusionProofBundle encodes the calldata verifying an inclusion proof bundle's transaction, see
+// EncodeVerifyTransaction.
+// Parameters:
+// bundle - the bundle proving the transaction's occurrence.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the contract.
+// intervalSize - the number of rounds each state proof message attests to, as used by the contract.
+func EncodeInclusionProofBundle(bundle *inclusionbundle.InclusionProofBundle, firstAttestedRound uint64,
+	intervalSize uint64) (*VerifyTransactionCall, error) {
+	if intervalSize == 0 {
+		return nil, transactionverifier.ErrInvalidIntervalSize
+	}
+	if uint64(bundle.Round) < firstAttestedRound {
+		return nil, transactionverifier.ErrRoundNotAttested
+	}
+
+	intervalIndex := (uint64(bundle.Round) - firstAttestedRound) / intervalSize
+	return EncodeVerifyTransaction(bundle.TransactionHash, bundle.TransactionProof, bundle.LightBlockHeaderProof,
+		bundle.Round, bundle.GenesisHash, bundle.Seed, intervalIndex)
+}
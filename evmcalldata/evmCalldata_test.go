@@ -0,0 +1,230 @@
+package evmcalldata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// loadTestBundle returns the fixture bundle's valid transaction case as an inclusion proof bundle, along with the
+// fixture network's first attested round and interval size.
+func loadTestBundle(t *testing.T) (*inclusionbundle.InclusionProofBundle, uint64, uint64) {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		bundle := inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID,
+			transactionCase.TransactionProofResponse, transactionCase.LightBlockHeaderProofResponse,
+			transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed)
+		return bundle, fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return nil, 0, 0
+}
+
+// checkGolden compares data to the hex encoded golden file at testdata/name, rewriting it if -update is set.
+func checkGolden(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		err := os.WriteFile(path, []byte(hex.EncodeToString(data)+"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	encodedGolden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := hex.DecodeString(strings.TrimSpace(string(encodedGolden)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, golden) {
+		t.Fatalf("%s: expected %x, got %x", name, golden, data)
+	}
+}
+
+// getWord returns the index'th ABI word of the calldata's arguments, following the selector.
+func getWord(calldata []byte, index int) []byte {
+	return calldata[4+index*wordSize : 4+(index+1)*wordSize]
+}
+
+func TestGetFunctionSelector(t *testing.T) {
+	expectedSelector := [4]byte{0x49, 0xc7, 0x3e, 0xb5}
+	selector := GetFunctionSelector(VerifyTransactionSignature)
+	if selector != expectedSelector {
+		t.Fatalf("expected %x, got %x", expectedSelector, selector)
+	}
+
+	// A well known selector, pinning the hash function to Keccak256 rather than the standardized SHA3-256.
+	expectedSelector = [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+	selector = GetFunctionSelector("transfer(address,uint256)")
+	if selector != expectedSelector {
+		t.Fatalf("expected %x, got %x", expectedSelector, selector)
+	}
+}
+
+func TestEncodeInclusionProofBundleGolden(t *testing.T) {
+	bundle, firstAttestedRound, intervalSize := loadTestBundle(t)
+
+	call, err := EncodeInclusionProofBundle(bundle, firstAttestedRound, intervalSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "calldata.hex", call.Calldata)
+	checkGolden(t, "lightBlockHeaderPreimage.hex", call.LightBlockHeaderPreimage)
+
+	expectedIntervalIndex := (uint64(bundle.Round) - firstAttestedRound) / intervalSize
+	if call.IntervalIndex != expectedIntervalIndex {
+		t.Fatalf("expected %d, got %d", expectedIntervalIndex, call.IntervalIndex)
+	}
+	expectedPreimage := transactionverifier.GetLightBlockHeaderPreimage(bundle.Round, call.TransactionCommitment,
+		bundle.GenesisHash, bundle.Seed)
+	if !bytes.Equal(call.LightBlockHeaderPreimage, expectedPreimage) {
+		t.Fatalf("expected %x, got %x", expectedPreimage, call.LightBlockHeaderPreimage)
+	}
+}
+
+func TestEncodeVerifyTransactionLayout(t *testing.T) {
+	bundle, firstAttestedRound, intervalSize := loadTestBundle(t)
+
+	call, err := EncodeInclusionProofBundle(bundle, firstAttestedRound, intervalSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calldata := call.Calldata
+
+	selector := GetFunctionSelector(VerifyTransactionSignature)
+	if !bytes.Equal(calldata[:4], selector[:]) {
+		t.Fatalf("expected %x, got %x", selector, calldata[:4])
+	}
+
+	const argumentCount = 12
+	transactionProofLength := len(bundle.TransactionProof.Proof)
+	lightBlockHeaderProofLength := len(bundle.LightBlockHeaderProof.Proof)
+	expectedLength := 4 + (argumentCount+2)*wordSize + transactionProofLength + lightBlockHeaderProofLength
+	if len(calldata) != expectedLength {
+		t.Fatalf("expected %d, got %d", expectedLength, len(calldata))
+	}
+
+	wordToUint64 := func(index int) uint64 {
+		word := getWord(calldata, index)
+		if !bytes.Equal(word[:wordSize-8], make([]byte, wordSize-8)) {
+			t.Fatalf("word %d is not a left padded uint64: %x", index, word)
+		}
+		return binary.BigEndian.Uint64(word[wordSize-8:])
+	}
+
+	if !bytes.Equal(getWord(calldata, 0), bundle.TransactionHash[:]) {
+		t.Fatalf("expected %x, got %x", bundle.TransactionHash, getWord(calldata, 0))
+	}
+	if !bytes.Equal(getWord(calldata, 1), bundle.TransactionProof.Stibhash) {
+		t.Fatalf("expected %x, got %x", bundle.TransactionProof.Stibhash, getWord(calldata, 1))
+	}
+	if !bytes.Equal(getWord(calldata, 9), bundle.GenesisHash[:]) {
+		t.Fatalf("expected %x, got %x", bundle.GenesisHash, getWord(calldata, 9))
+	}
+	if !bytes.Equal(getWord(calldata, 10), bundle.Seed[:]) {
+		t.Fatalf("expected %x, got %x", bundle.Seed, getWord(calldata, 10))
+	}
+
+	transactionProofOffset := uint64(argumentCount * wordSize)
+	lightBlockHeaderProofOffset := transactionProofOffset + wordSize + uint64(transactionProofLength)
+	expectedWords := map[int]uint64{
+		2:  bundle.TransactionProof.Idx,
+		3:  bundle.TransactionProof.Treedepth,
+		4:  transactionProofOffset,
+		5:  bundle.LightBlockHeaderProof.Index,
+		6:  bundle.LightBlockHeaderProof.Treedepth,
+		7:  lightBlockHeaderProofOffset,
+		8:  uint64(bundle.Round),
+		11: call.IntervalIndex,
+		argumentCount + transactionProofLength/wordSize + 1: bundle.LightBlockHeaderProof.Treedepth,
+		argumentCount: bundle.TransactionProof.Treedepth,
+	}
+	for index, expectedWord := range expectedWords {
+		word := wordToUint64(index)
+		if word != expectedWord {
+			t.Fatalf("word %d: expected %d, got %d", index, expectedWord, word)
+		}
+	}
+
+	// Each array's elements follow its length word.
+	transactionProofStart := 4 + int(transactionProofOffset) + wordSize
+	if !bytes.Equal(calldata[transactionProofStart:transactionProofStart+transactionProofLength],
+		bundle.TransactionProof.Proof) {
+		t.Fatal("transaction proof does not follow its length")
+	}
+	if !bytes.Equal(calldata[len(calldata)-lightBlockHeaderProofLength:], bundle.LightBlockHeaderProof.Proof) {
+		t.Fatal("light block header proof does not follow its length")
+	}
+}
+
+func TestEncodeVerifyTransactionErrors(t *testing.T) {
+	bundle, firstAttestedRound, intervalSize := loadTestBundle(t)
+
+	testCases := map[string]struct {
+		tamper      func(bundle *inclusionbundle.InclusionProofBundle)
+		expectedErr error
+	}{
+		"partial transaction proof word": {func(bundle *inclusionbundle.InclusionProofBundle) {
+			bundle.TransactionProof.Proof = append(bundle.TransactionProof.Proof, 0)
+		}, transactionverifier.ErrProofLengthTreeDepthMismatch},
+		"transaction tree depth mismatch": {func(bundle *inclusionbundle.InclusionProofBundle) {
+			bundle.TransactionProof.Treedepth++
+		}, transactionverifier.ErrProofLengthTreeDepthMismatch},
+		"light block header tree depth mismatch": {func(bundle *inclusionbundle.InclusionProofBundle) {
+			bundle.LightBlockHeaderProof.Treedepth++
+		}, transactionverifier.ErrProofLengthTreeDepthMismatch},
+		"short stib hash": {func(bundle *inclusionbundle.InclusionProofBundle) {
+			bundle.TransactionProof.Stibhash = bundle.TransactionProof.Stibhash[1:]
+		}, ErrInvalidStibHashLength},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tampered := *bundle
+			tampered.TransactionProof.Proof = append([]byte{}, bundle.TransactionProof.Proof...)
+			testCase.tamper(&tampered)
+
+			_, err := EncodeInclusionProofBundle(&tampered, firstAttestedRound, intervalSize)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+
+	_, err := EncodeInclusionProofBundle(bundle, firstAttestedRound, 0)
+	if !errors.Is(err, transactionverifier.ErrInvalidIntervalSize) {
+		t.Fatalf("expected %v, got %v", transactionverifier.ErrInvalidIntervalSize, err)
+	}
+	_, err = EncodeInclusionProofBundle(bundle, uint64(bundle.Round)+1, intervalSize)
+	if !errors.Is(err, transactionverifier.ErrRoundNotAttested) {
+		t.Fatalf("expected %v, got %v", transactionverifier.ErrRoundNotAttested, err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// exportResult describes the checkpoint written by the export subcommand.
+type exportResult struct {
+	Output           string `json:"output"`
+	EarliestInterval uint64 `json:"earliest_interval"`
+	NextInterval     uint64 `json:"next_interval"`
+	Commitments      uint64 `json:"commitments"`
+}
+
+func (r *exportResult) writeText(output io.Writer) {
+	fmt.Fprintf(output, "Wrote a checkpoint holding %d commitments, for intervals %d up to %d, to %s\n", r.Commitments,
+		r.EarliestInterval, r.NextInterval, r.Output)
+}
+
+// setupExportCommand sets up the export subcommand, which exports a checkpoint of the oracle's state. A checkpoint can
+// be passed to init -checkpoint, allowing another oracle to start from this oracle's state rather than from genesis.
+func setupExportCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	outputFile := flags.String("output", "", "path to write the checkpoint to, instead of standard output")
+	intervals := flags.Int64("intervals", -1, "number of most recent commitments to keep, or -1 to keep all of them")
+
+	return func(arguments []string) (commandResult, error) {
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		state := oracleInstance.State()
+		if *intervals >= 0 {
+			state = oracleInstance.Checkpoint(uint64(*intervals))
+		}
+
+		encodedState, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		encodedState = append(encodedState, '\n')
+
+		// Without an output file, the checkpoint itself is the subcommand's output, and it's already JSON.
+		if *outputFile == "" {
+			_, err = os.Stdout.Write(encodedState)
+			return nil, err
+		}
+
+		err = writeFileAtomically(*outputFile, encodedState)
+		if err != nil {
+			return nil, err
+		}
+
+		return &exportResult{
+			Output:           *outputFile,
+			EarliestInterval: state.EarliestInterval,
+			NextInterval:     state.NextInterval,
+			Commitments:      uint64(len(state.Commitments)),
+		}, nil
+	}
+}
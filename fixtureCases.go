@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+var (
+	errUnknownExpectedError = errors.New("fixture transaction case expects an unknown error")
+)
+
+// fixtureCaseErrors maps the error names recorded in fixture transaction cases to the errors themselves. It must hold
+// every name in encodedassets.ExpectedErrorNames.
+var fixtureCaseErrors = map[string]error{
+	"ErrTooEarlyRoundRequested":            oracle.ErrTooEarlyRoundRequested,
+	"ErrNoStateProofForRound":              oracle.ErrNoStateProofForRound,
+	"ErrUnsupportedHashFunction":           transactionverifier.ErrUnsupportedHashFunction,
+	"ErrProofLengthTreeDepthMismatch":      transactionverifier.ErrProofLengthTreeDepthMismatch,
+	"ErrRootMismatch":                      transactionverifier.ErrRootMismatch,
+	"ErrInvalidTreeDepth":                  transactionverifier.ErrInvalidTreeDepth,
+	"ErrIndexDepthMismatch":                transactionverifier.ErrIndexDepthMismatch,
+	"ErrLightBlockHeaderTreeDepthMismatch": transactionverifier.ErrLightBlockHeaderTreeDepthMismatch,
+	"ErrLightBlockHeaderIndexMismatch":     transactionverifier.ErrLightBlockHeaderIndexMismatch,
+}
+
+// verifyFixtureCase verifies a fixture transaction case exactly as a third party's transaction is verified: the
+// commitment for the case's round is retrieved from the oracle, and the transaction is verified using it.
+// Parameters:
+// oracleInstance - an oracle whose state was advanced using the fixture bundle's state proofs.
+// fixtureCase - the case to verify.
+// tracer - records the verification's steps, allowing an unexpected result to be inspected. May be nil.
+func verifyFixtureCase(oracleInstance *oracle.Oracle, fixtureCase encodedassets.FixtureTransactionCase,
+	tracer *transactionverifier.Tracer) error {
+	desiredTransactionCommitment, err := oracleInstance.GetStateProofCommitment(fixtureCase.Round)
+	if err != nil {
+		return err
+	}
+
+	history := oracleInstance.BlockIntervalCommitmentHistory
+	return transactionverifier.VerifyTransactionWithTrace(fixtureCase.TransactionID, fixtureCase.TransactionProofResponse,
+		fixtureCase.LightBlockHeaderProofResponse, fixtureCase.Round, fixtureCase.GenesisHash, fixtureCase.Seed,
+		desiredTransactionCommitment, history.FirstAttestedRound, history.IntervalSize, tracer)
+}
+
+// matchesExpectedError returns true if a fixture transaction case's verification produced the error the case expects.
+// Parameters:
+// verifyErr - the error verifying the case produced, or nil if verification succeeded.
+// expectedErrorName - the name of the error the case expects, or empty if verification must succeed.
+func matchesExpectedError(verifyErr error, expectedErrorName string) (bool, error) {
+	if expectedErrorName == "" {
+		return verifyErr == nil, nil
+	}
+
+	expectedErr, exists := fixtureCaseErrors[expectedErrorName]
+	if !exists {
+		return false, fmt.Errorf("%w: %q", errUnknownExpectedError, expectedErrorName)
+	}
+
+	return errors.Is(verifyErr, expectedErr), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// loadAdvancedFixtureOracle returns the fixture bundle, along with an oracle advanced using its state proofs.
+func loadAdvancedFixtureOracle(t *testing.T) (*encodedassets.FixtureBundle, *oracle.Oracle) {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+		fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	for _, stateProofResponse := range fixtureBundle.StateProofs {
+		message, stateProof, err := encodedassets.ParseStateProofResponse(stateProofResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = oracleInstance.AdvanceState(stateProof, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fixtureBundle, oracleInstance
+}
+
+func TestFixtureCaseErrorsMatchExpectedErrorNames(t *testing.T) {
+	if len(fixtureCaseErrors) != len(encodedassets.ExpectedErrorNames) {
+		t.Fatalf("fixtureCaseErrors holds %d errors, but there are %d expected error names", len(fixtureCaseErrors),
+			len(encodedassets.ExpectedErrorNames))
+	}
+
+	for _, name := range encodedassets.ExpectedErrorNames {
+		if _, exists := fixtureCaseErrors[name]; !exists {
+			t.Fatalf("fixtureCaseErrors is missing %s", name)
+		}
+	}
+}
+
+func TestMatchesExpectedError(t *testing.T) {
+	testCases := []struct {
+		name              string
+		verifyErr         error
+		expectedErrorName string
+		expectedMatch     bool
+		expectedErr       error
+	}{
+		{"success expected and produced", nil, "", true, nil},
+		{"success expected, error produced", transactionverifier.ErrRootMismatch, "", false, nil},
+		{"error expected, success produced", nil, "ErrRootMismatch", false, nil},
+		{"error expected and produced", transactionverifier.ErrRootMismatch, "ErrRootMismatch", true, nil},
+		{"error expected and produced wrapped", fmt.Errorf("verifying: %w", oracle.ErrNoStateProofForRound),
+			"ErrNoStateProofForRound", true, nil},
+		{"other error produced", transactionverifier.ErrInvalidTreeDepth, "ErrRootMismatch", false, nil},
+		{"unknown error expected", transactionverifier.ErrRootMismatch, "ErrUnknown", false, errUnknownExpectedError},
+		{"unknown error expected, success produced", nil, "ErrUnknown", false, errUnknownExpectedError},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			match, err := matchesExpectedError(testCase.verifyErr, testCase.expectedErrorName)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected error %v, got %v", testCase.expectedErr, err)
+			}
+			if match != testCase.expectedMatch {
+				t.Fatalf("expected match %v, got %v", testCase.expectedMatch, match)
+			}
+		})
+	}
+}
+
+func TestVerifyFixtureCases(t *testing.T) {
+	fixtureBundle, oracleInstance := loadAdvancedFixtureOracle(t)
+
+	for _, fixtureCase := range fixtureBundle.TransactionCases {
+		t.Run(fixtureCase.Name, func(t *testing.T) {
+			verifyErr := verifyFixtureCase(oracleInstance, fixtureCase, nil)
+			match, err := matchesExpectedError(verifyErr, fixtureCase.ExpectedError)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !match {
+				t.Fatalf("expected %q, got %v", fixtureCase.ExpectedError, verifyErr)
+			}
+		})
+	}
+}
@@ -5,12 +5,13 @@ go 1.17
 require (
 	github.com/algorand/go-algorand-sdk v1.14.1-0.20220901113244-e97b2d10de7d
 	github.com/algorand/go-stateproof-verification v0.0.0-20220901111510-6d875c3e7c43
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
 )
 
 require (
 	github.com/algorand/falcon v0.0.0-20220727072124-02a2a64c4414 // indirect
 	github.com/algorand/go-codec/codec v1.1.8 // indirect
 	github.com/algorand/go-sumhash v1.0.0 // indirect
-	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
 	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
 )
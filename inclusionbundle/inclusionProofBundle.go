@@ -0,0 +1,144 @@
+package inclusionbundle
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// InclusionProofBundleVersion is the version of the bundle format produced by this package. Bundles of any other
+// version are rejected, so that changes to the format are never silently misinterpreted.
+const InclusionProofBundleVersion = 1
+
+// InclusionProofBundlePrefix is prepended to the canonical msgpack encoded bundle when computing its content hash.
+var InclusionProofBundlePrefix = []byte("IB")
+
+var (
+	ErrUnsupportedBundleVersion = errors.New("inclusion proof bundle version is unsupported")
+)
+
+// InclusionProofBundle holds everything a third party has to provide in order to prove a transaction's occurrence.
+// The commitment the proofs are verified against is not part of the bundle, as it must come from a trusted Oracle.
+// Bundles have canonical msgpack and JSON encodings, which makes them suitable as a wire format.
+type InclusionProofBundle struct {
+	_struct struct{} `codec:",omitempty,omitemptyarray"`
+
+	// Version is the version of the bundle format.
+	Version uint64 `codec:"v"`
+	// TransactionHash is the result of invoking Sha256 on the canonical msgpack encoded transaction.
+	TransactionHash types.Digest `codec:"txh"`
+	// TransactionProof is the response returned by an Algorand node when queried using GetTransactionProof.
+	TransactionProof models.TransactionProofResponse `codec:"txp"`
+	// LightBlockHeaderProof is the response returned by an Algorand node when queried using GetLightBlockHeaderProof.
+	LightBlockHeaderProof models.LightBlockHeaderProof `codec:"lbhp"`
+	// Round is the round in which the transaction was confirmed.
+	Round types.Round `codec:"rnd"`
+	// GenesisHash is the hash of the genesis block.
+	GenesisHash types.Digest `codec:"gh"`
+	// Seed is the sortition seed of the block in which the transaction was confirmed.
+	Seed types.Seed `codec:"seed"`
+}
+
+// InitializeInclusionProofBundle initializes an InclusionProofBundle of the current version.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// confirmedRound - the round in which the given transaction was confirmed.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block in which the transaction was confirmed.
+func InitializeInclusionProofBundle(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest,
+	seed types.Seed) *InclusionProofBundle {
+	return &InclusionProofBundle{
+		Version:               InclusionProofBundleVersion,
+		TransactionHash:       transactionHash,
+		TransactionProof:      transactionProofResponse,
+		LightBlockHeaderProof: lightBlockHeaderProofResponse,
+		Round:                 confirmedRound,
+		GenesisHash:           genesisHash,
+		Seed:                  seed,
+	}
+}
+
+// EncodeMsgpack returns the bundle's canonical msgpack encoding.
+func (b *InclusionProofBundle) EncodeMsgpack() []byte {
+	return msgpack.Encode(b)
+}
+
+// EncodeJSON returns the bundle's canonical JSON encoding. Byte fields are encoded using base64.
+func (b *InclusionProofBundle) EncodeJSON() []byte {
+	return json.Encode(b)
+}
+
+// ContentHash returns the hash identifying the bundle, which is of the form Sha256("IB" || msgpack(bundle)). Bundles
+// with identical contents have identical content hashes, regardless of the encoding they were received in.
+func (b *InclusionProofBundle) ContentHash() types.Digest {
+	encodedBundle := b.EncodeMsgpack()
+
+	contentHashData := make([]byte, 0, len(InclusionProofBundlePrefix)+len(encodedBundle))
+	contentHashData = append(contentHashData, InclusionProofBundlePrefix...)
+	contentHashData = append(contentHashData, encodedBundle...)
+
+	return sha256.Sum256(contentHashData)
+}
+
+// Verify verifies the bundle's transaction using the block interval commitment held by the given Oracle for the
+// bundle's round.
+// Parameters:
+// oracleInstance - the Oracle to retrieve the block interval commitment from.
+func (b *InclusionProofBundle) Verify(oracleInstance *oracle.Oracle) error {
+	if b.Version != InclusionProofBundleVersion {
+		return ErrUnsupportedBundleVersion
+	}
+
+	blockIntervalCommitment, err := oracleInstance.GetStateProofCommitment(b.Round)
+	if err != nil {
+		return err
+	}
+
+	history := oracleInstance.BlockIntervalCommitmentHistory
+	return transactionverifier.VerifyTransaction(b.TransactionHash, b.TransactionProof, b.LightBlockHeaderProof, b.Round,
+		b.GenesisHash, b.Seed, blockIntervalCommitment, history.FirstAttestedRound, history.IntervalSize)
+}
+
+// DecodeMsgpack decodes a bundle from its msgpack encoding. Unknown fields and versions are rejected.
+// Parameters:
+// encodedBundle - the msgpack encoded bundle.
+func DecodeMsgpack(encodedBundle []byte) (*InclusionProofBundle, error) {
+	var bundle InclusionProofBundle
+	err := msgpack.Decode(encodedBundle, &bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != InclusionProofBundleVersion {
+		return nil, ErrUnsupportedBundleVersion
+	}
+
+	return &bundle, nil
+}
+
+// DecodeJSON decodes a bundle from its JSON encoding. Unknown fields and versions are rejected.
+// Parameters:
+// encodedBundle - the JSON encoded bundle.
+func DecodeJSON(encodedBundle []byte) (*InclusionProofBundle, error) {
+	var bundle InclusionProofBundle
+	err := json.Decode(encodedBundle, &bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != InclusionProofBundleVersion {
+		return nil, ErrUnsupportedBundleVersion
+	}
+
+	return &bundle, nil
+}
@@ -0,0 +1,185 @@
+package inclusionbundle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// initializeTestBundle returns a bundle of the fixture bundle's valid transaction case, along with an Oracle that was
+// advanced using the fixture bundle's state proof.
+func initializeTestBundle(t *testing.T) (*InclusionProofBundle, *oracle.Oracle) {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound,
+		fixtureBundle.Network.IntervalSize, fixtureBundle.Genesis.VotersCommitment,
+		fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	message, stateProof, err := encodedassets.ParseStateProofResponse(fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = oracleInstance.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		return InitializeInclusionProofBundle(transactionCase.TransactionID, transactionCase.TransactionProofResponse,
+			transactionCase.LightBlockHeaderProofResponse, transactionCase.Round, transactionCase.GenesisHash,
+			transactionCase.Seed), oracleInstance
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return nil, nil
+}
+
+// addUnknownMsgpackField appends a field named "zz" to a msgpack encoded map holding fewer than 15 fields.
+func addUnknownMsgpackField(t *testing.T, encodedMap []byte) []byte {
+	t.Helper()
+
+	// A map holding fewer than 16 fields is encoded as a fixmap, whose first byte is 0x80 | number of fields.
+	if encodedMap[0]&0xf0 != 0x80 || encodedMap[0] == 0x8f {
+		t.Fatalf("expected a fixmap holding fewer than 15 fields, got first byte %#x", encodedMap[0])
+	}
+
+	extended := append([]byte{encodedMap[0] + 1}, encodedMap[1:]...)
+	return append(extended, 0xa2, 'z', 'z', 0x01)
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	bundle, _ := initializeTestBundle(t)
+
+	decoders := map[string]func() (*InclusionProofBundle, error){
+		"msgpack": func() (*InclusionProofBundle, error) { return DecodeMsgpack(bundle.EncodeMsgpack()) },
+		"JSON":    func() (*InclusionProofBundle, error) { return DecodeJSON(bundle.EncodeJSON()) },
+	}
+
+	for name, decode := range decoders {
+		decodedBundle, err := decode()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(decodedBundle, bundle) {
+			t.Fatalf("%s: expected %+v, got %+v", name, bundle, decodedBundle)
+		}
+		if !bytes.Equal(decodedBundle.EncodeMsgpack(), bundle.EncodeMsgpack()) {
+			t.Fatalf("%s: expected the decoded bundle to have the same canonical encoding", name)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	bundle, _ := initializeTestBundle(t)
+
+	// The content hash of the fixture bundle's valid transaction case. Changing it breaks every stored bundle
+	// identifier, so it must only change along with InclusionProofBundleVersion.
+	const expectedContentHash = "4767d400fd2d82e6596aad61ea654fd94a2f260aa969f8390a3487aa80792b41"
+	contentHash := bundle.ContentHash()
+	if hex.EncodeToString(contentHash[:]) != expectedContentHash {
+		t.Fatalf("expected %s, got %x", expectedContentHash, contentHash)
+	}
+
+	decodedFromJSON, err := DecodeJSON(bundle.EncodeJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedFromMsgpack, err := DecodeMsgpack(bundle.EncodeMsgpack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedFromJSON.ContentHash() != contentHash || decodedFromMsgpack.ContentHash() != contentHash {
+		t.Fatalf("expected %x regardless of the encoding, got %x from JSON and %x from msgpack", contentHash,
+			decodedFromJSON.ContentHash(), decodedFromMsgpack.ContentHash())
+	}
+
+	tampered := *bundle
+	tampered.Seed[0] ^= 1
+	if tampered.ContentHash() == contentHash {
+		t.Fatal("expected bundles with different contents to have different content hashes")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	bundle, _ := initializeTestBundle(t)
+
+	unsupportedVersion := *bundle
+	unsupportedVersion.Version = InclusionProofBundleVersion + 1
+
+	encodedJSON := bundle.EncodeJSON()
+	jsonWithUnknownField := append([]byte(`{"zz":1,`), encodedJSON[1:]...)
+
+	testCases := map[string]struct {
+		decode      func([]byte) (*InclusionProofBundle, error)
+		encoded     []byte
+		expectedErr error
+	}{
+		"msgpack unsupported version": {DecodeMsgpack, unsupportedVersion.EncodeMsgpack(),
+			ErrUnsupportedBundleVersion},
+		"JSON unsupported version": {DecodeJSON, unsupportedVersion.EncodeJSON(), ErrUnsupportedBundleVersion},
+		"msgpack unknown field":    {DecodeMsgpack, addUnknownMsgpackField(t, bundle.EncodeMsgpack()), nil},
+		"JSON unknown field":       {DecodeJSON, jsonWithUnknownField, nil},
+		"truncated msgpack":        {DecodeMsgpack, bundle.EncodeMsgpack()[:10], nil},
+		"truncated JSON":           {DecodeJSON, encodedJSON[:10], nil},
+	}
+
+	for name, testCase := range testCases {
+		decodedBundle, err := testCase.decode(testCase.encoded)
+		if err == nil || decodedBundle != nil {
+			t.Fatalf("%s: expected an error, got %+v", name, decodedBundle)
+		}
+		if testCase.expectedErr != nil && !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	bundle, oracleInstance := initializeTestBundle(t)
+
+	err := bundle.Verify(oracleInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		tamper      func(bundle *InclusionProofBundle)
+		expectedErr error
+	}{
+		"unsupported version": {func(bundle *InclusionProofBundle) { bundle.Version++ }, ErrUnsupportedBundleVersion},
+		"wrong transaction hash": {func(bundle *InclusionProofBundle) { bundle.TransactionHash[0] ^= 1 },
+			transactionverifier.ErrRootMismatch},
+		"wrong seed": {func(bundle *InclusionProofBundle) { bundle.Seed[0] ^= 1 }, transactionverifier.ErrRootMismatch},
+		"round before the first attested round": {func(bundle *InclusionProofBundle) { bundle.Round = 1 },
+			oracle.ErrTooEarlyRoundRequested},
+		"round after the last attested round": {func(bundle *InclusionProofBundle) { bundle.Round += 8 },
+			oracle.ErrNoStateProofForRound},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tampered := *bundle
+			testCase.tamper(&tampered)
+
+			err := tampered.Verify(oracleInstance)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+var (
+	errStateFileExists   = errors.New("oracle state file already exists, use -force to overwrite it")
+	errAmbiguousGenesis  = errors.New("exactly one of -checkpoint, -fixtures or -voters-commitment must be given")
+	errIncompleteGenesis = errors.New("-voters-commitment requires -ln-proven-weight and -interval-size")
+)
+
+// setupInitCommand sets up the init subcommand, which creates an oracle state file. The oracle is initialized from
+// trusted genesis data, given either using flags or using a fixture bundle, or from a checkpoint exported by another
+// oracle.
+func setupInitCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	flags.StringVar(&config.FixturesFile, "fixtures", "", "path of a fixture bundle to take the genesis data and network parameters from")
+	flags.Uint64Var(&config.Capacity, "capacity", 1000, "maximum number of commitments to hold, ignored for checkpoints")
+	checkpointFile := flags.String("checkpoint", "", "path of a checkpoint, as written by export, to initialize from")
+	votersCommitment := flags.String("voters-commitment", "", "base64 encoded genesis voters commitment")
+	lnProvenWeight := flags.Uint64("ln-proven-weight", 0, "genesis voters' ln proven weight")
+	firstAttestedRound := flags.Uint64("first-attested-round", 0, "first round to which a state proof message attests")
+	intervalSize := flags.Uint64("interval-size", 0, "number of rounds each state proof message attests to")
+	force := flags.Bool("force", false, "overwrite an existing oracle state file")
+
+	return func(arguments []string) (commandResult, error) {
+		sources := 0
+		for _, source := range []string{*checkpointFile, config.FixturesFile, *votersCommitment} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, errAmbiguousGenesis
+		}
+
+		if !*force {
+			_, err := os.Stat(config.StateFile)
+			if err == nil {
+				return nil, errStateFileExists
+			}
+		}
+
+		var oracleInstance *oracle.Oracle
+		switch {
+		case *checkpointFile != "":
+			encodedCheckpoint, err := os.ReadFile(*checkpointFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+			}
+
+			oracleInstance, err = oracle.DecodeOracle(encodedCheckpoint)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode checkpoint %s: %w", *checkpointFile, err)
+			}
+
+		case config.FixturesFile != "":
+			fixtureBundle, err := loadFixtureBundle(config.FixturesFile)
+			if err != nil {
+				return nil, err
+			}
+
+			oracleInstance = oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+				fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, config.Capacity)
+
+		default:
+			if *lnProvenWeight == 0 || *intervalSize == 0 {
+				return nil, errIncompleteGenesis
+			}
+
+			genesisVotersCommitment, err := base64.StdEncoding.DecodeString(*votersCommitment)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode -voters-commitment: %w", err)
+			}
+
+			oracleInstance = oracle.InitializeOracle(*firstAttestedRound, *intervalSize, genesisVotersCommitment,
+				*lnProvenWeight, config.Capacity)
+		}
+
+		err := saveOracle(config, oracleInstance)
+		if err != nil {
+			return nil, err
+		}
+
+		return getStatus(config, oracleInstance), nil
+	}
+}
+
+// loadFixtureBundle loads and validates a fixture bundle, whose format is determined by its file extension.
+// Parameters:
+// fixturesFile - the path of the fixture bundle.
+func loadFixtureBundle(fixturesFile string) (*encodedassets.FixtureBundle, error) {
+	format, err := encodedassets.GetEncodingFormat(fixturesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixture bundle %s: %w", fixturesFile, err)
+	}
+
+	fixturesReader, err := os.Open(fixturesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer fixturesReader.Close()
+
+	fixtureBundle, err := encodedassets.ReadFixtureBundle(fixturesReader, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixture bundle %s: %w", fixturesFile, err)
+	}
+
+	return fixtureBundle, nil
+}
@@ -0,0 +1,164 @@
+package lightblockheaderstore
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+var (
+	ErrIntervalSizeMismatch       = errors.New("number of light block headers does not match the interval size")
+	ErrIntervalNotAligned         = errors.New("first light block header's round is not the first round of an interval")
+	ErrNonConsecutiveRounds       = errors.New("light block headers' rounds are not consecutive")
+	ErrNoLightBlockHeaderForRound = errors.New("round belongs to an interval without stored light block headers")
+)
+
+// LightBlockHeaderStore holds verified light block headers for entire intervals. Each interval's light block headers
+// are verified by rebuilding the interval's vector commitment and comparing it to the block interval commitment held
+// by the Oracle. Once stored, a light block header can be used to verify transactions from its round using only a
+// transaction proof. Intervals the Oracle no longer holds commitments for are not served, and are discarded by Prune,
+// or by the next SyncInterval.
+type LightBlockHeaderStore struct {
+	// mu guards Data: lookups share it, while syncing and pruning hold it exclusively.
+	mu sync.RWMutex
+	// oracle is the Oracle used to retrieve block interval commitments.
+	oracle *oracle.Oracle
+	// Data is a map of intervals to the verified light block headers of every round in the interval, ordered by round.
+	Data map[uint64][]types.LightBlockHeader
+}
+
+// InitializeLightBlockHeaderStore initializes an empty LightBlockHeaderStore using an Oracle.
+// Parameters:
+// oracleInstance - the Oracle to retrieve block interval commitments from. Its state should be advanced separately.
+func InitializeLightBlockHeaderStore(oracleInstance *oracle.Oracle) *LightBlockHeaderStore {
+	return &LightBlockHeaderStore{
+		oracle: oracleInstance,
+		Data:   make(map[uint64][]types.LightBlockHeader),
+	}
+}
+
+// SyncInterval receives the light block headers of every round in an interval, verifies them using the Oracle's
+// block interval commitment, and stores them. Intervals whose commitment was discarded by the Oracle are discarded
+// from the store as well.
+// Parameters:
+// lightBlockHeaders - the light block headers of every round in the interval, ordered by round.
+func (s *LightBlockHeaderStore) SyncInterval(lightBlockHeaders []types.LightBlockHeader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.oracle.BlockIntervalCommitmentHistory
+	if len(lightBlockHeaders) == 0 || uint64(len(lightBlockHeaders)) != history.IntervalSize {
+		return ErrIntervalSizeMismatch
+	}
+
+	firstRound := lightBlockHeaders[0].RoundNumber
+	interval, err := history.GetCoveringInterval(firstRound)
+	if err != nil {
+		return err
+	}
+
+	// The vector commitment is built over the headers in the order they are given, so they must be exactly the
+	// interval's rounds, in order.
+	if uint64(firstRound) != history.FirstAttestedRound+interval*history.IntervalSize {
+		return ErrIntervalNotAligned
+	}
+
+	for i, lightBlockHeader := range lightBlockHeaders {
+		if lightBlockHeader.RoundNumber != firstRound+types.Round(i) {
+			return ErrNonConsecutiveRounds
+		}
+	}
+
+	blockIntervalCommitment, err := s.oracle.GetStateProofCommitment(firstRound)
+	if err != nil {
+		return err
+	}
+
+	// We rebuild the vector commitment over the interval's light block headers, and verify that it is identical to
+	// the commitment provided by the Oracle.
+	lightBlockHeaderProofBuilder, err := transactionverifier.BuildLightBlockHeaderProofs(lightBlockHeaders)
+	if err != nil {
+		return err
+	}
+
+	if lightBlockHeaderProofBuilder.Root() != blockIntervalCommitment {
+		return transactionverifier.ErrRootMismatch
+	}
+
+	s.Data[interval] = append([]types.LightBlockHeader{}, lightBlockHeaders...)
+	s.discardEvictedIntervals()
+	return nil
+}
+
+// discardEvictedIntervals discards the intervals the Oracle no longer holds commitments for, to keep the store's size
+// bounded by the Oracle's capacity, and returns the number of intervals discarded. The caller must hold mu exclusively.
+func (s *LightBlockHeaderStore) discardEvictedIntervals() int {
+	earliestInterval := s.oracle.BlockIntervalCommitmentHistory.EarliestInterval
+	discarded := 0
+	for storedInterval := range s.Data {
+		if storedInterval < earliestInterval {
+			delete(s.Data, storedInterval)
+			discarded++
+		}
+	}
+	return discarded
+}
+
+// Prune discards the intervals the Oracle no longer holds commitments for, and returns the number of intervals
+// discarded. It should be called whenever the Oracle advances, e.g. from relayer.Config.OnAdvance.
+func (s *LightBlockHeaderStore) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.discardEvictedIntervals()
+}
+
+// GetLightBlockHeader returns the verified light block header of the given round. Light block headers of intervals
+// the Oracle no longer holds commitments for are not returned, as the Oracle may have been advanced since they were
+// stored. It does not modify the store, see Prune.
+// Parameters:
+// round - the round to return the light block header for.
+func (s *LightBlockHeaderStore) GetLightBlockHeader(round types.Round) (types.LightBlockHeader, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.oracle.BlockIntervalCommitmentHistory
+	interval, err := history.GetCoveringInterval(round)
+	if err != nil {
+		return types.LightBlockHeader{}, err
+	}
+
+	// The store is only pruned when an interval is synced or Prune is called, so it may still hold intervals evicted
+	// by the Oracle since then.
+	if interval < history.EarliestInterval {
+		return types.LightBlockHeader{}, ErrNoLightBlockHeaderForRound
+	}
+
+	lightBlockHeaders, exists := s.Data[interval]
+	if !exists {
+		return types.LightBlockHeader{}, ErrNoLightBlockHeaderForRound
+	}
+
+	return lightBlockHeaders[uint64(round)-history.FirstAttestedRound-interval*history.IntervalSize], nil
+}
+
+// VerifyTransaction verifies a sha256 hashed transaction using only a proof to compute the transaction's commitment,
+// and the stored light block header of the round in which the transaction was confirmed.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// confirmedRound - the round in which the given transaction was confirmed.
+func (s *LightBlockHeaderStore) VerifyTransaction(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	confirmedRound types.Round) error {
+	lightBlockHeader, err := s.GetLightBlockHeader(confirmedRound)
+	if err != nil {
+		return err
+	}
+
+	return transactionverifier.VerifyTransactionWithLightBlockHeader(transactionHash, transactionProofResponse, lightBlockHeader)
+}
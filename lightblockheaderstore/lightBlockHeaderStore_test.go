@@ -0,0 +1,158 @@
+package lightblockheaderstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+const (
+	testFirstAttestedRound = 9
+	testIntervalSize       = 8
+)
+
+// getTestInterval returns the light block headers of every round in the given interval.
+func getTestInterval(interval uint64) []types.LightBlockHeader {
+	lightBlockHeaders := make([]types.LightBlockHeader, testIntervalSize)
+	for i := range lightBlockHeaders {
+		lightBlockHeaders[i] = types.LightBlockHeader{
+			RoundNumber:         types.Round(testFirstAttestedRound + interval*testIntervalSize + uint64(i)),
+			GenesisHash:         types.Digest{1},
+			Seed:                types.Seed{byte(interval), byte(i)},
+			Sha256TxnCommitment: types.Digest{byte(interval), byte(i), 1},
+		}
+	}
+	return lightBlockHeaders
+}
+
+// getTestOracle returns an Oracle holding the commitments of the given number of intervals. Commitments are inserted
+// directly, as the intervals are not attested to by real state proofs.
+func getTestOracle(t *testing.T, intervals uint64, capacity uint64) *oracle.Oracle {
+	t.Helper()
+
+	oracleInstance := oracle.InitializeOracle(testFirstAttestedRound, testIntervalSize, nil, 1, capacity)
+	for interval := uint64(0); interval < intervals; interval++ {
+		oracleInstance.BlockIntervalCommitmentHistory.InsertCommitment(getTestCommitment(t, interval))
+	}
+	return oracleInstance
+}
+
+func getTestCommitment(t *testing.T, interval uint64) types.Digest {
+	t.Helper()
+
+	builder, err := transactionverifier.BuildLightBlockHeaderProofs(getTestInterval(interval))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return builder.Root()
+}
+
+func TestSyncIntervalAndGetLightBlockHeader(t *testing.T) {
+	store := InitializeLightBlockHeaderStore(getTestOracle(t, 2, 10))
+	for interval := uint64(0); interval < 2; interval++ {
+		err := store.SyncInterval(getTestInterval(interval))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for interval := uint64(0); interval < 2; interval++ {
+		for i, expected := range getTestInterval(interval) {
+			lightBlockHeader, err := store.GetLightBlockHeader(expected.RoundNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lightBlockHeader != expected {
+				t.Fatalf("interval %d, header %d: expected %+v, got %+v", interval, i, expected, lightBlockHeader)
+			}
+		}
+	}
+
+	_, err := store.GetLightBlockHeader(testFirstAttestedRound - 1)
+	if !errors.Is(err, oracle.ErrTooEarlyRoundRequested) {
+		t.Fatalf("expected %v, got %v", oracle.ErrTooEarlyRoundRequested, err)
+	}
+
+	_, err = store.GetLightBlockHeader(testFirstAttestedRound + 2*testIntervalSize)
+	if !errors.Is(err, ErrNoLightBlockHeaderForRound) {
+		t.Fatalf("expected %v, got %v", ErrNoLightBlockHeaderForRound, err)
+	}
+}
+
+func TestSyncIntervalErrors(t *testing.T) {
+	testCases := map[string]struct {
+		lightBlockHeaders func() []types.LightBlockHeader
+		expectedErr       error
+	}{
+		"short interval": {func() []types.LightBlockHeader {
+			return getTestInterval(0)[1:]
+		}, ErrIntervalSizeMismatch},
+		"unaligned interval": {func() []types.LightBlockHeader {
+			lightBlockHeaders := getTestInterval(0)
+			for i := range lightBlockHeaders {
+				lightBlockHeaders[i].RoundNumber++
+			}
+			return lightBlockHeaders
+		}, ErrIntervalNotAligned},
+		"non consecutive rounds": {func() []types.LightBlockHeader {
+			lightBlockHeaders := getTestInterval(0)
+			lightBlockHeaders[1], lightBlockHeaders[2] = lightBlockHeaders[2], lightBlockHeaders[1]
+			return lightBlockHeaders
+		}, ErrNonConsecutiveRounds},
+		"altered header": {func() []types.LightBlockHeader {
+			lightBlockHeaders := getTestInterval(0)
+			lightBlockHeaders[3].Seed[0] ^= 1
+			return lightBlockHeaders
+		}, transactionverifier.ErrRootMismatch},
+		"interval without commitment": {func() []types.LightBlockHeader {
+			return getTestInterval(1)
+		}, oracle.ErrNoStateProofForRound},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			store := InitializeLightBlockHeaderStore(getTestOracle(t, 1, 10))
+			err := store.SyncInterval(testCase.lightBlockHeaders())
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+			if len(store.Data) != 0 {
+				t.Fatalf("expected no stored intervals, got %d", len(store.Data))
+			}
+		})
+	}
+}
+
+func TestGetLightBlockHeaderEvictedInterval(t *testing.T) {
+	oracleInstance := getTestOracle(t, 1, 1)
+	store := InitializeLightBlockHeaderStore(oracleInstance)
+	err := store.SyncInterval(getTestInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Advancing the Oracle past its capacity evicts the first interval's commitment, without syncing the store.
+	oracleInstance.BlockIntervalCommitmentHistory.InsertCommitment(getTestCommitment(t, 1))
+
+	_, err = store.GetLightBlockHeader(testFirstAttestedRound)
+	if !errors.Is(err, ErrNoLightBlockHeaderForRound) {
+		t.Fatalf("expected %v for an evicted interval, got %v", ErrNoLightBlockHeaderForRound, err)
+	}
+	// Lookups do not modify the store, so the evicted interval is only discarded once the store is pruned.
+	if len(store.Data) != 1 {
+		t.Fatalf("expected the evicted interval to be kept until pruning, got %d stored intervals", len(store.Data))
+	}
+
+	discarded := store.Prune()
+	if discarded != 1 || len(store.Data) != 0 {
+		t.Fatalf("expected the evicted interval to be discarded, discarded %d and kept %d intervals", discarded,
+			len(store.Data))
+	}
+	if store.Prune() != 0 {
+		t.Fatal("expected pruning again to discard nothing")
+	}
+}
@@ -1,77 +1,129 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"github.com/almog-t/light-client-poc/encodedassets"
-	"github.com/almog-t/light-client-poc/oracle"
-	"github.com/almog-t/light-client-poc/transactionverifier"
+	"io"
+	"os"
+	"sort"
 )
 
 // A light client is composed of two modules:
 // 1. An oracle, in charge of maintaining Algorand's state as verified with state proofs. For more details, see oracle.go.
 // 2. A transaction verifier, in charge of verifying Algorand transaction occurrence by interfacing with the oracle. For more
 // details, see transactionVerifier.go.
-// This main function aims to demonstrate the interface between these two modules. In an actual
-// light client, they can be entirely separate processes/smart contracts.
+// This command line interface demonstrates the interface between these two modules. In an actual light client, they
+// can be entirely separate processes/smart contracts.
 // The oracle should receive Algorand data from an off chain relayer, and the transaction verifier should receive
-// transaction occurrence queries from third parties. For the purposes of this PoC, they're two separate go packages, and
-// both the relayer and other third parties have been replaced with example committed data.
-func main() {
-	// This is the genesis data, required for initializing the oracle. This data can either be queried from the
-	// blockchain itself, or found in the developer's portal.
-	genesisVotersCommitment, genesisVotersLnProvenWeight, err := encodedassets.GetParsedGenesisData("encodedassets/genesis/")
-	if err != nil {
-		fmt.Printf("Failed to parse genesis assets: %s\n", err)
-		return
-	}
+// transaction occurrence queries from third parties. Here, the oracle's state is kept in a state file between
+// subcommands: init creates it, advance ingests state proofs provided by a relayer, relay runs such a relayer against an
+// algod REST API, and verify checks inclusion proof bundles provided by third parties. serve keeps the oracle in memory,
+// answering third parties and accepting state proofs from a relayer over HTTP, and calldata exports bundles for
+// an on-chain verifier. The committed fixture bundle can stand in for both the relayer and third parties.
 
-	// This is data required for verifying a transaction. In a real light client, this data should come from a
-	// third party. The third party is responsible for querying Algorand to get most of this data.
-	genesisHash, round, seed, transactionHash, transactionProofResponse, lightBlockHeaderProofResponse, err :=
-		encodedassets.GetParsedTypesData("encodedassets/transactionverification/")
-	if err != nil {
-		fmt.Printf("Failed to parse assets needed for transaction verification: %s\n", err)
-		return
+// command is a subcommand of the command line interface.
+type command struct {
+	// description is a one line description of the subcommand, shown in the usage message.
+	description string
+	// setup registers the subcommand's flags, and returns the function running the subcommand once the flags are
+	// parsed. The function receives the arguments remaining after the flags.
+	setup func(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error)
+}
+
+var commands = map[string]command{
+	"init":     {"create an oracle state file from genesis data or from a checkpoint", setupInitCommand},
+	"advance":  {"advance the oracle's state using one or more state proofs", setupAdvanceCommand},
+	"verify":   {"verify inclusion proof bundles using the oracle's state", setupVerifyCommand},
+	"status":   {"describe the rounds covered by the oracle's state", setupStatusCommand},
+	"export":   {"export a checkpoint of the oracle's state", setupExportCommand},
+	"relay":    {"advance the oracle's state using state proofs polled from algod", setupRelayCommand},
+	"serve":    {"serve verification requests over HTTP using the oracle's state", setupServeCommand},
+	"calldata": {"export inclusion proof bundles as calldata for an on-chain verifier", setupCalldataCommand},
+}
+
+// writeUsage writes the usage message, listing every subcommand.
+// Parameters:
+// output - the writer to write the usage message to.
+func writeUsage(output io.Writer) {
+	fmt.Fprintf(output, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", os.Args[0])
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// This is data required for advancing the oracle's state. In a real light client, this data should come from a relayer.
-	stateProofMessage, stateProof, err :=
-		encodedassets.GetParsedStateProofAdvancmentData("encodedassets/stateproofverification/")
-	if err != nil {
-		fmt.Printf("Failed to parse assets needed for oracle state advancement: %s\n", err)
-		return
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-8s %s\n", name, commands[name].description)
 	}
+	fmt.Fprintf(output, "\nRun %s <command> -h for the command's flags.\n", os.Args[0])
+}
 
-	// In a real light client, intervalSize and firstAttestedRound should be hardcoded, and retrieved from the Algorand
-	// consensus and from the Algorand blockchain respectively.
-	intervalSize := uint64(8)
-	firstAttestedRound := uint64(9)
+// runCommand parses the flags of the given subcommand, runs it and writes its result. It returns the process's exit
+// code: 0 on success, 1 if the subcommand failed and 2 if it was misused.
+// Parameters:
+// name - the name of the subcommand.
+// arguments - the arguments following the subcommand's name.
+func runCommand(name string, arguments []string) int {
+	selectedCommand, exists := commands[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		writeUsage(os.Stderr)
+		return 2
+	}
 
-	// We initialize the oracle using the parsed genesis data and a hard coded capacity.
-	oracleInstance := oracle.InitializeOracle(firstAttestedRound, intervalSize, genesisVotersCommitment, genesisVotersLnProvenWeight, 1000)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	var config cliConfig
+	registerCommonFlags(flags, &config)
+	run := selectedCommand.setup(flags, &config)
 
-	// We advance the oracle's state using the state proof and the state proof message. The oracle verifies the message
-	// using the state proof. See the documentation in oracle.go for more details.
-	err = oracleInstance.AdvanceState(stateProof, stateProofMessage)
+	err := flags.Parse(arguments)
+	if err == flag.ErrHelp {
+		return 0
+	}
 	if err != nil {
-		fmt.Printf("Failed to advance oracle state: %s\n", err)
-		return
+		return 2
 	}
 
-	// After advancing the oracle's state, we retrieve the block interval commitment containing the given transaction's round from the oracle.
-	desiredTransactionCommitment, err := oracleInstance.GetStateProofCommitment(round)
+	err = applyConfigFile(flags, &config)
 	if err != nil {
-		fmt.Printf("Failed to retrieve commitment interval for round %d: %s\n", round, err)
-		return
+		writeError(os.Stderr, &config, err)
+		return 2
 	}
 
-	// We then verify the transaction's occurrence using the data provided for transaction verification,
-	// along with the block interval commitment this transaction belongs to.
-	err = transactionverifier.VerifyTransaction(transactionHash, transactionProofResponse,
-		lightBlockHeaderProofResponse, round, genesisHash, seed, desiredTransactionCommitment)
+	result, err := run(flags.Args())
+	// A subcommand may fail after producing a result, e.g. verify when a bundle fails verification. The result then
+	// describes the failure, and is written as is.
+	if result != nil {
+		writeErr := writeResult(os.Stdout, &config, result)
+		if writeErr != nil {
+			writeError(os.Stderr, &config, writeErr)
+			return 1
+		}
+	}
 
 	if err != nil {
-		fmt.Printf("Transaction verification failed: %s\n", err)
+		// JSON results already describe their failure, and a second JSON document would make the output unparsable.
+		if result == nil || !config.JSONOutput {
+			writeError(os.Stderr, &config, err)
+		}
+		return 1
+	}
+
+	return 0
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		writeUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "-h", "-help", "--help", "help":
+		writeUsage(os.Stdout)
 		return
 	}
+
+	os.Exit(runCommand(os.Args[1], os.Args[2:]))
 }
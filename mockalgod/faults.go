@@ -0,0 +1,51 @@
+package mockalgod
+
+import (
+	"time"
+)
+
+// Endpoint is one of the algod endpoints served by the Server.
+type Endpoint int
+
+const (
+	// StateProofEndpoint is /v2/stateproofs/{round}.
+	StateProofEndpoint Endpoint = iota
+	// TransactionProofEndpoint is /v2/blocks/{round}/transactions/{txid}/proof.
+	TransactionProofEndpoint
+	// LightBlockHeaderProofEndpoint is /v2/blocks/{round}/lightheader/proof.
+	LightBlockHeaderProofEndpoint
+)
+
+func (e Endpoint) String() string {
+	switch e {
+	case StateProofEndpoint:
+		return "state proof"
+	case TransactionProofEndpoint:
+		return "transaction proof"
+	case LightBlockHeaderProofEndpoint:
+		return "light block header proof"
+	default:
+		return "unknown"
+	}
+}
+
+// Fault describes a misbehavior injected into the Server's responses. A Fault may combine several misbehaviors, e.g.
+// a delay followed by an error status.
+type Fault struct {
+	// Delay is the time to wait before responding. A client whose context is done before the delay elapses receives no
+	// response at all.
+	Delay time.Duration
+	// StatusCode, if set, replaces the response with an algod style error carrying this status, e.g. 404 for data
+	// that is not available yet, or 500 for an internal error.
+	StatusCode int
+	// Corrupt flips a bit in the middle of the encoded response, so that it either fails to decode, or decodes into a
+	// response whose proof no longer verifies.
+	Corrupt bool
+	// RoundShift makes the response describe a different round than the one requested. State proofs have their
+	// message's attested rounds shifted, and proofs have their index shifted, as if they were the proof of a later
+	// round.
+	RoundShift uint64
+	// Count is the number of requests the Fault applies to, after which the Server behaves normally again. A Count of
+	// 0 applies the Fault to every request until the Server's faults are cleared.
+	Count int
+}
@@ -0,0 +1,381 @@
+package mockalgod
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+)
+
+// apiTokenHeader is the header algod expects its API token in.
+const apiTokenHeader = "X-Algo-API-Token"
+
+var (
+	ErrServerNotStarted = errors.New("mock algod server was not started")
+)
+
+// transactionKey identifies a transaction proof by the transaction's round and ID.
+type transactionKey struct {
+	round uint64
+	txID  string
+}
+
+// injectedFault is a Fault along with the number of requests it still applies to.
+type injectedFault struct {
+	fault     Fault
+	remaining int
+}
+
+// Server is an in-process HTTP server imitating the algod endpoints used by the light client: state proofs,
+// transaction proofs and light block header proofs. It serves the data added to it, as algod would, and misbehaves
+// according to the faults injected into it, allowing relayers and verifiers to be exercised without a network.
+// A Server is an http.Handler, and can also be started on a local address using Start.
+type Server struct {
+	mu sync.Mutex
+	// stateProofs are the state proof responses served, each for every round in the interval it attests to.
+	stateProofs            []models.StateProof
+	transactionProofs      map[transactionKey]models.TransactionProofResponse
+	lightBlockHeaderProofs map[uint64]models.LightBlockHeaderProof
+	// faults are the faults injected into each endpoint, applied in the order they were injected.
+	faults map[Endpoint][]*injectedFault
+	// requests is the number of requests each endpoint received.
+	requests map[Endpoint]int
+	// apiToken is the API token requests must carry, or empty to accept every request.
+	apiToken   string
+	httpServer *httptest.Server
+}
+
+// InitializeServer initializes an empty Server.
+// Parameters:
+// apiToken - the API token requests must carry in the X-Algo-API-Token header, or empty to accept every request.
+func InitializeServer(apiToken string) *Server {
+	return &Server{
+		transactionProofs:      make(map[transactionKey]models.TransactionProofResponse),
+		lightBlockHeaderProofs: make(map[uint64]models.LightBlockHeaderProof),
+		faults:                 make(map[Endpoint][]*injectedFault),
+		requests:               make(map[Endpoint]int),
+		apiToken:               apiToken,
+	}
+}
+
+// InitializeServerFromFixtureBundle initializes a Server serving the state proofs in a fixture bundle, and the proofs
+// of its valid transaction cases. Adversarial cases are not served, as they are tampered copies of the valid cases;
+// inject faults to serve misbehaving responses instead.
+// Parameters:
+// bundle - the fixture bundle to serve.
+// apiToken - the API token requests must carry in the X-Algo-API-Token header, or empty to accept every request.
+func InitializeServerFromFixtureBundle(bundle *encodedassets.FixtureBundle, apiToken string) *Server {
+	server := InitializeServer(apiToken)
+	for _, stateProof := range bundle.StateProofs {
+		server.AddStateProof(stateProof)
+	}
+
+	for _, transactionCase := range bundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		server.AddTransactionProof(uint64(transactionCase.Round), transactionCase.TransactionID,
+			transactionCase.TransactionProofResponse)
+		server.AddLightBlockHeaderProof(uint64(transactionCase.Round), transactionCase.LightBlockHeaderProofResponse)
+	}
+
+	return server
+}
+
+// InitializeServerFromDirectoryLayout initializes a Server serving the assets in the encodedassets directory layout.
+// Parameters:
+// fsys - a file system holding the genesis, stateproofverification, transactionverification and
+// adversarialverification directories at its root.
+// format - the format the assets are encoded in.
+// apiToken - the API token requests must carry in the X-Algo-API-Token header, or empty to accept every request.
+func InitializeServerFromDirectoryLayout(fsys fs.FS, format encodedassets.EncodingFormat, apiToken string) (*Server,
+	error) {
+	bundle, err := encodedassets.ConvertDirectoryLayout(fsys, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return InitializeServerFromFixtureBundle(bundle, apiToken), nil
+}
+
+// AddStateProof adds a state proof response, served for every round in the interval it attests to.
+// Parameters:
+// stateProof - the state proof response, as returned by algod.
+func (s *Server) AddStateProof(stateProof models.StateProof) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stateProofs = append(s.stateProofs, stateProof)
+}
+
+// AddTransactionProof adds a transaction proof response, served for the given transaction.
+// Parameters:
+// round - the round the transaction was committed in.
+// transactionID - the transaction's ID.
+// transactionProof - the transaction proof response, as returned by algod.
+func (s *Server) AddTransactionProof(round uint64, transactionID types.Digest,
+	transactionProof models.TransactionProofResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// algod identifies transactions by the base32 encoding of their ID.
+	txID := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(transactionID[:])
+	s.transactionProofs[transactionKey{round: round, txID: txID}] = transactionProof
+}
+
+// AddLightBlockHeaderProof adds a light block header proof response, served for the given round.
+// Parameters:
+// round - the round of the light block header.
+// lightBlockHeaderProof - the light block header proof response, as returned by algod.
+func (s *Server) AddLightBlockHeaderProof(round uint64, lightBlockHeaderProof models.LightBlockHeaderProof) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lightBlockHeaderProofs[round] = lightBlockHeaderProof
+}
+
+// InjectFault injects a fault into an endpoint's responses. Faults injected into the same endpoint apply in the order
+// they were injected, each once its predecessors no longer apply.
+// Parameters:
+// endpoint - the endpoint to misbehave.
+// fault - the misbehavior.
+func (s *Server) InjectFault(endpoint Endpoint, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[endpoint] = append(s.faults[endpoint], &injectedFault{fault: fault, remaining: fault.Count})
+}
+
+// ClearFaults removes every fault injected into the Server.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = make(map[Endpoint][]*injectedFault)
+}
+
+// RequestCount returns the number of requests an endpoint received, including requests faults applied to.
+// Parameters:
+// endpoint - the endpoint whose requests to count.
+func (s *Server) RequestCount(endpoint Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[endpoint]
+}
+
+// Start starts serving on a local address, and returns the address.
+func (s *Server) Start() string {
+	s.httpServer = httptest.NewServer(s)
+	return s.httpServer.URL
+}
+
+// Client returns an algod client for the started Server. It returns ErrServerNotStarted if Start was not called.
+func (s *Server) Client() (*algod.Client, error) {
+	if s.httpServer == nil {
+		return nil, ErrServerNotStarted
+	}
+
+	return algod.MakeClient(s.httpServer.URL, s.apiToken)
+}
+
+// Close stops serving, blocking until every outstanding request completes. It returns ErrServerNotStarted if Start
+// was not called.
+func (s *Server) Close() error {
+	if s.httpServer == nil {
+		return ErrServerNotStarted
+	}
+
+	s.httpServer.Close()
+	return nil
+}
+
+// takeFault returns the fault applying to the current request to an endpoint, if any, and counts the request.
+// Parameters:
+// endpoint - the endpoint requested.
+func (s *Server) takeFault(endpoint Endpoint) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[endpoint]++
+	faults := s.faults[endpoint]
+	if len(faults) == 0 {
+		return Fault{}, false
+	}
+
+	fault := faults[0]
+	if fault.fault.Count > 0 {
+		fault.remaining--
+		if fault.remaining == 0 {
+			s.faults[endpoint] = faults[1:]
+		}
+	}
+
+	return fault.fault, true
+}
+
+// shiftRound returns a copy of a response, altered to describe a different round as described by Fault.RoundShift.
+// Parameters:
+// response - the response to alter.
+// shift - the number of rounds to shift the response by.
+func shiftRound(response interface{}, shift uint64) interface{} {
+	switch typedResponse := response.(type) {
+	case models.StateProof:
+		typedResponse.Message.Firstattestedround += shift
+		typedResponse.Message.Lastattestedround += shift
+		return typedResponse
+	case models.TransactionProofResponse:
+		typedResponse.Idx += shift
+		return typedResponse
+	case models.LightBlockHeaderProof:
+		typedResponse.Index += shift
+		return typedResponse
+	default:
+		return response
+	}
+}
+
+// writeError writes an algod style error response.
+// Parameters:
+// writer - the response writer.
+// statusCode - the HTTP status of the response.
+// message - the error's message.
+func writeError(writer http.ResponseWriter, statusCode int, message string) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	writer.Write(json.Encode(map[string]string{"message": message}))
+}
+
+// getEndpoint returns the endpoint a request's path belongs to, along with the path's parts.
+// Parameters:
+// request - the request to route.
+func getEndpoint(request *http.Request) (Endpoint, []string, bool) {
+	parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "v2" && parts[1] == "stateproofs":
+		return StateProofEndpoint, parts, true
+	case len(parts) == 6 && parts[0] == "v2" && parts[1] == "blocks" && parts[3] == "transactions" && parts[5] == "proof":
+		return TransactionProofEndpoint, parts, true
+	case len(parts) == 5 && parts[0] == "v2" && parts[1] == "blocks" && parts[3] == "lightheader" && parts[4] == "proof":
+		return LightBlockHeaderProofEndpoint, parts, true
+	default:
+		return 0, nil, false
+	}
+}
+
+// lookupResponse returns the response to serve for a request to an endpoint, or an error status and message if there
+// is none.
+// Parameters:
+// endpoint - the endpoint requested.
+// parts - the parts of the request's path.
+// request - the request.
+func (s *Server) lookupResponse(endpoint Endpoint, parts []string, request *http.Request) (interface{}, int, string) {
+	// Every endpoint takes the round as the path's third part.
+	round, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Sprintf("invalid round %q", parts[2])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch endpoint {
+	case StateProofEndpoint:
+		for _, stateProof := range s.stateProofs {
+			if stateProof.Message.Firstattestedround <= round && round <= stateProof.Message.Lastattestedround {
+				return stateProof, http.StatusOK, ""
+			}
+		}
+		return nil, http.StatusNotFound, "state proof not found"
+
+	case TransactionProofEndpoint:
+		transactionProof, exists := s.transactionProofs[transactionKey{round: round, txID: parts[4]}]
+		if !exists {
+			return nil, http.StatusNotFound, "transaction not found in block"
+		}
+
+		hashType := request.URL.Query().Get("hashtype")
+		if hashType != "" && hashType != transactionProof.Hashtype {
+			return nil, http.StatusBadRequest, fmt.Sprintf("unsupported hash type %q", hashType)
+		}
+		return transactionProof, http.StatusOK, ""
+
+	default:
+		lightBlockHeaderProof, exists := s.lightBlockHeaderProofs[round]
+		if !exists {
+			return nil, http.StatusNotFound, "light block header proof not found"
+		}
+		return lightBlockHeaderProof, http.StatusOK, ""
+	}
+}
+
+// ServeHTTP serves a request as algod would, applying the fault injected into the requested endpoint, if any.
+// Responses are encoded using JSON, or using msgpack if requested with format=msgpack.
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if s.apiToken != "" && request.Header.Get(apiTokenHeader) != s.apiToken {
+		writeError(writer, http.StatusUnauthorized, "invalid API token")
+		return
+	}
+
+	endpoint, parts, known := getEndpoint(request)
+	if !known {
+		writeError(writer, http.StatusNotFound, "unknown endpoint")
+		return
+	}
+
+	response, statusCode, message := s.lookupResponse(endpoint, parts, request)
+	fault, faulty := s.takeFault(endpoint)
+	if faulty && fault.Delay > 0 {
+		timer := time.NewTimer(fault.Delay)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	if faulty && fault.StatusCode != 0 {
+		writeError(writer, fault.StatusCode, fmt.Sprintf("injected fault for %s", endpoint))
+		return
+	}
+	if statusCode != http.StatusOK {
+		writeError(writer, statusCode, message)
+		return
+	}
+
+	if faulty && fault.RoundShift != 0 {
+		response = shiftRound(response, fault.RoundShift)
+	}
+
+	var encodedResponse []byte
+	if request.URL.Query().Get("format") == "msgpack" {
+		writer.Header().Set("Content-Type", "application/msgpack")
+		encodedResponse = msgpack.Encode(response)
+	} else {
+		writer.Header().Set("Content-Type", "application/json")
+		encodedResponse = json.Encode(response)
+	}
+
+	if faulty && fault.Corrupt && len(encodedResponse) > 0 {
+		encodedResponse[len(encodedResponse)/2] ^= 1
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(encodedResponse)
+}
@@ -0,0 +1,272 @@
+package mockalgod
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/relayer"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+const testAPIToken = "token"
+
+func loadTestFixtureBundle(t *testing.T) *encodedassets.FixtureBundle {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtureBundle
+}
+
+// startTestServer starts a Server serving the fixture bundle, and returns it along with a client for it.
+func startTestServer(t *testing.T, fixtureBundle *encodedassets.FixtureBundle) (*Server, *algod.Client) {
+	t.Helper()
+
+	server := InitializeServerFromFixtureBundle(fixtureBundle, testAPIToken)
+	server.Start()
+	t.Cleanup(func() {
+		err := server.Close()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+// getGenesisOracle returns an oracle initialized from the fixture bundle's genesis data.
+func getGenesisOracle(fixtureBundle *encodedassets.FixtureBundle) *oracle.Oracle {
+	return oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+		fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+}
+
+// getValidTransactionCase returns the fixture bundle's valid transaction case, whose proofs the Server serves.
+func getValidTransactionCase(t *testing.T,
+	fixtureBundle *encodedassets.FixtureBundle) encodedassets.FixtureTransactionCase {
+	t.Helper()
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError == "" {
+			return transactionCase
+		}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return encodedassets.FixtureTransactionCase{}
+}
+
+// verifyServedTransaction retrieves a transaction's proofs from algod, as a third party would, and verifies them using
+// the oracle.
+// Parameters:
+// ctx - the context of the requests to algod.
+// client - the algod client to retrieve the proofs from.
+// oracleInstance - the oracle to verify the proofs using.
+// transactionCase - the transaction to verify.
+func verifyServedTransaction(ctx context.Context, client *algod.Client, oracleInstance *oracle.Oracle,
+	transactionCase encodedassets.FixtureTransactionCase) error {
+	round := uint64(transactionCase.Round)
+	txID := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(transactionCase.TransactionID[:])
+
+	transactionProof, err := client.GetTransactionProof(round, txID).Hashtype(transactionverifier.Sha256HashType).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	lightBlockHeaderProof, err := client.GetLightBlockHeaderProof(round).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	bundle := inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID, transactionProof,
+		lightBlockHeaderProof, transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed)
+	return bundle.Verify(oracleInstance)
+}
+
+func TestServerNotStarted(t *testing.T) {
+	server := InitializeServer("")
+
+	_, err := server.Client()
+	if !errors.Is(err, ErrServerNotStarted) {
+		t.Fatalf("expected %v, got %v", ErrServerNotStarted, err)
+	}
+
+	err = server.Close()
+	if !errors.Is(err, ErrServerNotStarted) {
+		t.Fatalf("expected %v, got %v", ErrServerNotStarted, err)
+	}
+}
+
+func TestServerRejectsInvalidAPIToken(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	server, _ := startTestServer(t, fixtureBundle)
+
+	client, err := algod.MakeClient(server.httpServer.URL, "invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetStateProof(fixtureBundle.Network.FirstAttestedRound).Do(context.Background())
+	if err == nil {
+		t.Fatal("expected a request carrying an invalid API token to be rejected")
+	}
+	if server.RequestCount(StateProofEndpoint) != 0 {
+		t.Fatal("expected rejected requests not to be counted")
+	}
+}
+
+func TestRelayerAndVerifierWithoutFaults(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	server, client := startTestServer(t, fixtureBundle)
+
+	oracleInstance := getGenesisOracle(fixtureBundle)
+	relayerInstance := relayer.InitializeRelayer(client, oracleInstance, relayer.DefaultConfig())
+	err := relayerInstance.Step(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifyServedTransaction(context.Background(), client, oracleInstance, getValidTransactionCase(t, fixtureBundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, endpoint := range []Endpoint{StateProofEndpoint, TransactionProofEndpoint, LightBlockHeaderProofEndpoint} {
+		if server.RequestCount(endpoint) != 1 {
+			t.Fatalf("expected a single %s request, got %d", endpoint, server.RequestCount(endpoint))
+		}
+	}
+}
+
+// faultCase is a fault injected into an endpoint, along with the error it must cause. A nil expectedErr only requires
+// some error to occur.
+type faultCase struct {
+	name        string
+	fault       Fault
+	expectedErr error
+}
+
+func TestRelayerFaults(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+
+	faultCases := []faultCase{
+		{"delay", Fault{Delay: time.Second, Count: 1}, context.DeadlineExceeded},
+		{"status 404", Fault{StatusCode: http.StatusNotFound, Count: 1}, relayer.ErrStateProofNotAvailable},
+		{"status 500", Fault{StatusCode: http.StatusInternalServerError, Count: 1}, nil},
+		{"corrupt", Fault{Corrupt: true, Count: 1}, relayer.ErrInvalidStateProof},
+		{"round shift", Fault{RoundShift: fixtureBundle.Network.IntervalSize, Count: 1},
+			relayer.ErrUnexpectedStateProofInterval},
+	}
+
+	for _, faultCase := range faultCases {
+		t.Run(faultCase.name, func(t *testing.T) {
+			server, client := startTestServer(t, fixtureBundle)
+			server.InjectFault(StateProofEndpoint, faultCase.fault)
+
+			oracleInstance := getGenesisOracle(fixtureBundle)
+			config := relayer.DefaultConfig()
+			config.RequestTimeout = 50 * time.Millisecond
+			relayerInstance := relayer.InitializeRelayer(client, oracleInstance, config)
+
+			err := relayerInstance.Step(context.Background())
+			if err == nil || (faultCase.expectedErr != nil && !errors.Is(err, faultCase.expectedErr)) {
+				t.Fatalf("expected %v, got %v", faultCase.expectedErr, err)
+			}
+			if oracleInstance.BlockIntervalCommitmentHistory.NextInterval != 0 {
+				t.Fatal("expected the oracle not to advance")
+			}
+
+			// Once the fault no longer applies, the relayer advances the oracle.
+			err = relayerInstance.Step(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server.RequestCount(StateProofEndpoint) != 2 {
+				t.Fatalf("expected 2 state proof requests, got %d", server.RequestCount(StateProofEndpoint))
+			}
+		})
+	}
+}
+
+func TestVerifierFaults(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	transactionCase := getValidTransactionCase(t, fixtureBundle)
+
+	// A shifted transaction proof index selects another leaf, whereas a shifted light block header proof index no
+	// longer matches the round.
+	roundShiftErrs := map[Endpoint]error{
+		TransactionProofEndpoint:      transactionverifier.ErrRootMismatch,
+		LightBlockHeaderProofEndpoint: transactionverifier.ErrLightBlockHeaderIndexMismatch,
+	}
+
+	for endpoint, roundShiftErr := range roundShiftErrs {
+		faultCases := []faultCase{
+			{"delay", Fault{Delay: time.Second}, context.DeadlineExceeded},
+			{"status 404", Fault{StatusCode: http.StatusNotFound}, nil},
+			{"status 500", Fault{StatusCode: http.StatusInternalServerError}, nil},
+			{"corrupt", Fault{Corrupt: true}, nil},
+			{"round shift", Fault{RoundShift: 1}, roundShiftErr},
+		}
+
+		for _, faultCase := range faultCases {
+			t.Run(endpoint.String()+"/"+faultCase.name, func(t *testing.T) {
+				server, client := startTestServer(t, fixtureBundle)
+				oracleInstance := getGenesisOracle(fixtureBundle)
+				_, err := relayer.IngestStateProof(oracleInstance, fixtureBundle.StateProofs[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				server.InjectFault(endpoint, faultCase.fault)
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+
+				err = verifyServedTransaction(ctx, client, oracleInstance, transactionCase)
+				if err == nil || (faultCase.expectedErr != nil && !errors.Is(err, faultCase.expectedErr)) {
+					t.Fatalf("expected %v, got %v", faultCase.expectedErr, err)
+				}
+
+				// Once the faults are cleared, the transaction verifies.
+				server.ClearFaults()
+				err = verifyServedTransaction(context.Background(), client, oracleInstance, transactionCase)
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func TestFaultsApplyInOrder(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	server, client := startTestServer(t, fixtureBundle)
+	server.InjectFault(StateProofEndpoint, Fault{StatusCode: http.StatusNotFound, Count: 2})
+	server.InjectFault(StateProofEndpoint, Fault{RoundShift: 1, Count: 1})
+
+	oracleInstance := getGenesisOracle(fixtureBundle)
+	relayerInstance := relayer.InitializeRelayer(client, oracleInstance, relayer.DefaultConfig())
+
+	expectedErrs := []error{relayer.ErrStateProofNotAvailable, relayer.ErrStateProofNotAvailable,
+		relayer.ErrUnexpectedStateProofInterval, nil}
+	for i, expectedErr := range expectedErrs {
+		err := relayerInstance.Step(context.Background())
+		if !errors.Is(err, expectedErr) {
+			t.Fatalf("step %d: expected %v, got %v", i, expectedErr, err)
+		}
+	}
+}
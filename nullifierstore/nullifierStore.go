@@ -0,0 +1,309 @@
+package nullifierstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+// NullifierStoreVersion is the version of the persisted store format. It must be incremented whenever the format
+// changes.
+const NullifierStoreVersion = 1
+
+// MaxTransactionLife is the maximum number of rounds an Algorand transaction is valid for, i.e. the maximum difference
+// between its last valid round and its first valid round.
+const MaxTransactionLife = 1000
+
+var (
+	ErrAlreadyConsumed           = errors.New("transaction was already consumed")
+	ErrRoundPruned               = errors.New("round is earlier than the pruning horizon, so consumption cannot be checked")
+	ErrUnsupportedStoreVersion   = errors.New("persisted nullifier store version is unsupported")
+	ErrInvalidPersistedNullifier = errors.New("persisted nullifier is malformed")
+)
+
+// Nullifier identifies a consumed transaction.
+// Nullifiers are keyed by the transaction's Sha256 hash rather than by its ID, which is the result of invoking
+// Sha512_256 on "TX" || msgpack(transaction). The hash is what the transaction proof commits to, so it is the only
+// identifier a bundle proves; the ID cannot be derived from it, and trusting an ID supplied alongside the bundle would
+// let the submitter choose the nullifier. Both are unique to the transaction, so each transaction still has exactly
+// one nullifier. Bridges deduplicating by transaction ID must compute both from the transaction they hold, or record
+// the nullifier's TransactionHash along with the ID.
+type Nullifier struct {
+	// GenesisHash is the hash of the genesis block of the network the transaction was confirmed on.
+	GenesisHash types.Digest
+	// TransactionHash is the result of invoking Sha256 on the canonical msgpack encoded transaction, see Nullifier.
+	TransactionHash types.Digest
+	// Round is the round in which the transaction was confirmed.
+	Round types.Round
+}
+
+// Config holds the parameters of a NullifierStore.
+type Config struct {
+	// StateFile is the file nullifiers are persisted to, or empty to keep them in memory only.
+	StateFile string
+	// ValidityWindow is the number of rounds a consumed transaction's nullifier must be kept for after its round.
+	ValidityWindow uint64
+	// PruneMargin is the number of rounds nullifiers are kept for in addition to ValidityWindow.
+	PruneMargin uint64
+}
+
+// DefaultConfig returns the default NullifierStore parameters, keeping nullifiers in memory only.
+func DefaultConfig() Config {
+	return Config{
+		ValidityWindow: MaxTransactionLife,
+		PruneMargin:    MaxTransactionLife,
+	}
+}
+
+// persistedNullifier is the persisted form of a Nullifier.
+type persistedNullifier struct {
+	GenesisHash     []byte    `json:"genesis_hash"`
+	TransactionHash []byte    `json:"transaction_hash"`
+	Round           uint64    `json:"round"`
+	ConsumedAt      time.Time `json:"consumed_at"`
+}
+
+// persistedStore is the persisted form of a NullifierStore.
+type persistedStore struct {
+	Version      uint64               `json:"version"`
+	PrunedBefore uint64               `json:"pruned_before"`
+	Nullifiers   []persistedNullifier `json:"nullifiers"`
+}
+
+// NullifierStore records the transactions that were consumed, e.g. redeemed by a bridge, so that each transaction is
+// consumed only once. Nullifiers are persisted, so that they survive restarts.
+// Once pruned, a nullifier can no longer be checked, so transactions from rounds earlier than the pruning horizon are
+// rejected altogether. Pruning therefore bounds both the store's size and the time available for consuming a
+// transaction after its round.
+type NullifierStore struct {
+	mu     sync.Mutex
+	oracle *oracle.Oracle
+	config Config
+	// consumed maps the nullifiers of consumed transactions to the time they were consumed at.
+	consumed map[Nullifier]time.Time
+	// prunedBefore is the pruning horizon. Nullifiers of earlier rounds may have been pruned.
+	prunedBefore types.Round
+}
+
+// InitializeNullifierStore initializes a NullifierStore using an Oracle, restoring the nullifiers persisted to
+// Config.StateFile, if it exists.
+// Parameters:
+// oracleInstance - the Oracle used for verification.
+// config - the store's parameters.
+func InitializeNullifierStore(oracleInstance *oracle.Oracle, config Config) (*NullifierStore, error) {
+	store := &NullifierStore{
+		oracle:   oracleInstance,
+		config:   config,
+		consumed: make(map[Nullifier]time.Time),
+	}
+
+	if config.StateFile == "" {
+		return store, nil
+	}
+
+	encodedStore, err := os.ReadFile(config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var persisted persistedStore
+	err = json.Unmarshal(encodedStore, &persisted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nullifier store %s: %w", config.StateFile, err)
+	}
+	if persisted.Version != NullifierStoreVersion {
+		return nil, ErrUnsupportedStoreVersion
+	}
+
+	store.prunedBefore = types.Round(persisted.PrunedBefore)
+	for _, persistedEntry := range persisted.Nullifiers {
+		var nullifier Nullifier
+		if len(persistedEntry.GenesisHash) != len(nullifier.GenesisHash) ||
+			len(persistedEntry.TransactionHash) != len(nullifier.TransactionHash) {
+			return nil, ErrInvalidPersistedNullifier
+		}
+
+		copy(nullifier.GenesisHash[:], persistedEntry.GenesisHash)
+		copy(nullifier.TransactionHash[:], persistedEntry.TransactionHash)
+		nullifier.Round = types.Round(persistedEntry.Round)
+		store.consumed[nullifier] = persistedEntry.ConsumedAt
+	}
+
+	return store, nil
+}
+
+// GetNullifier returns the nullifier of an inclusion proof bundle's transaction.
+// Parameters:
+// bundle - the bundle proving the transaction's occurrence.
+func GetNullifier(bundle *inclusionbundle.InclusionProofBundle) Nullifier {
+	return Nullifier{GenesisHash: bundle.GenesisHash, TransactionHash: bundle.TransactionHash, Round: bundle.Round}
+}
+
+// save persists the nullifiers to Config.StateFile. The file is replaced atomically, so that an interrupted save never
+// leaves a corrupted store behind. The caller must hold the store's lock.
+func (s *NullifierStore) save() error {
+	if s.config.StateFile == "" {
+		return nil
+	}
+
+	persisted := persistedStore{
+		Version:      NullifierStoreVersion,
+		PrunedBefore: uint64(s.prunedBefore),
+		Nullifiers:   []persistedNullifier{},
+	}
+	for nullifier, consumedAt := range s.consumed {
+		genesisHash := nullifier.GenesisHash
+		transactionHash := nullifier.TransactionHash
+		persisted.Nullifiers = append(persisted.Nullifiers, persistedNullifier{
+			GenesisHash:     genesisHash[:],
+			TransactionHash: transactionHash[:],
+			Round:           uint64(nullifier.Round),
+			ConsumedAt:      consumedAt,
+		})
+	}
+	// We order the nullifiers by round, so that the persisted store is deterministic.
+	sort.Slice(persisted.Nullifiers, func(i, j int) bool {
+		first, second := persisted.Nullifiers[i], persisted.Nullifiers[j]
+		if first.Round != second.Round {
+			return first.Round < second.Round
+		}
+		return string(first.TransactionHash) < string(second.TransactionHash)
+	})
+
+	encodedStore, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(s.config.StateFile), filepath.Base(s.config.StateFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file fails harmlessly once it has been renamed.
+	defer os.Remove(temporaryFile.Name())
+
+	_, err = temporaryFile.Write(encodedStore)
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	// The nullifier must be durable before the caller acts on the consumption.
+	err = temporaryFile.Sync()
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	err = temporaryFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryFile.Name(), s.config.StateFile)
+}
+
+// IsConsumed returns true if the transaction identified by the nullifier was consumed.
+// Parameters:
+// nullifier - the transaction's nullifier.
+func (s *NullifierStore) IsConsumed(nullifier Nullifier) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.consumed[nullifier]
+	return exists
+}
+
+// VerifyAndConsume verifies an inclusion proof bundle's transaction using the Oracle, and marks it as consumed. It
+// fails with ErrAlreadyConsumed if the transaction was already consumed, and with ErrRoundPruned if the bundle's
+// round is earlier than the pruning horizon. The transaction is consumed only if verification succeeds and the
+// nullifier was persisted, and concurrent calls for the same transaction consume it exactly once.
+// Parameters:
+// bundle - the bundle proving the transaction's occurrence.
+func (s *NullifierStore) VerifyAndConsume(bundle *inclusionbundle.InclusionProofBundle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nullifier := GetNullifier(bundle)
+	if nullifier.Round < s.prunedBefore {
+		return ErrRoundPruned
+	}
+
+	_, exists := s.consumed[nullifier]
+	if exists {
+		return ErrAlreadyConsumed
+	}
+
+	err := bundle.Verify(s.oracle)
+	if err != nil {
+		return err
+	}
+
+	s.consumed[nullifier] = time.Now()
+	err = s.save()
+	if err != nil {
+		delete(s.consumed, nullifier)
+		return err
+	}
+
+	return nil
+}
+
+// Prune removes the nullifiers of rounds earlier than the pruning horizon, which is ValidityWindow + PruneMargin
+// rounds before the last round attested to by the Oracle, and returns the number of nullifiers removed.
+func (s *NullifierStore) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := s.oracle.BlockIntervalCommitmentHistory
+	nextRound := history.FirstAttestedRound + history.NextInterval*history.IntervalSize
+	retainedRounds := s.config.ValidityWindow + s.config.PruneMargin
+	// We never move the horizon backwards, and never past the rounds attested to so far.
+	if nextRound <= retainedRounds+1 || types.Round(nextRound-1-retainedRounds) <= s.prunedBefore {
+		return 0, nil
+	}
+
+	previousHorizon := s.prunedBefore
+	s.prunedBefore = types.Round(nextRound - 1 - retainedRounds)
+
+	removed := make(map[Nullifier]time.Time)
+	for nullifier, consumedAt := range s.consumed {
+		if nullifier.Round < s.prunedBefore {
+			removed[nullifier] = consumedAt
+			delete(s.consumed, nullifier)
+		}
+	}
+
+	err := s.save()
+	if err != nil {
+		// We restore the store as it was persisted, so that memory never holds fewer nullifiers than the file.
+		s.prunedBefore = previousHorizon
+		for nullifier, consumedAt := range removed {
+			s.consumed[nullifier] = consumedAt
+		}
+		return 0, err
+	}
+
+	return len(removed), nil
+}
+
+// PrunedBefore returns the pruning horizon: transactions from earlier rounds cannot be consumed.
+func (s *NullifierStore) PrunedBefore() types.Round {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.prunedBefore
+}
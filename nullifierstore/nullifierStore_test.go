@@ -0,0 +1,295 @@
+package nullifierstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// loadTestFixtures returns an oracle advanced using the fixture bundle's state proof, along with an inclusion proof
+// bundle of the fixture bundle's valid transaction case.
+func loadTestFixtures(t *testing.T) (*oracle.Oracle, *inclusionbundle.InclusionProofBundle) {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound,
+		fixtureBundle.Network.IntervalSize, fixtureBundle.Genesis.VotersCommitment,
+		fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	message, stateProof, err := encodedassets.ParseStateProofResponse(fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = oracleInstance.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError == "" {
+			return oracleInstance, inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID,
+				transactionCase.TransactionProofResponse, transactionCase.LightBlockHeaderProofResponse,
+				transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed)
+		}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return nil, nil
+}
+
+func TestVerifyAndConsume(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	store, err := InitializeNullifierStore(oracleInstance, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A transaction failing verification is not consumed.
+	tampered := *bundle
+	tampered.Seed[0] ^= 1
+	err = store.VerifyAndConsume(&tampered)
+	if !errors.Is(err, transactionverifier.ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", transactionverifier.ErrRootMismatch, err)
+	}
+	if store.IsConsumed(GetNullifier(&tampered)) {
+		t.Fatal("expected a transaction failing verification not to be consumed")
+	}
+
+	err = store.VerifyAndConsume(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.IsConsumed(GetNullifier(bundle)) {
+		t.Fatal("expected the transaction to be consumed")
+	}
+
+	// The same transaction, encoded differently, is still a duplicate.
+	decodedBundle, err := inclusionbundle.DecodeJSON(bundle.EncodeJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.VerifyAndConsume(decodedBundle)
+	if !errors.Is(err, ErrAlreadyConsumed) {
+		t.Fatalf("expected %v, got %v", ErrAlreadyConsumed, err)
+	}
+}
+
+func TestConcurrentConsumptionSucceedsOnce(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	config := DefaultConfig()
+	config.StateFile = filepath.Join(t.TempDir(), "nullifiers.json")
+	store, err := InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const consumers = 16
+	errs := make(chan error, consumers)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			errs <- store.VerifyAndConsume(bundle)
+		}()
+	}
+	waitGroup.Wait()
+	close(errs)
+
+	consumed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			consumed++
+		case !errors.Is(err, ErrAlreadyConsumed):
+			t.Fatalf("expected %v, got %v", ErrAlreadyConsumed, err)
+		}
+	}
+	if consumed != 1 {
+		t.Fatalf("expected the transaction to be consumed exactly once, got %d", consumed)
+	}
+}
+
+func TestFailedSaveDoesNotConsume(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	directory := filepath.Join(t.TempDir(), "nullifiers")
+	err := os.Mkdir(directory, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.StateFile = filepath.Join(directory, "nullifiers.json")
+	store, err := InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving fails once the state file's directory is gone.
+	err = os.Remove(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.VerifyAndConsume(bundle)
+	if err == nil {
+		t.Fatal("expected consuming to fail when the nullifier cannot be persisted")
+	}
+	if store.IsConsumed(GetNullifier(bundle)) {
+		t.Fatal("expected a nullifier that was not persisted not to be consumed")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	directory := t.TempDir()
+	config := Config{StateFile: filepath.Join(directory, "nullifiers.json"), ValidityWindow: 2}
+	store, err := InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.VerifyAndConsume(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The oracle attests to rounds up to 16, so rounds earlier than 16 - ValidityWindow are pruned.
+	removed, err := store.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || store.PrunedBefore() != 14 || store.IsConsumed(GetNullifier(bundle)) {
+		t.Fatalf("expected the nullifier to be pruned up to round 14, removed %d up to round %d", removed,
+			store.PrunedBefore())
+	}
+
+	err = store.VerifyAndConsume(bundle)
+	if !errors.Is(err, ErrRoundPruned) {
+		t.Fatalf("expected %v, got %v", ErrRoundPruned, err)
+	}
+
+	// The horizon only moves as the oracle advances.
+	removed, err = store.Prune()
+	if err != nil || removed != 0 || store.PrunedBefore() != 14 {
+		t.Fatalf("expected pruning again to remove nothing, removed %d up to round %d: %v", removed,
+			store.PrunedBefore(), err)
+	}
+
+	// Nothing is pruned while the oracle attests to fewer rounds than are retained.
+	defaultStore, err := InitializeNullifierStore(oracleInstance, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err = defaultStore.Prune()
+	if err != nil || removed != 0 || defaultStore.PrunedBefore() != 0 {
+		t.Fatalf("expected nothing to be pruned, removed %d up to round %d: %v", removed,
+			defaultStore.PrunedBefore(), err)
+	}
+}
+
+func TestFailedPruneRestoresNullifiers(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	directory := filepath.Join(t.TempDir(), "nullifiers")
+	err := os.Mkdir(directory, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := InitializeNullifierStore(oracleInstance, Config{StateFile: filepath.Join(directory, "nullifiers.json"),
+		ValidityWindow: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.VerifyAndConsume(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.RemoveAll(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Prune()
+	if err == nil {
+		t.Fatal("expected pruning to fail when the store cannot be persisted")
+	}
+	if !store.IsConsumed(GetNullifier(bundle)) || store.PrunedBefore() != 0 {
+		t.Fatal("expected a failed prune to leave the store as it was persisted")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	oracleInstance, bundle := loadTestFixtures(t)
+	config := Config{StateFile: filepath.Join(t.TempDir(), "nullifiers.json"), ValidityWindow: 2}
+	store, err := InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.VerifyAndConsume(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredStore, err := InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = restoredStore.VerifyAndConsume(bundle)
+	if !errors.Is(err, ErrAlreadyConsumed) {
+		t.Fatalf("expected the restored store to hold the nullifier, got %v", err)
+	}
+
+	_, err = restoredStore.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredStore, err = InitializeNullifierStore(oracleInstance, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredStore.PrunedBefore() != 14 || restoredStore.IsConsumed(GetNullifier(bundle)) {
+		t.Fatalf("expected the pruning horizon to be restored, got round %d", restoredStore.PrunedBefore())
+	}
+}
+
+func TestInitializeNullifierStoreErrors(t *testing.T) {
+	oracleInstance, _ := loadTestFixtures(t)
+
+	testCases := map[string]struct {
+		encodedStore string
+		expectedErr  error
+	}{
+		"unsupported version": {`{"version": 2, "nullifiers": []}`, ErrUnsupportedStoreVersion},
+		"malformed nullifier": {`{"version": 1, "nullifiers": [{"genesis_hash": "AAAA", "transaction_hash": "AAAA"}]}`,
+			ErrInvalidPersistedNullifier},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.StateFile = filepath.Join(t.TempDir(), "nullifiers.json")
+			err := os.WriteFile(config.StateFile, []byte(testCase.encodedStore), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = InitializeNullifierStore(oracleInstance, config)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
@@ -46,22 +46,28 @@ func InitializeCommitmentHistory(firstAttestedRound uint64, intervalSize uint64,
 	}
 }
 
-// GetCommitment receives a round and returns the block interval commitment for the interval that covers the given round.
+// GetCoveringInterval receives a round and returns the interval that covers it, regardless of whether a commitment
+// for the interval is currently saved in history.
 // Parameters:
-// round - the round to return the commitment for.
-func (c *CommitmentHistory) GetCommitment(round types.Round) (types.Digest, error) {
+// round - the round to return the covering interval for.
+func (c *CommitmentHistory) GetCoveringInterval(round types.Round) (uint64, error) {
 	// Rounds earlier than state proof generation beginning can not have commitments.
 	if uint64(round) < c.FirstAttestedRound {
-		return types.Digest{}, ErrTooEarlyRoundRequested
+		return 0, ErrTooEarlyRoundRequested
 	}
 
-	// coveringInterval is the interval that covers the given round.
-	coveringInterval := (uint64(round) - c.FirstAttestedRound) / c.IntervalSize
+	// Each interval covers IntervalSize consecutive rounds, with the first interval starting at FirstAttestedRound.
+	return (uint64(round) - c.FirstAttestedRound) / c.IntervalSize, nil
+}
 
-	// Intervals begin in rounds that come after rounds that are divisible by IntervalSize, so if our round is divisible
-	// by IntervalSize we have to adjust its interval accordingly.
-	if uint64(round)%c.IntervalSize == 0 {
-		coveringInterval -= 1
+// GetCommitment receives a round and returns the block interval commitment for the interval that covers the given round.
+// Parameters:
+// round - the round to return the commitment for.
+func (c *CommitmentHistory) GetCommitment(round types.Round) (types.Digest, error) {
+	// coveringInterval is the interval that covers the given round.
+	coveringInterval, err := c.GetCoveringInterval(round)
+	if err != nil {
+		return types.Digest{}, err
 	}
 
 	// If we either don't yet have a commitment for the round or we've already discarded the commitment for the round,
@@ -51,16 +51,7 @@ func InitializeOracle(firstAttestedRound uint64, intervalSize uint64, genesisVot
 // stateProof - the decoded state proof, retrieved using the Algorand SDK.
 // message - the message to which the state proof attests.
 func (o *Oracle) AdvanceState(stateProof *stateproof.StateProof, message types.Message) error {
-	// verifier is Algorand's implementation of the state proof verifier, exposed by the state proof verification library.
-	// It uses the previous proven VotersCommitment and LnProvenWeight.
-	verifier := stateproof.MkVerifierWithLnProvenWeight(o.VotersCommitment, o.LnProvenWeight)
-
-	// We hash the state proof message using the Algorand SDK. The resulting hash is of the form
-	// sha256("spm" || msgpack(stateProofMessage)).
-	messageHash := stateproofcrypto.MessageHash(crypto.HashStateProofMessage(&message))
-
-	// The newly formed verifier verifies the given message using the state proof.
-	err := verifier.Verify(message.LastAttestedRound, messageHash, stateProof)
+	err := o.VerifyStateProof(stateProof, message)
 	if err != nil {
 		// If the verification failed, for whatever reason, we return the error returned.
 		return err
@@ -81,6 +72,25 @@ func (o *Oracle) AdvanceState(stateProof *stateproof.StateProof, message types.M
 	return nil
 }
 
+// VerifyStateProof verifies a state proof message using the proof given and the VotersCommitment and LnProvenWeight
+// from the previous state proof message, exactly as AdvanceState does, without advancing the Oracle's state.
+// It allows choosing between several candidate state proofs before advancing using one of them.
+// Parameters:
+// stateProof - the decoded state proof, retrieved using the Algorand SDK.
+// message - the message to which the state proof attests.
+func (o *Oracle) VerifyStateProof(stateProof *stateproof.StateProof, message types.Message) error {
+	// verifier is Algorand's implementation of the state proof verifier, exposed by the state proof verification library.
+	// It uses the previous proven VotersCommitment and LnProvenWeight.
+	verifier := stateproof.MkVerifierWithLnProvenWeight(o.VotersCommitment, o.LnProvenWeight)
+
+	// We hash the state proof message using the Algorand SDK. The resulting hash is of the form
+	// sha256("spm" || msgpack(stateProofMessage)).
+	messageHash := stateproofcrypto.MessageHash(crypto.HashStateProofMessage(&message))
+
+	// The newly formed verifier verifies the given message using the state proof.
+	return verifier.Verify(message.LastAttestedRound, messageHash, stateProof)
+}
+
 // GetStateProofCommitment retrieves a saved commitment for a specific round.
 // Parameters:
 // round - the round to which a commitment will be retrieved.
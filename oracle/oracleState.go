@@ -0,0 +1,165 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproofcrypto"
+)
+
+// OracleStateVersion is the version of the state format produced by this package. States of any other version are
+// rejected.
+const OracleStateVersion = 1
+
+var (
+	ErrUnsupportedStateVersion    = errors.New("oracle state version is unsupported")
+	ErrInvalidStateIntervalSize   = errors.New("oracle state interval size must be positive")
+	ErrStateCommitmentsMismatch   = errors.New("oracle state commitments do not match its earliest and next intervals")
+	ErrStateCapacityExceeded      = errors.New("oracle state holds more commitments than its capacity")
+	ErrInvalidStateCommitmentSize = errors.New("oracle state commitment is not a digest")
+)
+
+// OracleState is a serializable snapshot of an Oracle. It allows an Oracle to persist across process restarts, and to
+// be initialized from a checkpoint taken by another, trusted, Oracle instead of from genesis.
+type OracleState struct {
+	// Version is the version of the state format.
+	Version uint64 `json:"version"`
+	// FirstAttestedRound is the first round to which a state proof message attests.
+	FirstAttestedRound uint64 `json:"first_attested_round"`
+	// IntervalSize is the number of rounds each state proof message attests to.
+	IntervalSize uint64 `json:"interval_size"`
+	// Capacity is the maximum number of commitments to hold before discarding the earliest commitment.
+	Capacity uint64 `json:"capacity"`
+	// EarliestInterval is the earliest interval saved in the state.
+	EarliestInterval uint64 `json:"earliest_interval"`
+	// NextInterval is the interval to which the next state proof attests.
+	NextInterval uint64 `json:"next_interval"`
+	// Commitments are the block interval commitments of every interval from EarliestInterval up to, but not
+	// including, NextInterval, ordered by interval.
+	Commitments []IntervalCommitment `json:"commitments"`
+	// VotersCommitment is the vector commitment root of the top N accounts to sign the next StateProof.
+	VotersCommitment []byte `json:"voters_commitment"`
+	// LnProvenWeight is the natural log of the proven weight with 16 bits of precision, used to verify the next state proof.
+	LnProvenWeight uint64 `json:"ln_proven_weight"`
+}
+
+// IntervalCommitment is a block interval commitment, along with the interval it attests to.
+type IntervalCommitment struct {
+	Interval   uint64 `json:"interval"`
+	Commitment []byte `json:"commitment"`
+}
+
+// State returns a snapshot of the Oracle. The snapshot shares no memory with the Oracle.
+func (o *Oracle) State() *OracleState {
+	history := o.BlockIntervalCommitmentHistory
+
+	commitments := make([]IntervalCommitment, 0, len(history.Data))
+	for interval, commitment := range history.Data {
+		commitments = append(commitments, IntervalCommitment{
+			Interval:   interval,
+			Commitment: append([]byte{}, commitment[:]...),
+		})
+	}
+	sort.Slice(commitments, func(i, j int) bool {
+		return commitments[i].Interval < commitments[j].Interval
+	})
+
+	return &OracleState{
+		Version:            OracleStateVersion,
+		FirstAttestedRound: history.FirstAttestedRound,
+		IntervalSize:       history.IntervalSize,
+		Capacity:           history.Capacity,
+		EarliestInterval:   history.EarliestInterval,
+		NextInterval:       history.NextInterval,
+		Commitments:        commitments,
+		VotersCommitment:   append([]byte{}, o.VotersCommitment...),
+		LnProvenWeight:     o.LnProvenWeight,
+	}
+}
+
+// Checkpoint returns a snapshot of the Oracle holding only its most recent commitments. A checkpoint allows another
+// Oracle to start from the Oracle's current state without ingesting every state proof since genesis.
+// Parameters:
+// intervals - the number of most recent commitments to keep. Zero keeps none, in which case the checkpoint only
+// allows advancing from the current state.
+func (o *Oracle) Checkpoint(intervals uint64) *OracleState {
+	state := o.State()
+	if uint64(len(state.Commitments)) > intervals {
+		state.Commitments = state.Commitments[uint64(len(state.Commitments))-intervals:]
+		state.EarliestInterval = state.NextInterval - intervals
+	}
+
+	return state
+}
+
+// RestoreOracle initializes an Oracle from a snapshot. The snapshot is validated, but must come from a trusted source,
+// as its commitments are not verified again.
+// Parameters:
+// state - the snapshot to restore the Oracle from.
+func RestoreOracle(state *OracleState) (*Oracle, error) {
+	if state.Version != OracleStateVersion {
+		return nil, ErrUnsupportedStateVersion
+	}
+
+	if state.IntervalSize == 0 {
+		return nil, ErrInvalidStateIntervalSize
+	}
+
+	// Commitments must cover exactly the intervals from EarliestInterval up to NextInterval, as the history assumes
+	// every interval in that range has a commitment.
+	if state.NextInterval < state.EarliestInterval ||
+		uint64(len(state.Commitments)) != state.NextInterval-state.EarliestInterval {
+		return nil, ErrStateCommitmentsMismatch
+	}
+
+	if uint64(len(state.Commitments)) > state.Capacity {
+		return nil, ErrStateCapacityExceeded
+	}
+
+	history := InitializeCommitmentHistory(state.FirstAttestedRound, state.IntervalSize, state.Capacity)
+	history.EarliestInterval = state.EarliestInterval
+	history.NextInterval = state.NextInterval
+	for i, intervalCommitment := range state.Commitments {
+		if intervalCommitment.Interval != state.EarliestInterval+uint64(i) {
+			return nil, ErrStateCommitmentsMismatch
+		}
+
+		var commitment types.Digest
+		if len(intervalCommitment.Commitment) != len(commitment) {
+			return nil, ErrInvalidStateCommitmentSize
+		}
+		copy(commitment[:], intervalCommitment.Commitment)
+		history.Data[intervalCommitment.Interval] = commitment
+	}
+
+	return &Oracle{
+		BlockIntervalCommitmentHistory: history,
+		VotersCommitment:               stateproofcrypto.GenericDigest(append([]byte{}, state.VotersCommitment...)),
+		LnProvenWeight:                 state.LnProvenWeight,
+	}, nil
+}
+
+// EncodeState returns the Oracle's snapshot encoded as indented JSON.
+func (o *Oracle) EncodeState() ([]byte, error) {
+	return json.MarshalIndent(o.State(), "", "  ")
+}
+
+// DecodeOracle initializes an Oracle from a JSON encoded snapshot. Unknown fields are rejected.
+// Parameters:
+// encodedState - the JSON encoded snapshot, as returned by EncodeState.
+func DecodeOracle(encodedState []byte) (*Oracle, error) {
+	decoder := json.NewDecoder(bytes.NewReader(encodedState))
+	decoder.DisallowUnknownFields()
+
+	var state OracleState
+	err := decoder.Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	return RestoreOracle(&state)
+}
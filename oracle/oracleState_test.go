@@ -0,0 +1,203 @@
+package oracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+)
+
+// initializeAdvancedOracle returns an Oracle that was advanced using the fixture bundle's state proofs.
+func initializeAdvancedOracle(t *testing.T) *Oracle {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+		fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	for _, stateProofResponse := range fixtureBundle.StateProofs {
+		message, stateProof, err := encodedassets.ParseStateProofResponse(stateProofResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = oracleInstance.AdvanceState(stateProof, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return oracleInstance
+}
+
+// initializeOracleWithCommitments returns an Oracle holding the given number of distinct commitments, of which the
+// earliest were discarded once the capacity was exceeded.
+func initializeOracleWithCommitments(numberOfCommitments int, capacity uint64) *Oracle {
+	oracleInstance := InitializeOracle(9, 8, bytes.Repeat([]byte{7}, 64), 1234, capacity)
+	for i := 0; i < numberOfCommitments; i++ {
+		oracleInstance.BlockIntervalCommitmentHistory.InsertCommitment(types.Digest{byte(i + 1)})
+	}
+
+	return oracleInstance
+}
+
+func TestEncodeStateRoundTrip(t *testing.T) {
+	oracleInstances := map[string]*Oracle{
+		"genesis":             initializeOracleWithCommitments(0, 10),
+		"advanced":            initializeAdvancedOracle(t),
+		"discarded intervals": initializeOracleWithCommitments(7, 4),
+	}
+
+	for name, oracleInstance := range oracleInstances {
+		encodedState, err := oracleInstance.EncodeState()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		decodedOracle, err := DecodeOracle(encodedState)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(decodedOracle, oracleInstance) {
+			t.Fatalf("%s: expected %+v, got %+v", name, oracleInstance, decodedOracle)
+		}
+
+		reencodedState, err := decodedOracle.EncodeState()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(reencodedState, encodedState) {
+			t.Fatalf("%s: expected %s, got %s", name, encodedState, reencodedState)
+		}
+	}
+}
+
+func TestStateSharesNoMemory(t *testing.T) {
+	oracleInstance := initializeAdvancedOracle(t)
+	expectedState := oracleInstance.State()
+
+	state := oracleInstance.State()
+	state.VotersCommitment[0] ^= 1
+	state.Commitments[0].Commitment[0] ^= 1
+
+	if !reflect.DeepEqual(oracleInstance.State(), expectedState) {
+		t.Fatal("expected modifying a snapshot to leave the Oracle unchanged")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	oracleInstance := initializeOracleWithCommitments(7, 4)
+	history := oracleInstance.BlockIntervalCommitmentHistory
+
+	for intervals := uint64(0); intervals <= 5; intervals++ {
+		restoredOracle, err := RestoreOracle(oracleInstance.Checkpoint(intervals))
+		if err != nil {
+			t.Fatalf("%d intervals: %v", intervals, err)
+		}
+
+		restoredHistory := restoredOracle.BlockIntervalCommitmentHistory
+		expectedLength := intervals
+		if expectedLength > uint64(len(history.Data)) {
+			expectedLength = uint64(len(history.Data))
+		}
+		if uint64(len(restoredHistory.Data)) != expectedLength {
+			t.Fatalf("%d intervals: expected %d commitments, got %d", intervals, expectedLength,
+				len(restoredHistory.Data))
+		}
+		if restoredHistory.NextInterval != history.NextInterval {
+			t.Fatalf("%d intervals: expected next interval %d, got %d", intervals, history.NextInterval,
+				restoredHistory.NextInterval)
+		}
+		for interval := restoredHistory.EarliestInterval; interval < restoredHistory.NextInterval; interval++ {
+			if restoredHistory.Data[interval] != history.Data[interval] {
+				t.Fatalf("%d intervals: expected %x, got %x", intervals, history.Data[interval],
+					restoredHistory.Data[interval])
+			}
+		}
+
+		// A restored Oracle must keep advancing exactly as the Oracle it was checkpointed from.
+		restoredHistory.InsertCommitment(types.Digest{0xff})
+		if restoredHistory.NextInterval != history.NextInterval+1 || restoredHistory.Data[history.NextInterval] !=
+			(types.Digest{0xff}) {
+			t.Fatalf("%d intervals: expected the restored Oracle to insert interval %d", intervals,
+				history.NextInterval)
+		}
+	}
+}
+
+func TestRestoreOracleErrors(t *testing.T) {
+	testCases := map[string]struct {
+		tamper      func(state *OracleState)
+		expectedErr error
+	}{
+		"unsupported version": {func(state *OracleState) { state.Version++ }, ErrUnsupportedStateVersion},
+		"zero interval size":  {func(state *OracleState) { state.IntervalSize = 0 }, ErrInvalidStateIntervalSize},
+		"missing commitment": {func(state *OracleState) { state.Commitments = state.Commitments[1:] },
+			ErrStateCommitmentsMismatch},
+		"earliest after next": {func(state *OracleState) { state.EarliestInterval = state.NextInterval + 1 },
+			ErrStateCommitmentsMismatch},
+		"out of order commitments": {func(state *OracleState) { state.Commitments[0].Interval++ },
+			ErrStateCommitmentsMismatch},
+		"capacity exceeded": {func(state *OracleState) { state.Capacity-- }, ErrStateCapacityExceeded},
+		"short commitment": {func(state *OracleState) {
+			state.Commitments[1].Commitment = state.Commitments[1].Commitment[:31]
+		}, ErrInvalidStateCommitmentSize},
+	}
+
+	for name, testCase := range testCases {
+		state := initializeOracleWithCommitments(7, 4).State()
+		testCase.tamper(state)
+
+		restoredOracle, err := RestoreOracle(state)
+		if !errors.Is(err, testCase.expectedErr) || restoredOracle != nil {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestDecodeOracleErrors(t *testing.T) {
+	encodedState, err := initializeAdvancedOracle(t).EncodeState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state map[string]interface{}
+	err = json.Unmarshal(encodedState, &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state["version"] = OracleStateVersion + 1
+	unsupportedVersion, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		encodedState []byte
+		expectedErr  error
+	}{
+		"unsupported version": {unsupportedVersion, ErrUnsupportedStateVersion},
+		"unknown field":       {append([]byte(`{"zz":1,`), encodedState[1:]...), nil},
+		"truncated state":     {encodedState[:len(encodedState)/2], nil},
+		"not JSON":            {[]byte("version: 1"), nil},
+		"empty state":         {nil, nil},
+	}
+
+	for name, testCase := range testCases {
+		decodedOracle, err := DecodeOracle(testCase.encodedState)
+		if err == nil || decodedOracle != nil {
+			t.Fatalf("%s: expected an error, got %+v", name, decodedOracle)
+		}
+		if testCase.expectedErr != nil && !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package randomnessbeacon
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// RandomnessOutput is prepended to the round and the verified seed when deriving the beacon's output. It separates
+// the beacon's output from any other hash computed over a block's seed.
+var RandomnessOutput = []byte("LCRB")
+
+// VerifiedRandomness holds a seed that was verified to belong to a given round, along with the output derived from it.
+type VerifiedRandomness struct {
+	// Round is the round of the block the seed belongs to.
+	Round types.Round
+	// Seed is the sortition seed of the block, as verified using the Oracle's commitment.
+	Seed types.Seed
+	// Output is the value dApps should consume as randomness. See deriveOutput for more details.
+	Output types.Digest
+}
+
+// Beacon is a randomness beacon built on top of the Oracle. Every block's light block header contains the block's
+// seed, and light block headers are committed to by the block interval commitments the Oracle holds. This means that
+// given a light block header proof, the Beacon can verify a block's seed without trusting the party that provided it.
+type Beacon struct {
+	// oracle is the Oracle used to retrieve block interval commitments.
+	oracle *oracle.Oracle
+}
+
+// InitializeBeacon initializes the Beacon using an Oracle.
+// Parameters:
+// oracleInstance - the Oracle to retrieve block interval commitments from. Its state should be advanced separately.
+func InitializeBeacon(oracleInstance *oracle.Oracle) *Beacon {
+	return &Beacon{
+		oracle: oracleInstance,
+	}
+}
+
+// deriveOutput computes the beacon's output for a verified seed. The output is of the form
+// Sha256("LCRB" || round || seed), with round encoded as a big-endian uint64. Binding the round into the output
+// makes sure two rounds never share an output, even if they were to share a seed.
+func deriveOutput(round types.Round, seed types.Seed) types.Digest {
+	var encodedRound [8]byte
+	binary.BigEndian.PutUint64(encodedRound[:], uint64(round))
+
+	outputData := make([]byte, 0, len(RandomnessOutput)+len(encodedRound)+len(seed))
+	outputData = append(outputData, RandomnessOutput...)
+	outputData = append(outputData, encodedRound[:]...)
+	outputData = append(outputData, seed[:]...)
+
+	return sha256.Sum256(outputData)
+}
+
+// GetVerifiedRandomness verifies the given seed using the light block header proof and the block interval commitment
+// the Oracle holds for the given round. If successful, it returns the seed along with the output derived from it.
+// Parameters:
+// round - the round to retrieve randomness for.
+// seed - the sortition seed of the block in the given round.
+// transactionCommitment - the sha256 vector commitment root for the transactions in the block in the given round.
+// genesisHash - the hash of the genesis block.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+func (b *Beacon) GetVerifiedRandomness(round types.Round, seed types.Seed, transactionCommitment types.Digest,
+	genesisHash types.Digest, lightBlockHeaderProofResponse models.LightBlockHeaderProof) (VerifiedRandomness, error) {
+	// We first retrieve the block interval commitment for the given round from the Oracle.
+	blockIntervalCommitment, err := b.oracle.GetStateProofCommitment(round)
+	if err != nil {
+		return VerifiedRandomness{}, err
+	}
+
+	// We then verify the light block header containing the seed against the retrieved commitment.
+	// The proof's position is checked against the parameters of the Oracle's commitment history.
+	history := b.oracle.BlockIntervalCommitmentHistory
+	err = transactionverifier.VerifyLightBlockHeader(round, seed, transactionCommitment, genesisHash,
+		lightBlockHeaderProofResponse, blockIntervalCommitment, history.FirstAttestedRound, history.IntervalSize)
+	if err != nil {
+		return VerifiedRandomness{}, err
+	}
+
+	return VerifiedRandomness{
+		Round:  round,
+		Seed:   seed,
+		Output: deriveOutput(round, seed),
+	}, nil
+}
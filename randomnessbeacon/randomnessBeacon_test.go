@@ -0,0 +1,130 @@
+package randomnessbeacon
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// testBlock holds the light block header fields of the fixture bundle's valid transaction case, along with its proof.
+type testBlock struct {
+	encodedassets.FixtureTransactionCase
+	transactionCommitment types.Digest
+}
+
+// initializeTestBeacon returns a Beacon whose oracle was advanced using the fixture bundle's state proof, along with
+// a block the oracle covers.
+func initializeTestBeacon(t *testing.T) (*Beacon, testBlock) {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound,
+		fixtureBundle.Network.IntervalSize, fixtureBundle.Genesis.VotersCommitment,
+		fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	message, stateProof, err := encodedassets.ParseStateProofResponse(fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = oracleInstance.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		transactionCommitment, err := transactionverifier.ComputeTransactionCommitment(transactionCase.TransactionID,
+			transactionCase.TransactionProofResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return InitializeBeacon(oracleInstance), testBlock{transactionCase, transactionCommitment}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return nil, testBlock{}
+}
+
+func (b testBlock) getVerifiedRandomness(beacon *Beacon) (VerifiedRandomness, error) {
+	return beacon.GetVerifiedRandomness(b.Round, b.Seed, b.transactionCommitment, b.GenesisHash,
+		b.LightBlockHeaderProofResponse)
+}
+
+func TestGetVerifiedRandomness(t *testing.T) {
+	beacon, block := initializeTestBeacon(t)
+
+	verifiedRandomness, err := block.getVerifiedRandomness(beacon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRandomness := VerifiedRandomness{Round: block.Round, Seed: block.Seed,
+		Output: deriveOutput(block.Round, block.Seed)}
+	if verifiedRandomness != expectedRandomness {
+		t.Fatalf("expected %+v, got %+v", expectedRandomness, verifiedRandomness)
+	}
+}
+
+func TestDeriveOutput(t *testing.T) {
+	var seed types.Seed
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	// Sha256("LCRB" || round || seed), computed independently.
+	expectedOutputs := map[types.Round]string{
+		9:  "66628bbf69c7ed79d961b26cc13190d9deee3921376ea4463024cc3ab77bb8a1",
+		10: "38ac993bb901b024100acb9e32c9c7c69d3ea6e7071aab82cae5a930bbd1d6d0",
+	}
+
+	for round, expectedOutput := range expectedOutputs {
+		output := deriveOutput(round, seed)
+		if hex.EncodeToString(output[:]) != expectedOutput {
+			t.Fatalf("round %d: expected %s, got %x", round, expectedOutput, output)
+		}
+	}
+}
+
+func TestGetVerifiedRandomnessErrors(t *testing.T) {
+	beacon, block := initializeTestBeacon(t)
+
+	testCases := map[string]struct {
+		tamper      func(block *testBlock)
+		expectedErr error
+	}{
+		"wrong seed": {func(block *testBlock) { block.Seed[0] ^= 1 }, transactionverifier.ErrRootMismatch},
+		"wrong transaction commitment": {func(block *testBlock) { block.transactionCommitment[0] ^= 1 },
+			transactionverifier.ErrRootMismatch},
+		"round before the first attested round": {func(block *testBlock) { block.Round = 1 },
+			oracle.ErrTooEarlyRoundRequested},
+		"round after the last attested round": {func(block *testBlock) { block.Round += 8 },
+			oracle.ErrNoStateProofForRound},
+		"proof of another round": {func(block *testBlock) { block.Round++ },
+			transactionverifier.ErrLightBlockHeaderIndexMismatch},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tampered := block
+			testCase.tamper(&tampered)
+
+			_, err := tampered.getVerifiedRandomness(beacon)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/relayer"
+)
+
+var (
+	errNoAlgodAddress = errors.New("no algod address given, use -algod-address or a config file")
+)
+
+// stateProofRelayer is implemented by both relayer.Relayer and relayer.MultiSourceRelayer.
+type stateProofRelayer interface {
+	Step(ctx context.Context) error
+	Run(ctx context.Context) error
+}
+
+// sourceResult describes the statistics of a single state proof source.
+type sourceResult struct {
+	Name               string  `json:"name"`
+	Requests           uint64  `json:"requests"`
+	ValidStateProofs   uint64  `json:"valid_state_proofs"`
+	NotAvailable       uint64  `json:"not_available"`
+	Failures           uint64  `json:"failures"`
+	InvalidStateProofs uint64  `json:"invalid_state_proofs"`
+	Disagreements      uint64  `json:"disagreements"`
+	AverageLatencyMS   float64 `json:"average_latency_ms"`
+	Blacklisted        bool    `json:"blacklisted"`
+}
+
+// relayResult describes the state proofs ingested by the relay subcommand, and the oracle's resulting state.
+type relayResult struct {
+	Intervals []roundRange `json:"intervals"`
+	// Sources holds the statistics of every source, if more than one source was given.
+	Sources []sourceResult `json:"sources,omitempty"`
+	// Error describes the reason the relayer stopped, if it stopped for any reason other than being interrupted or
+	// catching up with -once.
+	Error  string        `json:"error,omitempty"`
+	Status *statusResult `json:"status"`
+}
+
+func (r *relayResult) writeText(output io.Writer) {
+	fmt.Fprintf(output, "Ingested %d state proofs\n", len(r.Intervals))
+	for _, source := range r.Sources {
+		fmt.Fprintf(output, "Source %s: %d requests, %d valid, %d not available, %d failed, %d invalid, "+
+			"%d disagreements, %.1fms average latency", source.Name, source.Requests, source.ValidStateProofs,
+			source.NotAvailable, source.Failures, source.InvalidStateProofs, source.Disagreements,
+			source.AverageLatencyMS)
+		if source.Blacklisted {
+			fmt.Fprint(output, ", blacklisted")
+		}
+		fmt.Fprintln(output)
+	}
+	r.Status.writeText(output)
+}
+
+// setupRelayCommand sets up the relay subcommand, which runs a relayer: it polls an algod compatible REST API for
+// state proofs and advances the oracle's state using them, saving the state after every state proof. It runs until
+// interrupted, or with -once, until no further state proof is available. Given several comma separated addresses, it
+// requests every one of them in parallel, see relayer.MultiSourceRelayer.
+func setupRelayCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	defaultConfig := relayer.DefaultConfig()
+	flags.StringVar(&config.AlgodAddress, "algod-address", "",
+		"address of the algod REST API, e.g. http://localhost:4001, or comma separated addresses of several")
+	flags.StringVar(&config.AlgodToken, "algod-token", "", "API token of the algod REST API")
+	pollInterval := flags.Duration("poll-interval", defaultConfig.PollInterval,
+		"time to wait before requesting a state proof that was not available yet")
+	maxRetries := flags.Int("max-retries", defaultConfig.MaxRetries,
+		"consecutive failures to reach algod tolerated before stopping, or 0 to retry indefinitely")
+	once := flags.Bool("once", false, "stop once no further state proof is available, rather than polling for it")
+
+	return func(arguments []string) (commandResult, error) {
+		if config.AlgodAddress == "" {
+			return nil, errNoAlgodAddress
+		}
+
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var sources []relayer.Source
+		for _, address := range strings.Split(config.AlgodAddress, ",") {
+			client, err := algod.MakeClient(strings.TrimSpace(address), config.AlgodToken)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, relayer.Source{Name: strings.TrimSpace(address), Client: client})
+		}
+
+		var intervals []roundRange
+		relayerConfig := defaultConfig
+		relayerConfig.PollInterval = *pollInterval
+		relayerConfig.MaxRetries = *maxRetries
+		// We save the state after every state proof, so that stopping the relayer at any point loses no progress.
+		relayerConfig.OnAdvance = func(message types.Message) error {
+			intervals = append(intervals, roundRange{FirstRound: message.FirstAttestedRound,
+				LastRound: message.LastAttestedRound})
+			if !config.JSONOutput {
+				fmt.Fprintf(os.Stderr, "Ingested the state proof for rounds %d-%d\n", message.FirstAttestedRound,
+					message.LastAttestedRound)
+			}
+
+			return saveOracle(config, oracleInstance)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var selectedRelayer stateProofRelayer
+		var multiSourceRelayer *relayer.MultiSourceRelayer
+		if len(sources) == 1 {
+			selectedRelayer = relayer.InitializeRelayer(sources[0].Client, oracleInstance, relayerConfig)
+		} else {
+			multiSourceRelayer = relayer.InitializeMultiSourceRelayer(sources, oracleInstance, relayerConfig)
+			selectedRelayer = multiSourceRelayer
+		}
+
+		if *once {
+			for err == nil {
+				err = selectedRelayer.Step(ctx)
+			}
+			if errors.Is(err, relayer.ErrStateProofNotAvailable) {
+				err = nil
+			}
+		} else {
+			err = selectedRelayer.Run(ctx)
+		}
+
+		// Being interrupted is the only way to stop a relayer running indefinitely, so it is not a failure.
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+
+		result := &relayResult{Intervals: intervals, Status: getStatus(config, oracleInstance)}
+		if multiSourceRelayer != nil {
+			for _, stats := range multiSourceRelayer.Stats() {
+				result.Sources = append(result.Sources, sourceResult{
+					Name:               stats.Name,
+					Requests:           stats.Requests,
+					ValidStateProofs:   stats.ValidStateProofs,
+					NotAvailable:       stats.NotAvailable,
+					Failures:           stats.Failures,
+					InvalidStateProofs: stats.InvalidStateProofs,
+					Disagreements:      stats.Disagreements,
+					AverageLatencyMS:   float64(stats.AverageLatency()) / float64(time.Millisecond),
+					Blacklisted:        stats.Blacklisted,
+				})
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		return result, err
+	}
+}
@@ -0,0 +1,370 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproof"
+
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+// Source is an algod compatible REST API state proofs can be retrieved from.
+type Source struct {
+	// Name identifies the source in statistics and errors.
+	Name   string
+	Client *algod.Client
+}
+
+// SourceStats holds the statistics of a single source.
+type SourceStats struct {
+	Name string
+	// Requests is the number of state proofs requested from the source.
+	Requests uint64
+	// ValidStateProofs is the number of state proofs served by the source that passed verification, whether the
+	// oracle advanced using them or using an identical state proof from a faster source.
+	ValidStateProofs uint64
+	// NotAvailable is the number of requests answered with a state proof not being available yet.
+	NotAvailable uint64
+	// Failures is the number of requests that failed to complete, e.g. due to timeouts or errors returned by algod.
+	Failures uint64
+	// InvalidStateProofs is the number of state proofs served by the source that did not pass verification.
+	InvalidStateProofs uint64
+	// Disagreements is the number of state proofs served by the source whose message differed from the one the
+	// oracle advanced using, see Disagreement.
+	Disagreements uint64
+	// TotalLatency is the sum of the latencies of every request that completed, whatever its outcome.
+	TotalLatency time.Duration
+	// Completed is the number of requests TotalLatency sums over.
+	Completed uint64
+	// LastError is the error of the source's last unsuccessful request.
+	LastError string
+	// Blacklisted is true if the source served an invalid state proof, and is no longer requested from.
+	Blacklisted bool
+}
+
+// AverageLatency returns the average latency of the source's completed requests.
+func (s SourceStats) AverageLatency() time.Duration {
+	if s.Completed == 0 {
+		return 0
+	}
+
+	return s.TotalLatency / time.Duration(s.Completed)
+}
+
+// Disagreement records a source serving a state proof message different from the one the oracle advanced using. Since
+// the oracle only advances using verified messages, the disagreeing source was either faulty or malicious, and its
+// state proof is counted as invalid as well.
+type Disagreement struct {
+	// Round is the first round of the interval both state proofs attest to.
+	Round uint64
+	// Source is the name of the disagreeing source.
+	Source string
+	// AcceptedSource is the name of the source whose state proof the oracle advanced using.
+	AcceptedSource string
+	// MessageHash and AcceptedMessageHash are the hashes of the disagreeing and accepted state proof messages.
+	MessageHash         types.Digest
+	AcceptedMessageHash types.Digest
+}
+
+// sourceState is a source along with its statistics.
+type sourceState struct {
+	source Source
+	stats  SourceStats
+}
+
+// fetchResult is the outcome of requesting a state proof from a single source.
+type fetchResult struct {
+	source  *sourceState
+	latency time.Duration
+	// parsed is true if the source served a state proof for the expected interval that could be decoded. message and
+	// stateProof are then set, even if the state proof failed verification.
+	parsed     bool
+	message    types.Message
+	stateProof *stateproof.StateProof
+	err        error
+}
+
+// MultiSourceRelayer is a Relayer retrieving state proofs from several sources, so that no single source can delay
+// the oracle. Every source is requested in parallel, and the oracle advances using the first state proof that passes
+// verification. State proofs are self-verifying, so no quorum is needed: a single honest source suffices.
+// Sources serving invalid state proofs are blacklisted, and sources disagreeing with the state proof the oracle
+// advanced using are recorded.
+type MultiSourceRelayer struct {
+	// mu guards the sources' statistics and the disagreements, which may be read while the relayer runs.
+	mu      sync.Mutex
+	sources []*sourceState
+	// disagreements are the disagreements detected, in the order they were detected.
+	disagreements []Disagreement
+	// oracle is the Oracle to advance. It must not be modified by others while the relayer runs.
+	oracle *oracle.Oracle
+	config Config
+}
+
+// InitializeMultiSourceRelayer initializes a MultiSourceRelayer advancing an Oracle using state proofs from several
+// sources.
+// Parameters:
+// sources - the sources to retrieve state proofs from.
+// oracleInstance - the Oracle to advance.
+// config - the relayer's timing parameters, see DefaultConfig. RequestTimeout bounds the time a single Step waits for
+// the slowest source.
+func InitializeMultiSourceRelayer(sources []Source, oracleInstance *oracle.Oracle, config Config) *MultiSourceRelayer {
+	sourceStates := make([]*sourceState, len(sources))
+	for i, source := range sources {
+		sourceStates[i] = &sourceState{source: source, stats: SourceStats{Name: source.Name}}
+	}
+
+	return &MultiSourceRelayer{
+		sources: sourceStates,
+		oracle:  oracleInstance,
+		config:  config,
+	}
+}
+
+// NextRound returns the round whose state proof the relayer requests next: the first round of the oracle's next
+// expected interval.
+func (r *MultiSourceRelayer) NextRound() uint64 {
+	history := r.oracle.BlockIntervalCommitmentHistory
+	return history.FirstAttestedRound + history.NextInterval*history.IntervalSize
+}
+
+// Stats returns the statistics of every source, in the order the sources were given.
+func (r *MultiSourceRelayer) Stats() []SourceStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]SourceStats, len(r.sources))
+	for i, source := range r.sources {
+		stats[i] = source.stats
+	}
+
+	return stats
+}
+
+// Disagreements returns every disagreement detected, in the order they were detected.
+func (r *MultiSourceRelayer) Disagreements() []Disagreement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Disagreement(nil), r.disagreements...)
+}
+
+// Reinstate removes a source from the blacklist, e.g. once its operator fixed it.
+// Parameters:
+// name - the name of the source to reinstate.
+func (r *MultiSourceRelayer) Reinstate(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, source := range r.sources {
+		if source.source.Name == name {
+			source.stats.Blacklisted = false
+		}
+	}
+}
+
+// fetch requests the state proof for a round from a single source, decoding and verifying it against a snapshot of
+// the oracle taken before any of the round's state proofs was ingested.
+// Parameters:
+// ctx - the context of the request.
+// source - the source to request the state proof from.
+// snapshot - the oracle's voters commitment and proven weight before the round's state proof is ingested.
+// round - the first round of the interval whose state proof to request.
+// intervalSize - the number of rounds in an interval.
+func (r *MultiSourceRelayer) fetch(ctx context.Context, source *sourceState, snapshot *oracle.Oracle, round uint64,
+	intervalSize uint64) fetchResult {
+	start := time.Now()
+	stateProofResponse, err := fetchStateProof(ctx, source.source.Client, round, r.config.RequestTimeout)
+	result := fetchResult{source: source, latency: time.Since(start)}
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	result.message, result.stateProof, result.err = parseStateProof(round, intervalSize, stateProofResponse)
+	if result.err != nil {
+		return result
+	}
+	result.parsed = true
+
+	err = snapshot.VerifyStateProof(result.stateProof, result.message)
+	if err != nil {
+		result.err = wrapCause(ErrInvalidStateProof, err)
+	}
+
+	return result
+}
+
+// Step makes a single attempt at advancing the oracle: it requests the state proof for NextRound from every source
+// that is not blacklisted, in parallel, and advances the oracle using the first one that passes verification. It then
+// waits for the remaining sources, at most RequestTimeout, to record their statistics and detect disagreements.
+// It returns nil if the oracle advanced, and otherwise an error wrapping ErrNoSourcesAvailable if every source is
+// blacklisted, ErrStateProofNotAvailable if any source reported the state proof is not available yet, or the error of
+// the last source to fail. ErrOnAdvanceFailed is returned if OnAdvance failed.
+// Parameters:
+// ctx - the context of the requests to the sources.
+func (r *MultiSourceRelayer) Step(ctx context.Context) error {
+	nextRound := r.NextRound()
+
+	r.mu.Lock()
+	var activeSources []*sourceState
+	for _, source := range r.sources {
+		if !source.stats.Blacklisted {
+			activeSources = append(activeSources, source)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(activeSources) == 0 {
+		return fmt.Errorf("round %d: %w", nextRound, ErrNoSourcesAvailable)
+	}
+
+	// Every source's state proof is verified against the oracle's state before advancing, even if it arrives after
+	// the oracle advanced, so that late sources are judged exactly as early ones are. Verification only reads the
+	// voters commitment and proven weight, which advancing replaces rather than modifies.
+	snapshot := &oracle.Oracle{
+		VotersCommitment: r.oracle.VotersCommitment,
+		LnProvenWeight:   r.oracle.LnProvenWeight,
+	}
+	intervalSize := r.oracle.BlockIntervalCommitmentHistory.IntervalSize
+
+	results := make(chan fetchResult, len(activeSources))
+	for _, source := range activeSources {
+		go func(source *sourceState) {
+			results <- r.fetch(ctx, source, snapshot, nextRound, intervalSize)
+		}(source)
+	}
+
+	var accepted *fetchResult
+	var parsedResults []fetchResult
+	var notAvailable bool
+	var lastErr error
+	for range activeSources {
+		result := <-results
+
+		r.mu.Lock()
+		stats := &result.source.stats
+		stats.Requests++
+		if ctx.Err() == nil {
+			stats.Completed++
+			stats.TotalLatency += result.latency
+		}
+
+		switch {
+		case result.err == nil:
+			stats.ValidStateProofs++
+		case errors.Is(result.err, ErrStateProofNotAvailable):
+			stats.NotAvailable++
+			notAvailable = true
+		case errors.Is(result.err, ErrUnexpectedStateProofInterval), errors.Is(result.err, ErrInvalidStateProof):
+			// A source serving an invalid state proof can no longer be trusted to serve valid ones in time.
+			stats.InvalidStateProofs++
+			stats.Blacklisted = true
+		default:
+			stats.Failures++
+		}
+		if result.err != nil {
+			stats.LastError = result.err.Error()
+			lastErr = fmt.Errorf("round %d: source %s: %w", nextRound, result.source.source.Name, result.err)
+		}
+		r.mu.Unlock()
+
+		if result.parsed {
+			parsedResults = append(parsedResults, result)
+		}
+		if result.err != nil || accepted != nil {
+			continue
+		}
+
+		// We advance as soon as a state proof passes verification, rather than once every source responded. The state
+		// proof was verified against the snapshot, which the oracle still matches, so advancing cannot fail
+		// verification.
+		err := r.oracle.AdvanceState(result.stateProof, result.message)
+		if err != nil {
+			// The source is blacklisted like any other source serving an invalid state proof, so that Run does not
+			// request it again.
+			r.mu.Lock()
+			result.source.stats.InvalidStateProofs++
+			result.source.stats.Blacklisted = true
+			result.source.stats.LastError = err.Error()
+			r.mu.Unlock()
+
+			lastErr = fmt.Errorf("round %d: source %s: %w", nextRound, result.source.source.Name,
+				wrapCause(ErrInvalidStateProof, err))
+			continue
+		}
+		acceptedResult := result
+		accepted = &acceptedResult
+	}
+
+	if accepted != nil {
+		r.recordDisagreements(nextRound, *accepted, parsedResults)
+
+		if r.config.OnAdvance != nil {
+			err := r.config.OnAdvance(accepted.message)
+			if err != nil {
+				return fmt.Errorf("round %d: %w", nextRound, wrapCause(ErrOnAdvanceFailed, err))
+			}
+		}
+		return nil
+	}
+
+	if notAvailable {
+		return fmt.Errorf("round %d: %w", nextRound, ErrStateProofNotAvailable)
+	}
+
+	return lastErr
+}
+
+// recordDisagreements records every source that served a state proof message different from the accepted one, whether
+// or not its state proof passed verification.
+// Parameters:
+// round - the first round of the interval the state proofs attest to.
+// accepted - the result whose state proof the oracle advanced using.
+// parsedResults - every result holding a decoded state proof message.
+func (r *MultiSourceRelayer) recordDisagreements(round uint64, accepted fetchResult, parsedResults []fetchResult) {
+	acceptedMessageHash := types.Digest(crypto.HashStateProofMessage(&accepted.message))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, result := range parsedResults {
+		messageHash := types.Digest(crypto.HashStateProofMessage(&result.message))
+		if messageHash == acceptedMessageHash {
+			continue
+		}
+
+		result.source.stats.Disagreements++
+		r.disagreements = append(r.disagreements, Disagreement{
+			Round:               round,
+			Source:              result.source.source.Name,
+			AcceptedSource:      accepted.source.source.Name,
+			MessageHash:         messageHash,
+			AcceptedMessageHash: acceptedMessageHash,
+		})
+	}
+}
+
+// Run advances the oracle repeatedly until the context is done or a non retryable error occurs, exactly as
+// Relayer.Run does. Since invalid state proofs only blacklist their source, the relayer only stops on its own once
+// every source is blacklisted, or if OnAdvance fails.
+// Parameters:
+// ctx - the context whose cancellation stops the relayer. Its error is returned once it is done.
+func (r *MultiSourceRelayer) Run(ctx context.Context) error {
+	return run(ctx, r.config, func(ctx context.Context) error {
+		err := r.Step(ctx)
+		// The sources that served invalid state proofs were blacklisted, so we request the remaining sources right
+		// away, rather than stopping as Relayer.Run would.
+		if errors.Is(err, ErrUnexpectedStateProofInterval) || errors.Is(err, ErrInvalidStateProof) {
+			return nil
+		}
+		return err
+	})
+}
@@ -0,0 +1,160 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/mockalgod"
+)
+
+// startStateProofServer starts a mock algod serving a single state proof response, and returns a source for it.
+func startStateProofServer(t *testing.T, stateProofResponse models.StateProof, name string) Source {
+	t.Helper()
+
+	server := mockalgod.InitializeServer("token")
+	server.AddStateProof(stateProofResponse)
+	return startServer(t, server, name)
+}
+
+// expectAdvanced fails the test unless the relayer's oracle ingested exactly the fixture bundle's state proof.
+func expectAdvanced(t *testing.T, relayer *MultiSourceRelayer, fixtureBundle *encodedassets.FixtureBundle) {
+	t.Helper()
+
+	expectedNextRound := fixtureBundle.Network.FirstAttestedRound + fixtureBundle.Network.IntervalSize
+	if relayer.NextRound() != expectedNextRound {
+		t.Fatalf("expected the next round to be %d, got %d", expectedNextRound, relayer.NextRound())
+	}
+}
+
+func TestMultiSourceStepBlacklistsInvalidSources(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	_, honest := startTestServer(t, fixtureBundle, "honest")
+	tampered := startStateProofServer(t, getTamperedStateProof(fixtureBundle.StateProofs[0]), "tampered")
+
+	relayer := InitializeMultiSourceRelayer([]Source{tampered, honest}, getGenesisOracle(fixtureBundle), testConfig)
+	err := relayer.Step(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAdvanced(t, relayer, fixtureBundle)
+
+	stats := relayer.Stats()
+	if !stats[0].Blacklisted || stats[0].InvalidStateProofs != 1 || stats[0].Disagreements != 1 ||
+		stats[0].LastError == "" {
+		t.Fatalf("expected the tampered source to be blacklisted, got %+v", stats[0])
+	}
+	if stats[1].Blacklisted || stats[1].ValidStateProofs != 1 || stats[1].Requests != 1 || stats[1].Completed != 1 {
+		t.Fatalf("unexpected statistics of the honest source: %+v", stats[1])
+	}
+
+	disagreements := relayer.Disagreements()
+	if len(disagreements) != 1 || disagreements[0].Source != "tampered" || disagreements[0].AcceptedSource != "honest" ||
+		disagreements[0].Round != fixtureBundle.Network.FirstAttestedRound ||
+		disagreements[0].MessageHash == disagreements[0].AcceptedMessageHash {
+		t.Fatalf("unexpected disagreements: %+v", disagreements)
+	}
+
+	// Blacklisted sources are no longer requested.
+	_ = relayer.Step(context.Background())
+	if relayer.Stats()[0].Requests != 1 {
+		t.Fatal("expected the blacklisted source not to be requested again")
+	}
+}
+
+func TestMultiSourceStepToleratesSlowAndFailingSources(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	slowServer, slow := startTestServer(t, fixtureBundle, "slow")
+	slowServer.InjectFault(mockalgod.StateProofEndpoint, mockalgod.Fault{Delay: time.Second})
+	failingServer, failing := startTestServer(t, fixtureBundle, "failing")
+	failingServer.InjectFault(mockalgod.StateProofEndpoint, mockalgod.Fault{StatusCode: http.StatusInternalServerError})
+	_, honest := startTestServer(t, fixtureBundle, "honest")
+
+	config := testConfig
+	config.RequestTimeout = 100 * time.Millisecond
+	relayer := InitializeMultiSourceRelayer([]Source{slow, failing, honest}, getGenesisOracle(fixtureBundle), config)
+	err := relayer.Step(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAdvanced(t, relayer, fixtureBundle)
+
+	for _, stats := range relayer.Stats()[:2] {
+		if stats.Blacklisted || stats.Failures != 1 || stats.LastError == "" {
+			t.Fatalf("expected a single failure without blacklisting, got %+v", stats)
+		}
+	}
+	if len(relayer.Disagreements()) != 0 {
+		t.Fatalf("expected no disagreements, got %+v", relayer.Disagreements())
+	}
+}
+
+func TestMultiSourceStepErrors(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	notAvailableServer, notAvailable := startTestServer(t, fixtureBundle, "not available")
+	notAvailableServer.InjectFault(mockalgod.StateProofEndpoint, mockalgod.Fault{StatusCode: http.StatusNotFound})
+	tampered := startStateProofServer(t, getTamperedStateProof(fixtureBundle.StateProofs[0]), "tampered")
+
+	// A source reporting the state proof is not available yet takes precedence over an invalid one.
+	oracleInstance := getGenesisOracle(fixtureBundle)
+	relayer := InitializeMultiSourceRelayer([]Source{notAvailable, tampered}, oracleInstance, testConfig)
+	err := relayer.Step(context.Background())
+	if !errors.Is(err, ErrStateProofNotAvailable) {
+		t.Fatalf("expected %v, got %v", ErrStateProofNotAvailable, err)
+	}
+	if oracleInstance.BlockIntervalCommitmentHistory.NextInterval != 0 {
+		t.Fatal("expected the oracle not to advance")
+	}
+	// Disagreements are only recorded against an accepted state proof.
+	if len(relayer.Disagreements()) != 0 {
+		t.Fatalf("expected no disagreements, got %+v", relayer.Disagreements())
+	}
+
+	relayer = InitializeMultiSourceRelayer([]Source{tampered}, oracleInstance, testConfig)
+	err = relayer.Run(context.Background())
+	if !errors.Is(err, ErrNoSourcesAvailable) {
+		t.Fatalf("expected %v, got %v", ErrNoSourcesAvailable, err)
+	}
+
+	// A reinstated source is requested again.
+	relayer.Reinstate("tampered")
+	err = relayer.Step(context.Background())
+	if !errors.Is(err, ErrInvalidStateProof) {
+		t.Fatalf("expected %v, got %v", ErrInvalidStateProof, err)
+	}
+	if relayer.Stats()[0].Requests != 2 {
+		t.Fatalf("expected the reinstated source to be requested twice, got %d", relayer.Stats()[0].Requests)
+	}
+}
+
+func TestMultiSourceStepOnAdvanceFailed(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	_, honest := startTestServer(t, fixtureBundle, "honest")
+
+	config := testConfig
+	config.OnAdvance = func(message types.Message) error {
+		return errors.New("disk full")
+	}
+
+	relayer := InitializeMultiSourceRelayer([]Source{honest}, getGenesisOracle(fixtureBundle), config)
+	err := relayer.Step(context.Background())
+	if !errors.Is(err, ErrOnAdvanceFailed) {
+		t.Fatalf("expected %v, got %v", ErrOnAdvanceFailed, err)
+	}
+}
+
+func TestAverageLatency(t *testing.T) {
+	stats := SourceStats{TotalLatency: 30 * time.Millisecond, Completed: 3}
+	if stats.AverageLatency() != 10*time.Millisecond {
+		t.Fatalf("expected an average latency of 10ms, got %v", stats.AverageLatency())
+	}
+	if (SourceStats{}).AverageLatency() != 0 {
+		t.Fatal("expected a source without completed requests to have no average latency")
+	}
+}
@@ -0,0 +1,322 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproof"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+var (
+	ErrStateProofNotAvailable       = errors.New("state proof for the next interval is not available yet")
+	ErrRequestRejected              = errors.New("algod rejected the state proof request")
+	ErrUnexpectedStateProofInterval = errors.New("state proof does not attest to the next expected interval")
+	ErrInvalidStateProof            = errors.New("state proof could not be ingested by the oracle")
+	ErrRetriesExhausted             = errors.New("failed to retrieve a state proof too many consecutive times")
+	ErrOnAdvanceFailed              = errors.New("OnAdvance failed after the oracle advanced")
+	ErrNoSourcesAvailable           = errors.New("every state proof source is blacklisted")
+)
+
+// causedError is an error classified by one of the package's sentinel errors, which keeps the error that caused it
+// available to errors.Is and errors.As.
+type causedError struct {
+	// sentinel is the package's error classifying the error.
+	sentinel error
+	// cause is the error that caused it.
+	cause error
+}
+
+// wrapCause returns an error matching both the given sentinel error and the given cause using errors.Is.
+// Parameters:
+// sentinel - the package's error classifying the error.
+// cause - the error that caused it.
+func wrapCause(sentinel error, cause error) error {
+	return &causedError{sentinel: sentinel, cause: cause}
+}
+
+func (e *causedError) Error() string {
+	return e.sentinel.Error() + ": " + e.cause.Error()
+}
+
+// Is allows callers to check for the sentinel error using errors.Is.
+func (e *causedError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// Unwrap allows callers to check for the cause using errors.Is and errors.As.
+func (e *causedError) Unwrap() error {
+	return e.cause
+}
+
+// Config holds the timing parameters of a Relayer.
+type Config struct {
+	// PollInterval is the time to wait before requesting a state proof that was not available yet. State proofs are
+	// created once every interval, so there is little point in polling more often than an interval's duration.
+	PollInterval time.Duration
+	// InitialBackoff is the time to wait after the first consecutive failure to retrieve a state proof. Each
+	// additional consecutive failure doubles the time to wait, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest time to wait between retries.
+	MaxBackoff time.Duration
+	// MaxRetries is the number of consecutive failures tolerated before giving up, or 0 to retry indefinitely.
+	MaxRetries int
+	// RequestTimeout is the longest time a single request for a state proof may take, or 0 for no limit.
+	RequestTimeout time.Duration
+	// OnAdvance, if set, is called after every state proof the oracle ingests, e.g. to persist the oracle's state.
+	// An error returned by it stops the Relayer.
+	OnAdvance func(message types.Message) error
+}
+
+// DefaultConfig returns a Config suited to Algorand's MainNet, where an interval of 256 rounds takes roughly
+// 15 minutes.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:   time.Minute,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		MaxRetries:     0,
+		RequestTimeout: 30 * time.Second,
+	}
+}
+
+// Relayer is the off chain process the oracle relies on for state proofs. It polls an algod compatible REST API for
+// the state proof attesting to the oracle's next expected interval, and advances the oracle's state using it.
+// The Relayer only ever feeds the oracle state proofs, so a faulty or malicious node can delay the oracle, but can
+// never make it accept an unverified commitment.
+type Relayer struct {
+	// client is the algod client state proofs are retrieved from.
+	client *algod.Client
+	// oracle is the Oracle to advance. It must not be modified by others while the Relayer runs.
+	oracle *oracle.Oracle
+	config Config
+}
+
+// InitializeRelayer initializes a Relayer advancing an Oracle using state proofs from an algod client.
+// Parameters:
+// client - the algod client to retrieve state proofs from. Any algod compatible REST API can be used.
+// oracleInstance - the Oracle to advance.
+// config - the Relayer's timing parameters, see DefaultConfig.
+func InitializeRelayer(client *algod.Client, oracleInstance *oracle.Oracle, config Config) *Relayer {
+	return &Relayer{
+		client: client,
+		oracle: oracleInstance,
+		config: config,
+	}
+}
+
+// NextRound returns the round whose state proof the Relayer requests next: the first round of the oracle's next
+// expected interval.
+func (r *Relayer) NextRound() uint64 {
+	history := r.oracle.BlockIntervalCommitmentHistory
+	return history.FirstAttestedRound + history.NextInterval*history.IntervalSize
+}
+
+// parseStateProof decodes a state proof response, provided it attests to the expected interval.
+// Parameters:
+// nextRound - the first round of the interval the state proof must attest to.
+// intervalSize - the number of rounds in an interval.
+// stateProofResponse - the state proof response.
+func parseStateProof(nextRound uint64, intervalSize uint64, stateProofResponse models.StateProof) (types.Message,
+	*stateproof.StateProof, error) {
+	// The oracle assumes every state proof attests to the interval following the previous one's, so we make sure of
+	// it before advancing.
+	message := stateProofResponse.Message
+	if message.Firstattestedround != nextRound || message.Lastattestedround != nextRound+intervalSize-1 {
+		return types.Message{}, nil, fmt.Errorf("%w: attests to rounds %d-%d rather than %d-%d",
+			ErrUnexpectedStateProofInterval, message.Firstattestedround, message.Lastattestedround, nextRound,
+			nextRound+intervalSize-1)
+	}
+
+	stateProofMessage, stateProof, err := encodedassets.ParseStateProofResponse(stateProofResponse)
+	if err != nil {
+		return types.Message{}, nil, wrapCause(ErrInvalidStateProof, err)
+	}
+
+	return stateProofMessage, stateProof, nil
+}
+
+// IngestStateProof advances an oracle's state using a state proof response, as returned by algod, provided it attests
+// to the oracle's next expected interval. It returns the state proof message the oracle ingested.
+// It returns an error wrapping ErrUnexpectedStateProofInterval if the state proof attests to any other interval, and
+// ErrInvalidStateProof if it cannot be decoded or fails verification.
+// Parameters:
+// oracleInstance - the Oracle to advance.
+// stateProofResponse - the state proof response.
+func IngestStateProof(oracleInstance *oracle.Oracle, stateProofResponse models.StateProof) (types.Message, error) {
+	history := oracleInstance.BlockIntervalCommitmentHistory
+	nextRound := history.FirstAttestedRound + history.NextInterval*history.IntervalSize
+	stateProofMessage, stateProof, err := parseStateProof(nextRound, history.IntervalSize, stateProofResponse)
+	if err != nil {
+		return types.Message{}, err
+	}
+
+	err = oracleInstance.AdvanceState(stateProof, stateProofMessage)
+	if err != nil {
+		return types.Message{}, wrapCause(ErrInvalidStateProof, err)
+	}
+
+	return stateProofMessage, nil
+}
+
+// getStatusCode extracts the HTTP status code from an error returned by the algod client, which only reports it as
+// part of the error's message. It returns 0 for errors that did not originate from an HTTP response.
+func getStatusCode(err error) int {
+	var statusCode int
+	_, scanErr := fmt.Sscanf(err.Error(), "HTTP %d:", &statusCode)
+	if scanErr != nil {
+		return 0
+	}
+
+	return statusCode
+}
+
+// fetchStateProof requests the state proof for a round from an algod client. It returns an error wrapping
+// ErrStateProofNotAvailable if algod has not created the state proof yet, and ErrRequestRejected if algod rejected the
+// request itself.
+// Parameters:
+// ctx - the context of the request.
+// client - the algod client to request the state proof from.
+// round - the round whose state proof to request.
+// timeout - the longest time the request may take, or 0 for no limit.
+func fetchStateProof(ctx context.Context, client *algod.Client, round uint64, timeout time.Duration) (models.StateProof,
+	error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stateProofResponse, err := client.GetStateProof(round).Do(ctx)
+	if err != nil {
+		// algod answers with 404 both for rounds later than its latest round, and for rounds whose state proof was
+		// not created yet. Either way, the state proof will be available later.
+		statusCode := getStatusCode(err)
+		switch {
+		case statusCode == http.StatusNotFound:
+			return models.StateProof{}, ErrStateProofNotAvailable
+		case statusCode >= 400 && statusCode < 500:
+			return models.StateProof{}, wrapCause(ErrRequestRejected, err)
+		default:
+			return models.StateProof{}, err
+		}
+	}
+
+	return stateProofResponse, nil
+}
+
+// Step makes a single attempt at advancing the oracle: it requests the state proof for NextRound, and if one is
+// available, verifies it using the oracle and advances the oracle's state.
+// It returns an error wrapping ErrStateProofNotAvailable if algod has not created the state proof yet,
+// ErrRequestRejected if algod rejected the request itself, ErrUnexpectedStateProofInterval or ErrInvalidStateProof if
+// the state proof returned cannot be ingested, and ErrOnAdvanceFailed if OnAdvance failed. Any other error is a
+// failure to communicate with algod.
+// Parameters:
+// ctx - the context of the request to algod.
+func (r *Relayer) Step(ctx context.Context) error {
+	nextRound := r.NextRound()
+	stateProofResponse, err := fetchStateProof(ctx, r.client, nextRound, r.config.RequestTimeout)
+	if err != nil {
+		return fmt.Errorf("round %d: %w", nextRound, err)
+	}
+
+	stateProofMessage, err := IngestStateProof(r.oracle, stateProofResponse)
+	if err != nil {
+		return fmt.Errorf("round %d: %w", nextRound, err)
+	}
+
+	if r.config.OnAdvance != nil {
+		err = r.config.OnAdvance(stateProofMessage)
+		if err != nil {
+			return fmt.Errorf("round %d: %w", nextRound, wrapCause(ErrOnAdvanceFailed, err))
+		}
+	}
+
+	return nil
+}
+
+// wait blocks for the given duration, or until the context is done.
+// Parameters:
+// ctx - the context whose cancellation stops the wait early.
+// duration - the time to wait.
+func wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Run advances the oracle repeatedly until the context is done or a non retryable error occurs.
+// Once a state proof is ingested, the next one is requested immediately, allowing an oracle that lags behind to catch
+// up. State proofs that are not available yet are requested again every PollInterval, and failures to communicate
+// with algod are retried with exponential backoff. Rejected requests, state proofs that cannot be ingested and errors
+// returned by OnAdvance stop the Relayer, as retrying would not change their outcome.
+// Parameters:
+// ctx - the context whose cancellation stops the Relayer. Its error is returned once it is done.
+func (r *Relayer) Run(ctx context.Context) error {
+	return run(ctx, r.config, r.Step)
+}
+
+// run calls step repeatedly until the context is done or step returns a non retryable error, waiting between calls
+// as described by the config. See Relayer.Run.
+// Parameters:
+// ctx - the context whose cancellation stops the loop. Its error is returned once it is done.
+// config - the timing parameters.
+// step - makes a single attempt at advancing the oracle.
+func run(ctx context.Context, config Config, step func(ctx context.Context) error) error {
+	backoff := config.InitialBackoff
+	failures := 0
+	for {
+		err := step(ctx)
+		// The algod client reports a cancelled context as a request failure, so we check for it first.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var waitDuration time.Duration
+		switch {
+		case err == nil:
+			backoff = config.InitialBackoff
+			failures = 0
+			continue
+		case errors.Is(err, ErrStateProofNotAvailable):
+			backoff = config.InitialBackoff
+			failures = 0
+			waitDuration = config.PollInterval
+		case errors.Is(err, ErrRequestRejected), errors.Is(err, ErrUnexpectedStateProofInterval),
+			errors.Is(err, ErrInvalidStateProof), errors.Is(err, ErrOnAdvanceFailed),
+			errors.Is(err, ErrNoSourcesAvailable):
+			return err
+		default:
+			failures++
+			if config.MaxRetries > 0 && failures > config.MaxRetries {
+				return wrapCause(ErrRetriesExhausted, err)
+			}
+
+			waitDuration = backoff
+			backoff *= 2
+			if backoff > config.MaxBackoff {
+				backoff = config.MaxBackoff
+			}
+		}
+
+		err = wait(ctx, waitDuration)
+		if err != nil {
+			return err
+		}
+	}
+}
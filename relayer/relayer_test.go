@@ -0,0 +1,301 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/mockalgod"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+// testConfig is a Config whose waits are short enough for tests.
+var testConfig = Config{
+	PollInterval:   time.Millisecond,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+	MaxRetries:     3,
+	RequestTimeout: time.Second,
+}
+
+func loadTestFixtureBundle(t *testing.T) *encodedassets.FixtureBundle {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtureBundle
+}
+
+// getGenesisOracle returns an oracle initialized from the fixture bundle's genesis data, which has not ingested any
+// state proof yet.
+func getGenesisOracle(fixtureBundle *encodedassets.FixtureBundle) *oracle.Oracle {
+	return oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+		fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+}
+
+// startTestServer starts a mock algod serving the fixture bundle, and returns it along with a source for it.
+func startTestServer(t *testing.T, fixtureBundle *encodedassets.FixtureBundle, name string) (*mockalgod.Server,
+	Source) {
+	t.Helper()
+
+	server := mockalgod.InitializeServerFromFixtureBundle(fixtureBundle, "token")
+	return server, startServer(t, server, name)
+}
+
+// startServer starts a mock algod, and returns a source for it. The mock algod is closed once the test completes.
+func startServer(t *testing.T, server *mockalgod.Server, name string) Source {
+	t.Helper()
+
+	server.Start()
+	t.Cleanup(func() {
+		err := server.Close()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	client, err := server.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Source{Name: name, Client: client}
+}
+
+// getTamperedStateProof returns a copy of a state proof response whose message no longer matches its state proof.
+func getTamperedStateProof(stateProofResponse models.StateProof) models.StateProof {
+	tampered := stateProofResponse
+	tampered.Message.Blockheaderscommitment = append([]byte{}, stateProofResponse.Message.Blockheaderscommitment...)
+	tampered.Message.Blockheaderscommitment[0] ^= 1
+	return tampered
+}
+
+func TestIngestStateProof(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	oracleInstance := getGenesisOracle(fixtureBundle)
+
+	message, err := IngestStateProof(oracleInstance, fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.FirstAttestedRound != fixtureBundle.Network.FirstAttestedRound {
+		t.Fatalf("expected the ingested message to attest from round %d, got %d",
+			fixtureBundle.Network.FirstAttestedRound, message.FirstAttestedRound)
+	}
+	if oracleInstance.BlockIntervalCommitmentHistory.NextInterval != 1 {
+		t.Fatal("expected the oracle to advance")
+	}
+
+	// The state proof no longer attests to the next expected interval.
+	_, err = IngestStateProof(oracleInstance, fixtureBundle.StateProofs[0])
+	if !errors.Is(err, ErrUnexpectedStateProofInterval) {
+		t.Fatalf("expected %v, got %v", ErrUnexpectedStateProofInterval, err)
+	}
+}
+
+func TestIngestInvalidStateProof(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+
+	undecodable := fixtureBundle.StateProofs[0]
+	undecodable.Stateproof = []byte{0xc1}
+
+	testCases := map[string]models.StateProof{
+		"tampered message":        getTamperedStateProof(fixtureBundle.StateProofs[0]),
+		"undecodable state proof": undecodable,
+	}
+
+	for name, stateProofResponse := range testCases {
+		t.Run(name, func(t *testing.T) {
+			oracleInstance := getGenesisOracle(fixtureBundle)
+			_, err := IngestStateProof(oracleInstance, stateProofResponse)
+			if !errors.Is(err, ErrInvalidStateProof) {
+				t.Fatalf("expected %v, got %v", ErrInvalidStateProof, err)
+			}
+			if oracleInstance.BlockIntervalCommitmentHistory.NextInterval != 0 {
+				t.Fatal("expected the oracle not to advance")
+			}
+		})
+	}
+}
+
+func TestStep(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	_, source := startTestServer(t, fixtureBundle, "mock")
+
+	var advancedMessages []types.Message
+	config := testConfig
+	config.OnAdvance = func(message types.Message) error {
+		advancedMessages = append(advancedMessages, message)
+		return nil
+	}
+
+	oracleInstance := getGenesisOracle(fixtureBundle)
+	relayer := InitializeRelayer(source.Client, oracleInstance, config)
+	if relayer.NextRound() != fixtureBundle.Network.FirstAttestedRound {
+		t.Fatalf("expected the next round to be %d, got %d", fixtureBundle.Network.FirstAttestedRound,
+			relayer.NextRound())
+	}
+
+	err := relayer.Step(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNextRound := fixtureBundle.Network.FirstAttestedRound + fixtureBundle.Network.IntervalSize
+	if len(advancedMessages) != 1 || relayer.NextRound() != expectedNextRound {
+		t.Fatalf("expected the relayer to advance once, got %d advances and next round %d", len(advancedMessages),
+			relayer.NextRound())
+	}
+
+	// The fixture bundle holds no state proof for the next interval.
+	err = relayer.Step(context.Background())
+	if !errors.Is(err, ErrStateProofNotAvailable) {
+		t.Fatalf("expected %v, got %v", ErrStateProofNotAvailable, err)
+	}
+}
+
+func TestStepOnAdvanceFailed(t *testing.T) {
+	fixtureBundle := loadTestFixtureBundle(t)
+	_, source := startTestServer(t, fixtureBundle, "mock")
+
+	errDiskFull := errors.New("disk full")
+	config := testConfig
+	config.OnAdvance = func(message types.Message) error {
+		return errDiskFull
+	}
+
+	err := InitializeRelayer(source.Client, getGenesisOracle(fixtureBundle), config).Run(context.Background())
+	if !errors.Is(err, ErrOnAdvanceFailed) {
+		t.Fatalf("expected %v, got %v", ErrOnAdvanceFailed, err)
+	}
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("expected %v, got %v", errDiskFull, err)
+	}
+}
+
+func TestWrapCause(t *testing.T) {
+	cause := fmt.Errorf("round 9: %w", context.DeadlineExceeded)
+	err := fmt.Errorf("round 9: %w", wrapCause(ErrRetriesExhausted, cause))
+
+	expectedMessage := "round 9: " + ErrRetriesExhausted.Error() + ": round 9: " + context.DeadlineExceeded.Error()
+	if err.Error() != expectedMessage {
+		t.Fatalf("expected %q, got %q", expectedMessage, err.Error())
+	}
+
+	testCases := map[error]bool{
+		ErrRetriesExhausted:       true,
+		context.DeadlineExceeded:  true,
+		ErrRequestRejected:        false,
+		ErrStateProofNotAvailable: false,
+		context.Canceled:          false,
+	}
+
+	for target, expected := range testCases {
+		if errors.Is(err, target) != expected {
+			t.Fatalf("%v: expected %v, got %v", target, expected, !expected)
+		}
+	}
+
+	var causedErr *causedError
+	if !errors.As(err, &causedErr) || errors.Unwrap(causedErr) != cause {
+		t.Fatalf("expected the cause %v to be unwrapped, got %v", cause, errors.Unwrap(causedErr))
+	}
+}
+
+func TestRunKeepsTheCauseOfExhaustedRetries(t *testing.T) {
+	err := run(context.Background(), testConfig, func(ctx context.Context) error {
+		return fmt.Errorf("round 9: %w", context.DeadlineExceeded)
+	})
+
+	if !errors.Is(err, ErrRetriesExhausted) {
+		t.Fatalf("expected %v, got %v", ErrRetriesExhausted, err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestGetStatusCode(t *testing.T) {
+	testCases := map[string]int{
+		"HTTP 404: state proof not found": 404,
+		"HTTP 500: internal error":        500,
+		"connection refused":              0,
+	}
+
+	for message, expectedStatusCode := range testCases {
+		statusCode := getStatusCode(errors.New(message))
+		if statusCode != expectedStatusCode {
+			t.Fatalf("%q: expected %d, got %d", message, expectedStatusCode, statusCode)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	errConnection := errors.New("connection refused")
+
+	testCases := map[string]struct {
+		// outcomes are returned by consecutive steps. Once exhausted, steps return the last outcome.
+		outcomes      []error
+		expectedErr   error
+		expectedSteps int
+	}{
+		"rejected request stops": {[]error{nil, ErrRequestRejected}, ErrRequestRejected, 2},
+		"invalid state proof stops": {[]error{ErrStateProofNotAvailable, ErrInvalidStateProof}, ErrInvalidStateProof,
+			2},
+		"unexpected interval stops": {[]error{ErrUnexpectedStateProofInterval}, ErrUnexpectedStateProofInterval, 1},
+		"no sources stops":          {[]error{ErrNoSourcesAvailable}, ErrNoSourcesAvailable, 1},
+		"failures exhaust retries":  {[]error{errConnection}, ErrRetriesExhausted, testConfig.MaxRetries + 1},
+		"failures reset on progress": {[]error{errConnection, errConnection, errConnection, nil, errConnection},
+			ErrRetriesExhausted, 4 + testConfig.MaxRetries + 1},
+		"failures reset when not available": {[]error{errConnection, errConnection, errConnection,
+			ErrStateProofNotAvailable, errConnection}, ErrRetriesExhausted, 4 + testConfig.MaxRetries + 1},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			steps := 0
+			err := run(context.Background(), testConfig, func(ctx context.Context) error {
+				outcome := testCase.outcomes[len(testCase.outcomes)-1]
+				if steps < len(testCase.outcomes) {
+					outcome = testCase.outcomes[steps]
+				}
+				steps++
+
+				if outcome == nil {
+					return nil
+				}
+				return fmt.Errorf("round %d: %w", steps, outcome)
+			})
+
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+			if steps != testCase.expectedSteps {
+				t.Fatalf("expected %d steps, got %d", testCase.expectedSteps, steps)
+			}
+		})
+	}
+}
+
+func TestRunStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	config := testConfig
+	config.PollInterval = time.Hour
+
+	steps := 0
+	err := run(ctx, config, func(ctx context.Context) error {
+		steps++
+		cancel()
+		return ErrStateProofNotAvailable
+	})
+	if !errors.Is(err, context.Canceled) || steps != 1 {
+		t.Fatalf("expected %v after a single step, got %v after %d steps", context.Canceled, err, steps)
+	}
+}
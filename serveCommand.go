@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/attestation"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/verificationserver"
+)
+
+var (
+	errInvalidAttestationKey   = errors.New("attestation key file must hold a base64 encoded 32 byte ed25519 seed")
+	errAttestationKeyNotInSet  = errors.New("attestation key set does not hold the attestation key")
+	errKeySetWithoutSigningKey = errors.New("attestation key set given without an attestation key")
+)
+
+// serveResult describes the oracle's state once the verification server shut down.
+type serveResult struct {
+	Status *statusResult `json:"status"`
+}
+
+func (r *serveResult) writeText(output io.Writer) {
+	r.Status.writeText(output)
+}
+
+// loadAttester loads the attestation signing key and the published key set given in the config. Without a key set
+// file, the published key set holds the signing key alone. It returns nil if attestations are disabled.
+// Parameters:
+// config - the subcommand's config.
+// oracleInstance - the Oracle attestations are produced using.
+func loadAttester(config *cliConfig, oracleInstance *oracle.Oracle) (*attestation.Attester, *attestation.KeySet,
+	error) {
+	if config.AttestationKeyFile == "" {
+		if config.AttestationKeySetFile != "" {
+			return nil, nil, errKeySetWithoutSigningKey
+		}
+		return nil, nil, nil
+	}
+
+	encodedSeed, err := os.ReadFile(config.AttestationKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attestation key: %w", err)
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encodedSeed)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, nil, errInvalidAttestationKey
+	}
+
+	attester := attestation.InitializeAttester(oracleInstance, ed25519.NewKeyFromSeed(seed))
+	if config.AttestationKeySetFile == "" {
+		keySet := &attestation.KeySet{
+			Version: attestation.KeySetVersion,
+			Keys:    []attestation.PublishedKey{{KeyID: attester.KeyID(), PublicKey: attester.PublicKey()}},
+		}
+		return attester, keySet, nil
+	}
+
+	encodedKeySet, err := os.ReadFile(config.AttestationKeySetFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attestation key set: %w", err)
+	}
+
+	keySet, err := attestation.DecodeKeySet(encodedKeySet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode attestation key set %s: %w", config.AttestationKeySetFile, err)
+	}
+
+	// Attestations signed by a key missing from the published set could never be verified.
+	for _, key := range keySet.Keys {
+		if key.KeyID == attester.KeyID() {
+			return attester, keySet, nil
+		}
+	}
+
+	return nil, nil, errAttestationKeyNotInSet
+}
+
+// setupServeCommand sets up the serve subcommand, which runs the verification server until interrupted. State proofs
+// pushed by a relayer are saved to the state file as they are ingested. With -attestation-key, every transaction
+// verified is attested to as well.
+func setupServeCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	address := flags.String("listen", "127.0.0.1:8080", "TCP address to serve on")
+	flags.StringVar(&config.RelayerToken, "relayer-token", "",
+		"bearer token relayers must present to push state proofs, or empty to disable pushing state proofs")
+	flags.StringVar(&config.AttestationKeyFile, "attestation-key", "",
+		"path of a file holding the base64 encoded ed25519 seed to sign attestations with, or empty to disable them")
+	flags.StringVar(&config.AttestationKeySetFile, "attestation-key-set", "",
+		"path of the published attestation key set, or empty to publish the attestation key alone")
+
+	return func(arguments []string) (commandResult, error) {
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		serverConfig := verificationserver.DefaultConfig()
+		serverConfig.RelayerToken = config.RelayerToken
+		serverConfig.Attester, serverConfig.KeySet, err = loadAttester(config, oracleInstance)
+		if err != nil {
+			return nil, err
+		}
+		serverConfig.OnAdvance = func(message types.Message) error {
+			if !config.JSONOutput {
+				fmt.Fprintf(os.Stderr, "Ingested the state proof for rounds %d-%d\n", message.FirstAttestedRound,
+					message.LastAttestedRound)
+			}
+
+			return saveOracle(config, oracleInstance)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if !config.JSONOutput {
+			fmt.Fprintf(os.Stderr, "Serving on %s\n", *address)
+		}
+
+		server := verificationserver.InitializeServer(oracleInstance, serverConfig)
+		err = server.ListenAndServe(ctx, *address)
+		if err != nil {
+			return nil, err
+		}
+
+		return &serveResult{Status: getStatus(config, oracleInstance)}, nil
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+// roundRange is an inclusive range of rounds.
+type roundRange struct {
+	FirstRound uint64 `json:"first_round"`
+	LastRound  uint64 `json:"last_round"`
+}
+
+// statusResult describes the oracle's state.
+type statusResult struct {
+	StateFile          string `json:"state_file"`
+	FirstAttestedRound uint64 `json:"first_attested_round"`
+	IntervalSize       uint64 `json:"interval_size"`
+	Capacity           uint64 `json:"capacity"`
+	StoredCommitments  uint64 `json:"stored_commitments"`
+	// CoveredRounds are the rounds transactions can currently be verified for. It is nil if no commitment is stored.
+	CoveredRounds *roundRange `json:"covered_rounds"`
+	// NextInterval is the interval the next state proof must attest to.
+	NextInterval       uint64     `json:"next_interval"`
+	NextIntervalRounds roundRange `json:"next_interval_rounds"`
+	VotersCommitment   []byte     `json:"voters_commitment"`
+	LnProvenWeight     uint64     `json:"ln_proven_weight"`
+}
+
+// getStatus describes the given oracle's state.
+// Parameters:
+// config - the subcommand's config, holding the state file's path.
+// oracleInstance - the oracle to describe.
+func getStatus(config *cliConfig, oracleInstance *oracle.Oracle) *statusResult {
+	history := oracleInstance.BlockIntervalCommitmentHistory
+	intervalFirstRound := func(interval uint64) uint64 {
+		return history.FirstAttestedRound + interval*history.IntervalSize
+	}
+
+	status := &statusResult{
+		StateFile:          config.StateFile,
+		FirstAttestedRound: history.FirstAttestedRound,
+		IntervalSize:       history.IntervalSize,
+		Capacity:           history.Capacity,
+		StoredCommitments:  uint64(len(history.Data)),
+		NextInterval:       history.NextInterval,
+		NextIntervalRounds: roundRange{
+			FirstRound: intervalFirstRound(history.NextInterval),
+			LastRound:  intervalFirstRound(history.NextInterval+1) - 1,
+		},
+		VotersCommitment: oracleInstance.VotersCommitment,
+		LnProvenWeight:   oracleInstance.LnProvenWeight,
+	}
+
+	if history.NextInterval > history.EarliestInterval {
+		status.CoveredRounds = &roundRange{
+			FirstRound: intervalFirstRound(history.EarliestInterval),
+			LastRound:  intervalFirstRound(history.NextInterval) - 1,
+		}
+	}
+
+	return status
+}
+
+func (s *statusResult) writeText(output io.Writer) {
+	fmt.Fprintf(output, "State file: %s\n", s.StateFile)
+	fmt.Fprintf(output, "First attested round: %d\n", s.FirstAttestedRound)
+	fmt.Fprintf(output, "Interval size: %d\n", s.IntervalSize)
+	fmt.Fprintf(output, "Stored commitments: %d of %d\n", s.StoredCommitments, s.Capacity)
+	if s.CoveredRounds != nil {
+		fmt.Fprintf(output, "Covered rounds: %d-%d\n", s.CoveredRounds.FirstRound, s.CoveredRounds.LastRound)
+	} else {
+		fmt.Fprintf(output, "Covered rounds: none\n")
+	}
+	fmt.Fprintf(output, "Next expected interval: %d (rounds %d-%d)\n", s.NextInterval, s.NextIntervalRounds.FirstRound,
+		s.NextIntervalRounds.LastRound)
+	fmt.Fprintf(output, "Voters commitment: %s\n", base64.StdEncoding.EncodeToString(s.VotersCommitment))
+	fmt.Fprintf(output, "Ln proven weight: %d\n", s.LnProvenWeight)
+}
+
+// setupStatusCommand sets up the status subcommand, which describes the oracle's state.
+func setupStatusCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	return func(arguments []string) (commandResult, error) {
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return getStatus(config, oracleInstance), nil
+	}
+}
@@ -0,0 +1,131 @@
+package transactionverifier
+
+import (
+	"crypto/sha256"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+var (
+	TransactionIDPrefix    = []byte("TX")
+	SignedTxnInBlockPrefix = []byte("STIB")
+)
+
+// VerifiedBlock holds the transactions of a block that was verified in its entirety. Since the block's complete
+// payset was verified, a transaction that does not appear in it was not confirmed in the block's round.
+type VerifiedBlock struct {
+	// Round is the round of the verified block.
+	Round types.Round
+	// TransactionIDs are the IDs of the block's transactions, in the order in which they appear in the block.
+	TransactionIDs []string
+	// transactionIDSet holds the same IDs as TransactionIDs, for quick lookups.
+	transactionIDSet map[string]bool
+}
+
+// Contains returns whether the transaction with the given ID was confirmed in the verified block. Returning false
+// proves that the transaction is absent from the block's round.
+// Parameters:
+// transactionID - the base32 encoded transaction ID, as returned by the Algorand SDK.
+func (v *VerifiedBlock) Contains(transactionID string) bool {
+	return v.transactionIDSet[transactionID]
+}
+
+// decodeSignedTxnInBlock restores the fields that are omitted from transactions when they are saved in a block.
+// Transactions only omit their genesis ID and hash if they match the block's, so the block's values are restored.
+// Parameters:
+// signedTxnInBlock - the transaction as it's saved in the block.
+// genesisID - the genesis ID of the network.
+// genesisHash - the hash of the genesis block.
+func decodeSignedTxnInBlock(signedTxnInBlock types.SignedTxnInBlock, genesisID string, genesisHash types.Digest) types.Transaction {
+	transaction := signedTxnInBlock.SignedTxn.Txn
+	if signedTxnInBlock.HasGenesisID {
+		transaction.GenesisID = genesisID
+	}
+
+	// Every protocol version supporting state proofs requires the genesis hash, so it's always omitted.
+	transaction.GenesisHash = genesisHash
+	return transaction
+}
+
+// hashWithPrefix computes Sha256(prefix || data), which is how Algorand hashes its objects.
+// Parameters:
+// prefix - the domain separator of the object's type.
+// data - the canonical msgpack encoding of the object.
+func hashWithPrefix(prefix []byte, data []byte) types.Digest {
+	prefixedData := make([]byte, 0, len(prefix)+len(data))
+	prefixedData = append(prefixedData, prefix...)
+	return sha256.Sum256(append(prefixedData, data...))
+}
+
+// GetTransactionHash returns the hash of a transaction that the sha256 transaction commitment commits to, which is
+// Sha256("TX" || msgpack(transaction)). It differs from the transaction's ID, which is the base32 encoding of
+// Sha512_256("TX" || msgpack(transaction)), only in its hash function, so both can be computed from the transaction,
+// but neither can be computed from the other.
+// Parameters:
+// transaction - the transaction, including its genesis ID and hash.
+func GetTransactionHash(transaction types.Transaction) types.Digest {
+	return hashWithPrefix(TransactionIDPrefix, msgpack.Encode(transaction))
+}
+
+// VerifyBlockTransactions receives the complete, ordered payset of a block and verifies it. It does so by recomputing
+// the block's sha256 transaction commitment from scratch, and verifying the commitment using a proof to compute the
+// commitment belonging to the block's light block header and an expected commitment to compare to.
+// If successful, it returns the IDs of all of the block's transactions.
+// Parameters:
+// payset - the block's transactions, in the order in which they appear in the block.
+// genesisID - the genesis ID of the network.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// round - the round of the block.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block associated with the light block header.
+// blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the Oracle.
+// intervalSize - the number of rounds each state proof message attests to, as used by the Oracle.
+func VerifyBlockTransactions(payset []types.SignedTxnInBlock, genesisID string, lightBlockHeaderProofResponse models.LightBlockHeaderProof,
+	round types.Round, genesisHash types.Digest, seed types.Seed, blockIntervalCommitment types.Digest,
+	firstAttestedRound uint64, intervalSize uint64) (*VerifiedBlock, error) {
+	// We make sure the light block header proof belongs to the given round before doing any hashing.
+	err := verifyLightBlockHeaderProofPosition(round, lightBlockHeaderProofResponse, firstAttestedRound, intervalSize)
+	if err != nil {
+		return nil, err
+	}
+
+	blockTransactions := make([]BlockTransaction, len(payset))
+	transactionIDs := make([]string, len(payset))
+	for i, signedTxnInBlock := range payset {
+		transaction := decodeSignedTxnInBlock(signedTxnInBlock, genesisID, genesisHash)
+		// The sha256 transaction commitment commits to Sha256("TX" || msgpack(transaction)), and to
+		// Sha256("STIB" || msgpack(signedTxnInBlock)), the hash of the transaction as it's saved in the block.
+		blockTransactions[i] = BlockTransaction{
+			TransactionHash: GetTransactionHash(transaction),
+			StibHash:        hashWithPrefix(SignedTxnInBlockPrefix, msgpack.Encode(signedTxnInBlock)),
+		}
+		transactionIDs[i] = crypto.TransactionIDString(transaction)
+	}
+
+	transactionProofBuilder, err := BuildTransactionProofs(Sha256HashType, blockTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	// The recomputed transaction commitment is verified exactly as a commitment computed from a single transaction's proof.
+	err = verifyLightBlockHeaderRoot(round, seed, transactionProofBuilder.Root(), genesisHash, lightBlockHeaderProofResponse,
+		blockIntervalCommitment, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionIDSet := make(map[string]bool, len(transactionIDs))
+	for _, transactionID := range transactionIDs {
+		transactionIDSet[transactionID] = true
+	}
+
+	return &VerifiedBlock{
+		Round:            round,
+		TransactionIDs:   transactionIDs,
+		transactionIDSet: transactionIDSet,
+	}, nil
+}
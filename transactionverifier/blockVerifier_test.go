@@ -0,0 +1,171 @@
+package transactionverifier
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+const (
+	testBlockRound         = 9
+	testFirstAttestedRound = 9
+	testIntervalSize       = 8
+	testGenesisID          = "testnet-v1.0"
+)
+
+var testGenesisHash = types.Digest{1, 2, 3}
+
+// getTestPayset returns the payset of a block, as it's saved in the block: the genesis hash is omitted, and the
+// genesis ID is omitted by the transactions that set HasGenesisID.
+func getTestPayset() []types.SignedTxnInBlock {
+	payset := make([]types.SignedTxnInBlock, 3)
+	for i := range payset {
+		payset[i].SignedTxn = types.SignedTxn{
+			Sig: types.Signature{byte(i + 1)},
+			Txn: types.Transaction{
+				Type: types.PaymentTx,
+				Header: types.Header{
+					Sender:     types.Address{byte(i + 1)},
+					Fee:        1000,
+					FirstValid: 1,
+					LastValid:  1001,
+					Note:       []byte{byte(i)},
+				},
+				PaymentTxnFields: types.PaymentTxnFields{Receiver: types.Address{0xff}, Amount: types.MicroAlgos(i + 1)},
+			},
+		}
+		payset[i].HasGenesisHash = true
+		payset[i].HasGenesisID = i%2 == 0
+	}
+	return payset
+}
+
+// computeExpectedTransactionCommitment computes the sha256 transaction commitment of a payset as an Algorand node
+// does: each leaf is Sha256("TL" || Sha256("TX" || msgpack(txn)) || Sha256("STIB" || msgpack(stib))).
+func computeExpectedTransactionCommitment(t *testing.T, payset []types.SignedTxnInBlock, stibPrefix []byte) types.Digest {
+	t.Helper()
+
+	leaves := make([][]byte, len(payset))
+	for i, signedTxnInBlock := range payset {
+		transaction := signedTxnInBlock.SignedTxn.Txn
+		transaction.GenesisHash = testGenesisHash
+		if signedTxnInBlock.HasGenesisID {
+			transaction.GenesisID = testGenesisID
+		}
+
+		transactionHash := sha256.Sum256(append([]byte("TX"), msgpack.Encode(transaction)...))
+		stibHash := sha256.Sum256(append(append([]byte{}, stibPrefix...), msgpack.Encode(signedTxnInBlock)...))
+		leaves[i] = vectorcommitment.HashElement(vectorcommitment.Sha256HashFactory, []byte("TL"),
+			append(transactionHash[:], stibHash[:]...))
+	}
+
+	tree, err := vectorcommitment.Build(vectorcommitment.VectorCommitmentLayout, vectorcommitment.Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commitment types.Digest
+	copy(commitment[:], tree.Root())
+	return commitment
+}
+
+// commitToBlock returns the block interval commitment of an interval whose first block has the given transaction
+// commitment, along with the block's light block header proof.
+func commitToBlock(t *testing.T, transactionCommitment types.Digest) (types.Digest, *LightBlockHeaderProofBuilder) {
+	t.Helper()
+
+	lightBlockHeaders := make([]types.LightBlockHeader, testIntervalSize)
+	for i := range lightBlockHeaders {
+		lightBlockHeaders[i] = types.LightBlockHeader{
+			RoundNumber: types.Round(testFirstAttestedRound + i),
+			GenesisHash: testGenesisHash,
+			Seed:        types.Seed{byte(i)},
+		}
+	}
+	lightBlockHeaders[testBlockRound-testFirstAttestedRound].Sha256TxnCommitment = transactionCommitment
+
+	builder, err := BuildLightBlockHeaderProofs(lightBlockHeaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return builder.Root(), builder
+}
+
+func verifyTestBlock(t *testing.T, payset []types.SignedTxnInBlock, transactionCommitment types.Digest) (*VerifiedBlock, error) {
+	t.Helper()
+
+	blockIntervalCommitment, builder := commitToBlock(t, transactionCommitment)
+	lightBlockHeaderProof, err := builder.GetLightBlockHeaderProof(testBlockRound - testFirstAttestedRound)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return VerifyBlockTransactions(payset, testGenesisID, lightBlockHeaderProof, testBlockRound, testGenesisHash,
+		types.Seed{testBlockRound - testFirstAttestedRound}, blockIntervalCommitment, testFirstAttestedRound,
+		testIntervalSize)
+}
+
+func TestVerifyBlockTransactions(t *testing.T) {
+	payset := getTestPayset()
+	verifiedBlock, err := verifyTestBlock(t, payset, computeExpectedTransactionCommitment(t, payset, []byte("STIB")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(verifiedBlock.TransactionIDs) != len(payset) {
+		t.Fatalf("expected %d transaction IDs, got %d", len(payset), len(verifiedBlock.TransactionIDs))
+	}
+	for i, signedTxnInBlock := range payset {
+		transaction := decodeSignedTxnInBlock(signedTxnInBlock, testGenesisID, testGenesisHash)
+		transactionID := crypto.TransactionIDString(transaction)
+		if verifiedBlock.TransactionIDs[i] != transactionID || !verifiedBlock.Contains(transactionID) {
+			t.Fatalf("transaction %d with ID %s is missing from the verified block", i, transactionID)
+		}
+	}
+	if verifiedBlock.Contains("NOTATRANSACTIONID") {
+		t.Fatal("verified block contains a transaction that is not in the payset")
+	}
+}
+
+func TestVerifyBlockTransactionsRequiresStibPrefix(t *testing.T) {
+	// A commitment over stib hashes that are missing the "STIB" domain separator is not the block's commitment.
+	payset := getTestPayset()
+	_, err := verifyTestBlock(t, payset, computeExpectedTransactionCommitment(t, payset, nil))
+	if !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", ErrRootMismatch, err)
+	}
+}
+
+func TestVerifyBlockTransactionsRejectsAlteredPayset(t *testing.T) {
+	payset := getTestPayset()
+	transactionCommitment := computeExpectedTransactionCommitment(t, payset, []byte("STIB"))
+
+	testCases := map[string]func(payset []types.SignedTxnInBlock) []types.SignedTxnInBlock{
+		"missing transaction": func(payset []types.SignedTxnInBlock) []types.SignedTxnInBlock {
+			return payset[:len(payset)-1]
+		},
+		"reordered transactions": func(payset []types.SignedTxnInBlock) []types.SignedTxnInBlock {
+			payset[0], payset[1] = payset[1], payset[0]
+			return payset
+		},
+		"altered apply data": func(payset []types.SignedTxnInBlock) []types.SignedTxnInBlock {
+			payset[2].ApplyData.SenderRewards = 1
+			return payset
+		},
+	}
+
+	for name, alter := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifyTestBlock(t, alter(getTestPayset()), transactionCommitment)
+			if !errors.Is(err, ErrRootMismatch) {
+				t.Fatalf("expected %v, got %v", ErrRootMismatch, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package transactionverifier
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"sync"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+const (
+	// Sha256HashType is the hash type of proofs committing to a block's Sha256TxnCommitment.
+	Sha256HashType = "sha256"
+	// Sha512_256HashType is the hash type of proofs committing to a block's TxnCommitment.
+	Sha512_256HashType = "sha512_256"
+)
+
+// HashFunction abstracts the hash function used to compute vector commitment leaves and nodes. Algorand uses the same
+// domain separators regardless of the hash function, so swapping the HashFunction is all that is required in order to
+// verify proofs created using a different hash function.
+type HashFunction interface {
+	// New returns a new hash.Hash computing the hash function. Its output must be exactly as long as a types.Digest.
+	New() hash.Hash
+}
+
+type sha256HashFunction struct{}
+
+func (sha256HashFunction) New() hash.Hash {
+	return sha256.New()
+}
+
+type sha512_256HashFunction struct{}
+
+func (sha512_256HashFunction) New() hash.Hash {
+	return sha512.New512_256()
+}
+
+var (
+	Sha256HashFunction     HashFunction = sha256HashFunction{}
+	Sha512_256HashFunction HashFunction = sha512_256HashFunction{}
+)
+
+// getHashFunction maps a hash type, as returned by an Algorand node in a TransactionProofResponse, to its HashFunction.
+// Parameters:
+// hashType - the hash type to map.
+func getHashFunction(hashType string) (HashFunction, error) {
+	switch hashType {
+	case Sha256HashType:
+		return Sha256HashFunction, nil
+	case Sha512_256HashType:
+		return Sha512_256HashFunction, nil
+	default:
+		return nil, ErrUnsupportedHashFunction
+	}
+}
+
+// getTransactionCommitmentLayout maps a hash type, as returned by an Algorand node in a TransactionProofResponse, to
+// the layout of the tree its proof belongs to. An Algorand node builds a block's Sha256TxnCommitment as a vector
+// commitment, but builds its TxnCommitment as a merkle array, whose leaves are neither reordered nor padded.
+// Parameters:
+// hashType - the hash type to map.
+func getTransactionCommitmentLayout(hashType string) (vectorcommitment.Layout, error) {
+	switch hashType {
+	case Sha256HashType:
+		return vectorcommitment.VectorCommitmentLayout, nil
+	case Sha512_256HashType:
+		return vectorcommitment.MerkleArrayLayout, nil
+	default:
+		return 0, ErrUnsupportedHashFunction
+	}
+}
+
+// hashWithFunction hashes the given data using the given hash function.
+// Parameters:
+// hashFunction - the hash function to use.
+// data - the data to hash.
+func hashWithFunction(hashFunction HashFunction, data []byte) types.Digest {
+	hasher := hashFunction.New()
+	hasher.Write(data)
+
+	var digest types.Digest
+	copy(digest[:], hasher.Sum(nil))
+	return digest
+}
+
+var (
+	sha256NodeHasherPool = sync.Pool{New: func() interface{} {
+		return vectorcommitment.NewNodeHasher(Sha256HashFunction.New)
+	}}
+	sha512_256NodeHasherPool = sync.Pool{New: func() interface{} {
+		return vectorcommitment.NewNodeHasher(Sha512_256HashFunction.New)
+	}}
+)
+
+// getNodeHasherPool returns the pool of NodeHashers for the given hash function, or nil if it has no pool. Pooling
+// the NodeHashers of the hash functions defined in this package allows computeVectorCommitmentRoot to avoid
+// allocations altogether.
+// Parameters:
+// hashFunction - the hash function used to compute nodes.
+func getNodeHasherPool(hashFunction HashFunction) *sync.Pool {
+	switch hashFunction.(type) {
+	case sha256HashFunction:
+		return &sha256NodeHasherPool
+	case sha512_256HashFunction:
+		return &sha512_256NodeHasherPool
+	default:
+		return nil
+	}
+}
+
+// acquireNodeHasher returns a NodeHasher for the given hash function, taken from its pool if it has one.
+// Parameters:
+// hashFunction - the hash function used to compute nodes.
+func acquireNodeHasher(hashFunction HashFunction) *vectorcommitment.NodeHasher {
+	pool := getNodeHasherPool(hashFunction)
+	if pool == nil {
+		return vectorcommitment.NewNodeHasher(hashFunction.New)
+	}
+	return pool.Get().(*vectorcommitment.NodeHasher)
+}
+
+// releaseNodeHasher returns a NodeHasher acquired using acquireNodeHasher to its pool, if it has one.
+// Parameters:
+// hashFunction - the hash function the NodeHasher was acquired for.
+// hasher - the NodeHasher to release.
+func releaseNodeHasher(hashFunction HashFunction, hasher *vectorcommitment.NodeHasher) {
+	pool := getNodeHasherPool(hashFunction)
+	if pool != nil {
+		pool.Put(hasher)
+	}
+}
@@ -0,0 +1,137 @@
+package transactionverifier
+
+import (
+	"errors"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+var (
+	ErrTransactionsStibHashesMismatch = errors.New("number of transactions and number of stib hashes do not match")
+)
+
+// TransactionMultiProof proves several transactions of the same block against the block's transaction commitment.
+// Siblings shared by the transactions' proofs appear in it only once. See vectorcommitment.MultiProof for more details.
+type TransactionMultiProof struct {
+	// Hashtype is the type of hash function used to create the proof, either "sha256" or "sha512_256".
+	Hashtype string `json:"hashtype"`
+	// Idxs are the indices of the transactions in the block's payset.
+	Idxs []uint64 `json:"idxs"`
+	// Proof is the concatenation of the siblings required to compute the transaction commitment.
+	Proof []byte `json:"proof"`
+	// Stibhashes are the hashes of the SignedTxnInBlock of each transaction, ordered as Idxs.
+	Stibhashes [][]byte `json:"stibhashes"`
+	// Treedepth is the depth of the tree that is being proven, i.e. the number of edges from a leaf to the root.
+	Treedepth uint64 `json:"treedepth"`
+}
+
+// GetTransactionMultiProof returns a multi-proof for the transactions in the given indices.
+// Parameters:
+// indices - the indices of the transactions in the block.
+func (b *TransactionProofBuilder) GetTransactionMultiProof(indices []uint64) (TransactionMultiProof, error) {
+	proof, err := b.tree.ProveMultiple(indices)
+	if err != nil {
+		return TransactionMultiProof{}, err
+	}
+
+	stibHashes := make([][]byte, len(indices))
+	for i, index := range indices {
+		stibHash := b.transactions[index].StibHash
+		stibHashes[i] = append([]byte{}, stibHash[:]...)
+	}
+
+	return TransactionMultiProof{
+		Hashtype:   b.hashType,
+		Idxs:       proof.Indices,
+		Proof:      proof.Path,
+		Stibhashes: stibHashes,
+		Treedepth:  proof.TreeDepth,
+	}, nil
+}
+
+// computeTransactionMultiProofRoot computes the root of the transaction commitment attesting to the given
+// transactions, using the hash function and layout matching the hash type specified in the given multi-proof.
+// Parameters:
+// transactionHashes - the hashes of the canonical msgpack encoded transactions, ordered as the multi-proof's indices.
+// transactionMultiProof - the multi-proof for the transactions.
+func computeTransactionMultiProofRoot(transactionHashes []types.Digest, transactionMultiProof TransactionMultiProof) (types.Digest, error) {
+	hashFunction, err := getHashFunction(transactionMultiProof.Hashtype)
+	if err != nil {
+		return types.Digest{}, err
+	}
+
+	layout, err := getTransactionCommitmentLayout(transactionMultiProof.Hashtype)
+	if err != nil {
+		return types.Digest{}, err
+	}
+
+	if len(transactionHashes) != len(transactionMultiProof.Stibhashes) {
+		return types.Digest{}, ErrTransactionsStibHashesMismatch
+	}
+
+	// We compute the leaf of each transaction, exactly as we would when verifying it on its own.
+	leaves := make([][]byte, len(transactionHashes))
+	for i, transactionHash := range transactionHashes {
+		var stibHashDigest types.Digest
+		copy(stibHashDigest[:], transactionMultiProof.Stibhashes[i])
+
+		leaf := computeTransactionLeaf(hashFunction, transactionHash, stibHashDigest, nil)
+		leaves[i] = leaf[:]
+	}
+
+	root, err := vectorcommitment.ComputeRootFromMultiProof(layout, hashFunction.New, leaves,
+		&vectorcommitment.MultiProof{
+			Indices:   transactionMultiProof.Idxs,
+			TreeDepth: transactionMultiProof.Treedepth,
+			Path:      transactionMultiProof.Proof,
+		})
+	if err != nil {
+		return types.Digest{}, err
+	}
+
+	var rootDigest types.Digest
+	copy(rootDigest[:], root)
+	return rootDigest, nil
+}
+
+// VerifyTransactions is the multi-proof counterpart of VerifyTransaction. It receives several sha256 hashed
+// transactions confirmed in the same round, a multi-proof to compute their transaction commitment, a proof to compute
+// the commitment belonging to the light block header associated with the transaction commitment, and an expected
+// commitment to compare to. The function verifies that the computed commitment using the given proofs is identical to
+// the provided commitment.
+// Parameters:
+// transactionHashes - the results of invoking Sha256 on the canonical msgpack encoded transactions, ordered as the
+// multi-proof's indices.
+// transactionMultiProof - the multi-proof for the transactions. See TransactionProofBuilder.GetTransactionMultiProof.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// confirmedRound - the round in which the given transactions were confirmed.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block associated with the light block header.
+// blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the Oracle.
+// intervalSize - the number of rounds each state proof message attests to, as used by the Oracle.
+func VerifyTransactions(transactionHashes []types.Digest, transactionMultiProof TransactionMultiProof,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest, seed types.Seed,
+	blockIntervalCommitment types.Digest, firstAttestedRound uint64, intervalSize uint64) error {
+	// As with VerifyTransaction, only sha256 transaction commitments are committed to by light block headers.
+	if transactionMultiProof.Hashtype != Sha256HashType {
+		return ErrUnsupportedHashFunction
+	}
+
+	// We make sure the light block header proof belongs to the confirmed round before doing any hashing.
+	err := verifyLightBlockHeaderProofPosition(confirmedRound, lightBlockHeaderProofResponse, firstAttestedRound, intervalSize)
+	if err != nil {
+		return err
+	}
+
+	transactionProofRoot, err := computeTransactionMultiProofRoot(transactionHashes, transactionMultiProof)
+	if err != nil {
+		return err
+	}
+
+	return verifyLightBlockHeaderRoot(confirmedRound, seed, transactionProofRoot, genesisHash, lightBlockHeaderProofResponse,
+		blockIntervalCommitment, nil)
+}
@@ -0,0 +1,156 @@
+package transactionverifier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+func TestTransactionMultiProofRoundTrip(t *testing.T) {
+	for _, hashType := range []string{Sha256HashType, Sha512_256HashType} {
+		for _, numberOfTransactions := range []int{1, 2, 3, 5, 8, 9} {
+			transactions := getTestTransactions(numberOfTransactions)
+			builder, err := BuildTransactionProofs(hashType, transactions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			last := uint64(numberOfTransactions - 1)
+			indexSets := [][]uint64{{0}, {last}}
+			if numberOfTransactions > 1 {
+				indexSets = append(indexSets, []uint64{last, 0})
+			}
+			if numberOfTransactions > 2 {
+				indexSets = append(indexSets, []uint64{1, last})
+			}
+
+			for _, indices := range indexSets {
+				transactionHashes := make([]types.Digest, len(indices))
+				singleProofsLength := 0
+				for i, index := range indices {
+					transactionHashes[i] = transactions[index].TransactionHash
+
+					transactionProof, err := builder.GetTransactionProof(index)
+					if err != nil {
+						t.Fatal(err)
+					}
+					singleProofsLength += len(transactionProof.Proof)
+				}
+
+				transactionMultiProof, err := builder.GetTransactionMultiProof(indices)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				root, err := computeTransactionMultiProofRoot(transactionHashes, transactionMultiProof)
+				if err != nil {
+					t.Fatalf("%s, %d transactions, indices %v: %v", hashType, numberOfTransactions, indices, err)
+				}
+				if root != builder.Root() {
+					t.Fatalf("%s, %d transactions, indices %v: expected %x, got %x", hashType, numberOfTransactions,
+						indices, builder.Root(), root)
+				}
+
+				if len(indices) > 1 && len(transactionMultiProof.Proof) >= singleProofsLength {
+					t.Fatalf("%s, %d transactions, indices %v: expected a proof shorter than %d bytes, got %d bytes",
+						hashType, numberOfTransactions, indices, singleProofsLength, len(transactionMultiProof.Proof))
+				}
+			}
+		}
+	}
+}
+
+func TestComputeTransactionMultiProofRootErrors(t *testing.T) {
+	transactions := getTestTransactions(5)
+	builder, err := BuildTransactionProofs(Sha256HashType, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactionMultiProof, err := builder.GetTransactionMultiProof([]uint64{1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactionHashes := []types.Digest{transactions[1].TransactionHash, transactions[3].TransactionHash}
+
+	tamperedTransactionHashes := append([]types.Digest{}, transactionHashes...)
+	tamperedTransactionHashes[1][0] ^= 1
+	tamperedProof := transactionMultiProof
+	tamperedProof.Proof = append([]byte{}, transactionMultiProof.Proof...)
+	tamperedProof.Proof[0] ^= 1
+	trailingBytes := transactionMultiProof
+	trailingBytes.Proof = append(append([]byte{}, transactionMultiProof.Proof...), 0)
+	missingBytes := transactionMultiProof
+	missingBytes.Proof = transactionMultiProof.Proof[:len(transactionMultiProof.Proof)-1]
+	missingStibHash := transactionMultiProof
+	missingStibHash.Stibhashes = transactionMultiProof.Stibhashes[:1]
+	unknownHashType := transactionMultiProof
+	unknownHashType.Hashtype = "md5"
+
+	testCases := map[string]struct {
+		transactionHashes     []types.Digest
+		transactionMultiProof TransactionMultiProof
+		expectedErr           error
+	}{
+		"tampered transaction":  {tamperedTransactionHashes, transactionMultiProof, ErrRootMismatch},
+		"tampered proof":        {transactionHashes, tamperedProof, ErrRootMismatch},
+		"trailing proof bytes":  {transactionHashes, trailingBytes, ErrProofLengthTreeDepthMismatch},
+		"missing proof bytes":   {transactionHashes, missingBytes, ErrProofLengthTreeDepthMismatch},
+		"missing stib hash":     {transactionHashes, missingStibHash, ErrTransactionsStibHashesMismatch},
+		"missing transaction":   {transactionHashes[:1], missingStibHash, vectorcommitment.ErrLeavesIndicesMismatch},
+		"unsupported hash type": {transactionHashes, unknownHashType, ErrUnsupportedHashFunction},
+	}
+
+	for name, testCase := range testCases {
+		root, err := computeTransactionMultiProofRoot(testCase.transactionHashes, testCase.transactionMultiProof)
+		if err == nil && root != builder.Root() {
+			err = ErrRootMismatch
+		}
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestVerifyTransactionsRecordedTransaction(t *testing.T) {
+	transaction := loadRecordedTransaction(t)
+	transactionProof := transaction.TransactionProofResponse
+
+	// A proof of a single transaction is also a multi-proof of it.
+	transactionMultiProof := TransactionMultiProof{
+		Hashtype:   transactionProof.Hashtype,
+		Idxs:       []uint64{transactionProof.Idx},
+		Proof:      transactionProof.Proof,
+		Stibhashes: [][]byte{transactionProof.Stibhash},
+		Treedepth:  transactionProof.Treedepth,
+	}
+	verify := func(transactionMultiProof TransactionMultiProof) error {
+		return VerifyTransactions([]types.Digest{transaction.TransactionID}, transactionMultiProof,
+			transaction.LightBlockHeaderProofResponse, transaction.Round, transaction.GenesisHash, transaction.Seed,
+			transaction.blockIntervalCommitment, transaction.firstAttestedRound, transaction.intervalSize)
+	}
+
+	err := verify(transactionMultiProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := transactionMultiProof
+	tampered.Proof = append([]byte{}, transactionMultiProof.Proof...)
+	tampered.Proof[0] ^= 1
+	err = verify(tampered)
+	if !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", ErrRootMismatch, err)
+	}
+
+	// Light block headers do not commit to sha512_256 transaction commitments.
+	sha512_256Proof := transactionMultiProof
+	sha512_256Proof.Hashtype = Sha512_256HashType
+	err = verify(sha512_256Proof)
+	if !errors.Is(err, ErrUnsupportedHashFunction) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedHashFunction, err)
+	}
+}
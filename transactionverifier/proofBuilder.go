@@ -0,0 +1,147 @@
+package transactionverifier
+
+import (
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+// BlockTransaction holds the hashes committed to by a transaction's leaf in its block's transaction commitment.
+type BlockTransaction struct {
+	// TransactionHash is the hash of the canonical msgpack encoded transaction.
+	TransactionHash types.Digest
+	// StibHash is the hash of the canonical msgpack encoded transaction as it's saved in the block.
+	StibHash types.Digest
+}
+
+// TransactionProofBuilder holds a transaction commitment built from a block's ordered transactions, and generates
+// proofs of membership for each of them. It's intended for relayers holding full blocks, and for creating test
+// fixtures.
+type TransactionProofBuilder struct {
+	// hashType is the hash type of the proofs generated, as it would be returned by an Algorand node.
+	hashType string
+	// transactions are the block's transactions, in order.
+	transactions []BlockTransaction
+	// tree is the tree over the transactions' leaves, whose layout matches hashType.
+	tree *vectorcommitment.Tree
+}
+
+// BuildTransactionProofs computes the transaction commitment over the given transactions.
+// Parameters:
+// hashType - the hash type of the commitment, either "sha256" or "sha512_256". The transactions' hashes must be computed
+// using the same hash function.
+// transactions - the block's transactions, in the order in which they appear in the block.
+func BuildTransactionProofs(hashType string, transactions []BlockTransaction) (*TransactionProofBuilder, error) {
+	hashFunction, err := getHashFunction(hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each leaf is computed exactly as it would be when verifying the transaction. See computeTransactionLeaf for more details.
+	leaves := make([][]byte, len(transactions))
+	for i, transaction := range transactions {
+		leaf := computeTransactionLeaf(hashFunction, transaction.TransactionHash, transaction.StibHash, nil)
+		leaves[i] = leaf[:]
+	}
+
+	// The Sha256TxnCommitment is a vector commitment, while the TxnCommitment is a merkle array. See
+	// getTransactionCommitmentLayout for more details.
+	layout, err := getTransactionCommitmentLayout(hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := vectorcommitment.Build(layout, hashFunction.New, leaves)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionProofBuilder{
+		hashType:     hashType,
+		transactions: transactions,
+		tree:         tree,
+	}, nil
+}
+
+// Root returns the transaction commitment. The commitment of a block with no transactions is the zero digest.
+func (b *TransactionProofBuilder) Root() types.Digest {
+	var root types.Digest
+	copy(root[:], b.tree.Root())
+	return root
+}
+
+// GetTransactionProof returns the proof for the transaction in the given index, in the format an Algorand node
+// returns when queried using GetTransactionProof.
+// Parameters:
+// index - the index of the transaction in the block.
+func (b *TransactionProofBuilder) GetTransactionProof(index uint64) (models.TransactionProofResponse, error) {
+	proof, err := b.tree.Prove(index)
+	if err != nil {
+		return models.TransactionProofResponse{}, err
+	}
+
+	stibHash := b.transactions[index].StibHash
+	return models.TransactionProofResponse{
+		Hashtype:  b.hashType,
+		Idx:       proof.Index,
+		Proof:     proof.Path,
+		Stibhash:  append([]byte{}, stibHash[:]...),
+		Treedepth: proof.TreeDepth,
+	}, nil
+}
+
+// LightBlockHeaderProofBuilder holds a block interval commitment built from an interval's light block headers, and
+// generates proofs of membership for each of them.
+type LightBlockHeaderProofBuilder struct {
+	// tree is the vector commitment over the light block headers' leaves.
+	tree *vectorcommitment.Tree
+}
+
+// BuildLightBlockHeaderProofs computes the block interval commitment over the given light block headers.
+// Parameters:
+// lightBlockHeaders - the light block headers of every round in the interval, ordered by round.
+func BuildLightBlockHeaderProofs(lightBlockHeaders []types.LightBlockHeader) (*LightBlockHeaderProofBuilder, error) {
+	// Each leaf is computed exactly as it would be when verifying the light block header. See
+	// computeLightBlockHeaderLeaf for more details.
+	leaves := make([][]byte, len(lightBlockHeaders))
+	for i, lightBlockHeader := range lightBlockHeaders {
+		leaf := computeLightBlockHeaderLeaf(lightBlockHeader.RoundNumber, lightBlockHeader.Sha256TxnCommitment,
+			lightBlockHeader.GenesisHash, lightBlockHeader.Seed, nil)
+		leaves[i] = leaf[:]
+	}
+
+	tree, err := vectorcommitment.Build(vectorcommitment.VectorCommitmentLayout, Sha256HashFunction.New, leaves)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LightBlockHeaderProofBuilder{
+		tree: tree,
+	}, nil
+}
+
+// Root returns the block interval commitment, as it would appear in the BlockHeadersCommitment of the interval's
+// state proof message.
+func (b *LightBlockHeaderProofBuilder) Root() types.Digest {
+	var root types.Digest
+	copy(root[:], b.tree.Root())
+	return root
+}
+
+// GetLightBlockHeaderProof returns the proof for the light block header in the given index, in the format an Algorand
+// node returns when queried using GetLightBlockHeaderProof.
+// Parameters:
+// index - the index of the light block header in the interval.
+func (b *LightBlockHeaderProofBuilder) GetLightBlockHeaderProof(index uint64) (models.LightBlockHeaderProof, error) {
+	proof, err := b.tree.Prove(index)
+	if err != nil {
+		return models.LightBlockHeaderProof{}, err
+	}
+
+	return models.LightBlockHeaderProof{
+		Index:     proof.Index,
+		Proof:     proof.Path,
+		Treedepth: proof.TreeDepth,
+	}, nil
+}
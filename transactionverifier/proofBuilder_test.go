@@ -0,0 +1,64 @@
+package transactionverifier
+
+import (
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// getTestTransactions returns the given number of transactions, each with distinct hashes.
+func getTestTransactions(numberOfTransactions int) []BlockTransaction {
+	transactions := make([]BlockTransaction, numberOfTransactions)
+	for i := range transactions {
+		transactions[i] = BlockTransaction{TransactionHash: types.Digest{byte(i)}, StibHash: types.Digest{0, byte(i)}}
+	}
+	return transactions
+}
+
+func TestBuildTransactionProofs(t *testing.T) {
+	for _, hashType := range []string{Sha256HashType, Sha512_256HashType} {
+		for numberOfTransactions := 1; numberOfTransactions <= 9; numberOfTransactions++ {
+			transactions := getTestTransactions(numberOfTransactions)
+			builder, err := BuildTransactionProofs(hashType, transactions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, transaction := range transactions {
+				transactionProof, err := builder.GetTransactionProof(uint64(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = VerifyTransactionWithTransactionCommitment(transaction.TransactionHash, transactionProof,
+					builder.Root())
+				if err != nil {
+					t.Fatalf("%s, %d transactions, index %d: expected no error, got %v", hashType, numberOfTransactions,
+						i, err)
+				}
+			}
+		}
+	}
+}
+
+func TestBuildTransactionProofsMerkleArray(t *testing.T) {
+	transactions := getTestTransactions(3)
+	leaves := make([][]byte, len(transactions))
+	for i, transaction := range transactions {
+		leaf := computeTransactionLeaf(Sha512_256HashFunction, transaction.TransactionHash, transaction.StibHash, nil)
+		leaves[i] = leaf[:]
+	}
+
+	builder, err := BuildTransactionProofs(Sha512_256HashType, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The TxnCommitment keeps its leaves in order, and hashes the node missing a right child with zero bytes.
+	var expectedRoot types.Digest
+	copy(expectedRoot[:], hashMerkleArrayNode(hashMerkleArrayNode(leaves[0], leaves[1]),
+		hashMerkleArrayNode(leaves[2], make([]byte, len(types.Digest{})))))
+	if builder.Root() != expectedRoot {
+		t.Fatalf("expected %x, got %x", expectedRoot, builder.Root())
+	}
+}
@@ -0,0 +1,95 @@
+package transactionverifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TraceStepKind identifies the kind of computation a TraceStep records.
+type TraceStepKind string
+
+const (
+	// TransactionLeafStep records the computation of a transaction's leaf.
+	TransactionLeafStep TraceStepKind = "transaction_leaf"
+	// LightBlockHeaderLeafStep records the computation of a light block header's leaf.
+	LightBlockHeaderLeafStep TraceStepKind = "light_block_header_leaf"
+	// VectorCommitmentNodeStep records the computation of an internal node while climbing from a leaf to the root.
+	// It belongs to the tree of the most recent leaf step.
+	VectorCommitmentNodeStep TraceStepKind = "vector_commitment_node"
+	// RootComparisonStep records the comparison of a computed root to the expected commitment.
+	RootComparisonStep TraceStepKind = "root_comparison"
+)
+
+// TraceStep is a single step of a verification. Only the fields relevant to the step's kind are set.
+type TraceStep struct {
+	Kind TraceStepKind `json:"kind"`
+	// Preimage is the data hashed to compute a leaf. For light block headers, it contains the header's msgpack encoding.
+	Preimage []byte `json:"preimage,omitempty"`
+	// Level is the distance of a computed node's child from the leaf.
+	Level uint64 `json:"level"`
+	// Position is the position of the computed node's child that is on the leaf-to-root path, either "left" or "right".
+	Position string `json:"position,omitempty"`
+	// Sibling is the sibling of the computed node's child, as retrieved from the proof.
+	Sibling []byte `json:"sibling,omitempty"`
+	// Hash is the computed leaf or node. For root comparisons, it's the computed root.
+	Hash []byte `json:"hash,omitempty"`
+	// Expected is the commitment the computed root is compared to.
+	Expected []byte `json:"expected,omitempty"`
+	// Match is the result of comparing the computed root to the expected commitment.
+	Match bool `json:"match"`
+}
+
+// Tracer records every step of a verification, allowing the intermediate hashes of a failed verification to be
+// inspected. A nil Tracer records nothing.
+type Tracer struct {
+	Steps []TraceStep `json:"steps"`
+}
+
+// record appends the given step, unless the Tracer is nil.
+// Parameters:
+// step - the step to record.
+func (t *Tracer) record(step TraceStep) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, step)
+}
+
+// JSON returns the recorded steps encoded as JSON. Byte fields are encoded using base64, as in Algorand node responses.
+func (t *Tracer) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// String returns the recorded steps in a human-readable format, one step per line. Node steps are indented under the
+// leaf they climb from. A nil Tracer returns an empty string.
+func (t *Tracer) String() string {
+	if t == nil {
+		return ""
+	}
+
+	encode := base64.StdEncoding.EncodeToString
+
+	var builder strings.Builder
+	for _, step := range t.Steps {
+		switch step.Kind {
+		case TransactionLeafStep:
+			fmt.Fprintf(&builder, "transaction leaf: preimage=%s hash=%s\n", encode(step.Preimage), encode(step.Hash))
+		case LightBlockHeaderLeafStep:
+			fmt.Fprintf(&builder, "light block header leaf: preimage=%s hash=%s\n", encode(step.Preimage), encode(step.Hash))
+		case VectorCommitmentNodeStep:
+			fmt.Fprintf(&builder, "  level %d: position=%s sibling=%s parent=%s\n", step.Level, step.Position,
+				encode(step.Sibling), encode(step.Hash))
+		case RootComparisonStep:
+			result := "mismatch"
+			if step.Match {
+				result = "match"
+			}
+			fmt.Fprintf(&builder, "root comparison: computed=%s expected=%s result=%s\n", encode(step.Hash),
+				encode(step.Expected), result)
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,55 @@
+package transactionverifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	tracer.record(TraceStep{Kind: TransactionLeafStep})
+
+	if tracer.String() != "" {
+		t.Fatalf("expected an empty string, got %q", tracer.String())
+	}
+
+	encodedTrace, err := tracer.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encodedTrace) != "null" {
+		t.Fatalf("expected null, got %s", encodedTrace)
+	}
+}
+
+func TestTracerRecordsVerification(t *testing.T) {
+	transaction := loadRecordedTransaction(t)
+	tracer := &Tracer{}
+	err := transaction.verify(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(tracer.String(), "\n"), "\n")
+	if len(lines) != len(tracer.Steps) {
+		t.Fatalf("expected a line for each of the %d steps, got %d lines", len(tracer.Steps), len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "transaction leaf: ") || !strings.HasSuffix(lines[len(lines)-1], "result=match") {
+		t.Fatalf("unexpected trace:\n%s", tracer.String())
+	}
+
+	encodedTrace, err := tracer.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decodedTracer Tracer
+	err = json.Unmarshal(encodedTrace, &decodedTracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedTracer.String() != tracer.String() {
+		t.Fatalf("decoded trace differs:\n%s\nexpected:\n%s", decodedTracer.String(), tracer.String())
+	}
+}
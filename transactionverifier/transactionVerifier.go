@@ -2,26 +2,37 @@ package transactionverifier
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"errors"
+	"math/bits"
 
 	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
 	"github.com/algorand/go-algorand-sdk/crypto"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
 	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
 )
 
 var (
 	TxnMerkleLeaf   = []byte("TL")
-	MerkleArrayNode = []byte("MA")
+	MerkleArrayNode = vectorcommitment.MerkleArrayNode
+)
+
+var (
+	ErrUnsupportedHashFunction           = errors.New("proof hash function is unsupported")
+	ErrInvalidPosition                   = errors.New("invalid position for node")
+	ErrInvalidIntervalSize               = errors.New("interval size must be positive")
+	ErrRoundNotAttested                  = errors.New("confirmed round precedes the first attested round")
+	ErrLightBlockHeaderTreeDepthMismatch = errors.New("light block header proof tree depth does not match the interval size")
+	ErrLightBlockHeaderIndexMismatch     = errors.New("light block header proof index does not match the round's offset in its interval")
 )
 
+// Vector commitment roots are computed by the vectorcommitment package, so its errors are returned as is.
 var (
-	ErrUnsupportedHashFunction      = errors.New("proof hash function is unsupported")
-	ErrProofLengthTreeDepthMismatch = errors.New("proof length and tree depth do not match")
-	ErrRootMismatch                 = errors.New("root mismatch")
-	ErrInvalidTreeDepth             = errors.New("invalid tree depth")
-	ErrIndexDepthMismatch           = errors.New("node index is not smaller than 2^depth")
-	ErrInvalidPosition              = errors.New("invalid position for node")
+	ErrProofLengthTreeDepthMismatch = vectorcommitment.ErrProofLengthTreeDepthMismatch
+	ErrRootMismatch                 = vectorcommitment.ErrRootMismatch
+	ErrInvalidTreeDepth             = vectorcommitment.ErrInvalidTreeDepth
+	ErrIndexDepthMismatch           = vectorcommitment.ErrIndexDepthMismatch
 )
 
 type NodePosition int
@@ -31,19 +42,37 @@ const (
 	rightChild
 )
 
+// String returns a human-readable name for the position.
+func (p NodePosition) String() string {
+	switch p {
+	case leftChild:
+		return "left"
+	case rightChild:
+		return "right"
+	default:
+		return "invalid"
+	}
+}
+
 // computeTransactionLeaf receives the transaction ID and the signed transaction in block's hash, and computes
 // the leaf of the vector commitment associated with the transaction.
 // Parameters:
-// transactionHash - the Sha256 hash of the canonical msgpack encoded transaction.
-// stibHash - the Sha256 of the canonical msgpack encoded transaction as it's saved in the block.
-func computeTransactionLeaf(transactionHash types.Digest, stibHash types.Digest) types.Digest {
+// hashFunction - the hash function used to create the vector commitment.
+// transactionHash - the hash of the canonical msgpack encoded transaction, computed using hashFunction.
+// stibHash - the hash of the canonical msgpack encoded transaction as it's saved in the block, computed using hashFunction.
+// tracer - records the leaf's preimage and hash. May be nil.
+func computeTransactionLeaf(hashFunction HashFunction, transactionHash types.Digest, stibHash types.Digest, tracer *Tracer) types.Digest {
 	leafData := make([]byte, 0, len(TxnMerkleLeaf)+len(transactionHash)+len(stibHash))
 	leafData = append(leafData, TxnMerkleLeaf...)
 	leafData = append(leafData, transactionHash[:]...)
 	leafData = append(leafData, stibHash[:]...)
 
-	// The leaf returned is of the form: Sha256("TL" || Sha256(transaction) || Sha256(transaction in block))
-	return sha256.Sum256(leafData)
+	// The leaf returned is of the form: Hash("TL" || Hash(transaction) || Hash(transaction in block))
+	leaf := hashWithFunction(hashFunction, leafData)
+	if tracer != nil {
+		tracer.record(TraceStep{Kind: TransactionLeafStep, Preimage: leafData, Hash: append([]byte{}, leaf[:]...)})
+	}
+	return leaf
 }
 
 // computeLightBlockHeaderLeaf receives the parameters comprising a light block header, and computes the leaf
@@ -53,8 +82,9 @@ func computeTransactionLeaf(transactionHash types.Digest, stibHash types.Digest)
 // transactionCommitment - the sha256 vector commitment root for the transactions in the block to which the light block header belongs.
 // genesisHash - the hash of the genesis block.
 // seed - the sortition seed of the block associated with the light block header.
+// tracer - records the leaf's preimage, including the light block header's encoding, and hash. May be nil.
 func computeLightBlockHeaderLeaf(roundNumber types.Round,
-	transactionCommitment types.Digest, genesisHash types.Digest, seed types.Seed) types.Digest {
+	transactionCommitment types.Digest, genesisHash types.Digest, seed types.Seed, tracer *Tracer) types.Digest {
 	lightBlockHeader := types.LightBlockHeader{
 		RoundNumber:         roundNumber,
 		GenesisHash:         genesisHash,
@@ -63,104 +93,182 @@ func computeLightBlockHeaderLeaf(roundNumber types.Round,
 	}
 
 	// The leaf returned is of the form Sha256("B256" || msgpack(lightBlockHeader))
-	return crypto.HashLightBlockHeader(lightBlockHeader)
+	leaf := crypto.HashLightBlockHeader(lightBlockHeader)
+	if tracer != nil {
+		preimage := GetLightBlockHeaderPreimage(roundNumber, transactionCommitment, genesisHash, seed)
+		tracer.record(TraceStep{Kind: LightBlockHeaderLeafStep, Preimage: preimage, Hash: append([]byte{}, leaf[:]...)})
+	}
+	return leaf
 }
 
-// getVectorCommitmentPositions maps a depth and a vector commitment index to the "positions" of the nodes
-// on the leaf-to-root path, with 0/1 denoting left/right (respectively). It does so by expressing the index in binary
-// using exactly depth bits, starting from the most-significant bit.
-// For example, leafDepth=4 and index=5 maps to the array [0,1,0,1] -- indicating that the leaf is a left child,
-// the leaf's parent is a right child, etc.
+// GetLightBlockHeaderPreimage returns the data hashed by crypto.HashLightBlockHeader to compute a light block header's
+// leaf, which is of the form "B256" || msgpack(lightBlockHeader). HashLightBlockHeader does not expose it, so it is
+// reconstructed here, for verifiers that have to hash it themselves.
 // Parameters:
-// index - the leaf's index in the vector commitment tree.
-// depth - the length of the path from the leaf to the root.
-func getVectorCommitmentPositions(index uint64, depth uint64) ([]NodePosition, error) {
-	// A depth of 0 is only valid when the proof's length is also 0. Since the calling function checks for the situation
-	// where both values are 0, depth of 0 must be invalid.
-	if depth == 0 {
-		return []NodePosition{}, ErrInvalidTreeDepth
-	}
-
-	// Since each bit of the index is mapped to an element in the resulting positions array,
-	// the index must contain a number of bits amounting to the depth parameter, which means it must be smaller than 2 ^ depth.
-	if index >= 1<<depth {
-		return []NodePosition{}, ErrIndexDepthMismatch
-	}
-
-	// The resulting array should contain a number of positions equal to the depth of the tree -
-	// the length of the path between the root and the leaves - as that is the amounts of nodes traversed when calculating
-	// the vector commitment root.
-	directions := make([]NodePosition, depth)
-
-	// We iterate on the resulting array starting from the end, to allow us to extract LSBs, yet have the eventual result
-	// be equivalent to extracting MSBs.
-	for i := len(directions) - 1; i >= 0; i-- {
-		// We take index's current LSB, translate it to a node position and place it in index i.
-		directions[i] = NodePosition(index & 1)
-		// We shift the index to the right, to prepare for the extracting of the next LSB.
-		index >>= 1
+// roundNumber - the round of the block to which the light block header belongs.
+// transactionCommitment - the sha256 vector commitment root for the transactions in the block to which the light block header belongs.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block associated with the light block header.
+func GetLightBlockHeaderPreimage(roundNumber types.Round, transactionCommitment types.Digest, genesisHash types.Digest,
+	seed types.Seed) []byte {
+	lightBlockHeader := types.LightBlockHeader{
+		RoundNumber:         roundNumber,
+		GenesisHash:         genesisHash,
+		Sha256TxnCommitment: transactionCommitment,
+		Seed:                seed,
 	}
 
-	return directions, nil
+	return append(append([]byte{}, crypto.LightBlockHeaderPrefix...), msgpack.Encode(lightBlockHeader)...)
 }
 
 // computeVectorCommitmentRoot takes a vector commitment leaf, its index, a proof, and a tree depth. it calculates
-// the vector commitment root using the provided data. This is done by computing internal nodes using the proof,
-// starting from the leaf, until we reach the root. This function uses sha256 - it cannot be used correctly with leaves
-// and proofs created using a different hash function.
+// the vector commitment root using the provided data, see vectorcommitment.ComputeRoot. Nodes are hashed using the
+// given hash function, which must be the hash function used to create the leaf and the proof. Computing the root
+// does not allocate when using one of the hash functions defined in this package, as their NodeHashers are pooled.
 // Parameters:
+// layout - the layout of the tree. Transaction proofs use the layout matching their hash type, see
+// getTransactionCommitmentLayout, while light block header proofs always use VectorCommitmentLayout.
+// hashFunction - the hash function used to create the vector commitment.
 // leaf - the node we start computing the vector commitment root from.
 // leafIndex - the leaf's index.
 // proof - the proof to use in computing the vector commitment root. It holds hashed sibling nodes for each internal node
 // calculated.
 // treeDepth - the length of the path from the leaf to the root.
-func computeVectorCommitmentRoot(leaf types.Digest, leafIndex uint64, proof []byte, treeDepth uint64) (types.Digest, error) {
+// tracer - records the position, sibling and parent of each level. May be nil.
+func computeVectorCommitmentRoot(layout vectorcommitment.Layout, hashFunction HashFunction, leaf types.Digest,
+	leafIndex uint64, proof []byte, treeDepth uint64, tracer *Tracer) (types.Digest, error) {
 	// An empty proof is only possible when the leaf received is already the root, which means that the treeDepth
 	// must be 0. In this case, the result is the leaf itself.
+	// Such a tree holds a single leaf, so its index must be 0.
 	if len(proof) == 0 && treeDepth == 0 {
+		if leafIndex != 0 {
+			return types.Digest{}, ErrIndexDepthMismatch
+		}
 		return leaf, nil
 	}
 
-	nodeHashSize := uint64(sha256.New().Size())
-	// The proof must hold exactly treeDepth node hashes to allow us to compute enough nodes to reach the root.
-	if treeDepth*nodeHashSize != uint64(len(proof)) {
-		return types.Digest{}, ErrProofLengthTreeDepthMismatch
+	// Since the situation where both the proof's length and the depth are 0 was handled above, a depth of 0 must be
+	// invalid.
+	if treeDepth == 0 {
+		return types.Digest{}, ErrInvalidTreeDepth
 	}
 
-	// See comments on getVectorCommitmentPositions for more details on the contents of the positions variable.
-	positions, err := getVectorCommitmentPositions(leafIndex, treeDepth)
+	hasher := acquireNodeHasher(hashFunction)
+	defer releaseNodeHasher(hashFunction, hasher)
+
+	// Computed nodes are saved as digests, so hash functions with a different output length can not be used.
+	if hasher.Size() != len(types.Digest{}) {
+		return types.Digest{}, ErrUnsupportedHashFunction
+	}
+
+	// The closure is only created when tracing, so that computing the root does not allocate otherwise.
+	var onLevel vectorcommitment.LevelFunc
+	if tracer != nil {
+		onLevel = func(level uint64, isRightChild bool, sibling []byte, node []byte) {
+			position := leftChild
+			if isRightChild {
+				position = rightChild
+			}
+
+			tracer.record(TraceStep{
+				Kind:     VectorCommitmentNodeStep,
+				Level:    level,
+				Position: position.String(),
+				Sibling:  append([]byte{}, sibling...),
+				Hash:     append([]byte{}, node...),
+			})
+		}
+	}
+
+	root, err := vectorcommitment.ComputeRootWithNodeHasher(layout, hasher, leaf[:],
+		&vectorcommitment.Proof{Index: leafIndex, TreeDepth: treeDepth, Path: proof}, onLevel)
 	if err != nil {
 		return types.Digest{}, err
 	}
 
-	// We start climbing from the leaf.
-	currentNode := leaf
-	// When distanceFromLeaf equals treeDepth, currentNode will contain the computed root.
-	for distanceFromLeaf := uint64(0); distanceFromLeaf < treeDepth; distanceFromLeaf++ {
-		siblingIndexInProof := distanceFromLeaf * nodeHashSize
-		// siblingHash is the next node to append to our current node, retrieved from the proof.
-		siblingHash := proof[siblingIndexInProof : siblingIndexInProof+nodeHashSize]
-
-		// Vector commitment nodes are of the form Sha256("MA" || left child || right child). To calculate the internal node,
-		// we have to use the positions array to determine if our current node is the left or right child.
-		// Positions[distanceFromLeaf] is the position of the current node at height distanceFromLeaf.
-		nodeDomainSeparator := MerkleArrayNode
-		internalNodeData := nodeDomainSeparator
-		switch positions[distanceFromLeaf] {
-		case leftChild:
-			internalNodeData = append(internalNodeData, currentNode[:]...)
-			internalNodeData = append(internalNodeData, siblingHash[:]...)
-		case rightChild:
-			internalNodeData = append(internalNodeData, siblingHash[:]...)
-			internalNodeData = append(internalNodeData, currentNode[:]...)
-		default:
-			return types.Digest{}, ErrInvalidPosition
-		}
+	var rootDigest types.Digest
+	copy(rootDigest[:], root)
+	return rootDigest, nil
+}
+
+// verifyLightBlockHeaderProofPosition verifies that the given light block header proof is positioned where the
+// light block header of the given round must be. Light block headers are committed to in the order of their rounds,
+// so the header of a round must be found at the round's offset inside its state proof interval, and the tree depth
+// must be the depth of a vector commitment holding exactly one interval's worth of headers. Checking this ahead
+// of computing any hashes allows rejecting malformed or mismatched proofs with a clear reason.
+// Parameters:
+// round - the round of the block to which the light block header belongs.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// firstAttestedRound - the first round to which a state proof message attests.
+// intervalSize - the number of rounds each state proof message attests to.
+func verifyLightBlockHeaderProofPosition(round types.Round, lightBlockHeaderProofResponse models.LightBlockHeaderProof,
+	firstAttestedRound uint64, intervalSize uint64) error {
+	if intervalSize == 0 {
+		return ErrInvalidIntervalSize
+	}
+
+	// Rounds earlier than state proof generation beginning are not committed to by any interval.
+	if uint64(round) < firstAttestedRound {
+		return ErrRoundNotAttested
+	}
+
+	// A vector commitment over intervalSize leaves is padded to the nearest power of 2, so its depth is the number of
+	// bits required to express the largest index in the interval.
+	expectedTreeDepth := uint64(bits.Len64(intervalSize - 1))
+	if lightBlockHeaderProofResponse.Treedepth != expectedTreeDepth {
+		return ErrLightBlockHeaderTreeDepthMismatch
+	}
+
+	expectedIndex := (uint64(round) - firstAttestedRound) % intervalSize
+	if lightBlockHeaderProofResponse.Index != expectedIndex {
+		return ErrLightBlockHeaderIndexMismatch
+	}
+
+	return nil
+}
+
+// computeTransactionProofRoot computes the root of the vector commitment attesting to the given transaction, using the
+// hash function specified in the given transactionProofResponse.
+// Parameters:
+// transactionHash - the hash of the canonical msgpack encoded transaction, computed using the proof's hash function.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// tracer - records every step of the computation. May be nil.
+func computeTransactionProofRoot(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	tracer *Tracer) (types.Digest, error) {
+	hashFunction, err := getHashFunction(transactionProofResponse.Hashtype)
+	if err != nil {
+		return types.Digest{}, err
+	}
+
+	layout, err := getTransactionCommitmentLayout(transactionProofResponse.Hashtype)
+	if err != nil {
+		return types.Digest{}, err
+	}
+
+	var stibHashDigest types.Digest
+	copy(stibHashDigest[:], transactionProofResponse.Stibhash[:])
+
+	// We first compute the leaf in the vector commitment that attests to the given transaction.
+	transactionLeaf := computeTransactionLeaf(hashFunction, transactionHash, stibHashDigest, tracer)
+	// We use the transactionLeaf and the given transactionProofResponse to compute the root of the vector commitment
+	// that attests to the given transaction.
+	return computeVectorCommitmentRoot(layout, hashFunction, transactionLeaf, transactionProofResponse.Idx,
+		transactionProofResponse.Proof, transactionProofResponse.Treedepth, tracer)
+}
 
-		currentNode = sha256.Sum256(internalNodeData)
+// ComputeTransactionCommitment computes the root of the vector commitment over a block's transactions, i.e. the
+// light block header's Sha256TxnCommitment, from a transaction and its proof. It verifies nothing by itself.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+func ComputeTransactionCommitment(transactionHash types.Digest,
+	transactionProofResponse models.TransactionProofResponse) (types.Digest, error) {
+	// Light block headers only commit to sha256 transaction commitments.
+	if transactionProofResponse.Hashtype != Sha256HashType {
+		return types.Digest{}, ErrUnsupportedHashFunction
 	}
 
-	return currentNode, nil
+	return computeTransactionProofRoot(transactionHash, transactionProofResponse, nil)
 }
 
 // VerifyTransaction receives a sha256 hashed transaction, a proof to compute the transaction's commitment, a proof
@@ -175,43 +283,164 @@ func computeVectorCommitmentRoot(leaf types.Digest, leafIndex uint64, proof []by
 // genesisHash - the hash of the genesis block.
 // seed - the sortition seed of the block associated with the light block header.
 // blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the Oracle.
+// intervalSize - the number of rounds each state proof message attests to, as used by the Oracle.
 func VerifyTransaction(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
-	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest, seed types.Seed, blockIntervalCommitment types.Digest) error {
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest, seed types.Seed,
+	blockIntervalCommitment types.Digest, firstAttestedRound uint64, intervalSize uint64) error {
+	return VerifyTransactionWithTrace(transactionHash, transactionProofResponse, lightBlockHeaderProofResponse, confirmedRound,
+		genesisHash, seed, blockIntervalCommitment, firstAttestedRound, intervalSize, nil)
+}
+
+// VerifyTransactionWithTrace verifies a transaction exactly as VerifyTransaction does, while recording every leaf,
+// internal node and root comparison computed along the way. This allows inspecting where a failed verification
+// diverged from the expected commitment.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// confirmedRound - the round in which the given transaction was confirmed.
+// genesisHash - the hash of the genesis block.
+// seed - the sortition seed of the block associated with the light block header.
+// blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the Oracle.
+// intervalSize - the number of rounds each state proof message attests to, as used by the Oracle.
+// tracer - records the verification's steps. May be nil, in which case nothing is recorded.
+func VerifyTransactionWithTrace(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, confirmedRound types.Round, genesisHash types.Digest, seed types.Seed,
+	blockIntervalCommitment types.Digest, firstAttestedRound uint64, intervalSize uint64, tracer *Tracer) error {
 	// Verifying attested vector commitment roots is currently exclusively supported with sha256 hashing, both for transactions
 	// and light block headers.
-	if transactionProofResponse.Hashtype != "sha256" {
+	if transactionProofResponse.Hashtype != Sha256HashType {
 		return ErrUnsupportedHashFunction
 	}
 
-	var stibHashDigest types.Digest
-	copy(stibHashDigest[:], transactionProofResponse.Stibhash[:])
-
-	// We first compute the leaf in the vector commitment that attests to the given transaction.
-	transactionLeaf := computeTransactionLeaf(transactionHash, stibHashDigest)
-	// We use the transactionLeaf and the given transactionProofResponse to compute the root of the vector commitment
-	// that attests to the given transaction.
-	transactionProofRoot, err := computeVectorCommitmentRoot(transactionLeaf, transactionProofResponse.Idx,
-		transactionProofResponse.Proof, transactionProofResponse.Treedepth)
+	// We make sure the light block header proof belongs to the confirmed round before doing any hashing.
+	err := verifyLightBlockHeaderProofPosition(confirmedRound, lightBlockHeaderProofResponse, firstAttestedRound, intervalSize)
+	if err != nil {
+		return err
+	}
 
+	// We compute the root of the vector commitment that attests to the given transaction.
+	transactionProofRoot, err := computeTransactionProofRoot(transactionHash, transactionProofResponse, tracer)
 	if err != nil {
 		return err
 	}
 
 	// We use our computed transaction vector commitment root, saved in transactionProofRoot, and the given data
-	// to calculate the leaf in the vector commitment that attests to the light block headers.
-	candidateLightBlockHeaderLeaf := computeLightBlockHeaderLeaf(confirmedRound, transactionProofRoot, genesisHash, seed)
+	// to verify the light block header against the given commitment. See verifyLightBlockHeaderRoot for more details.
+	return verifyLightBlockHeaderRoot(confirmedRound, seed, transactionProofRoot, genesisHash, lightBlockHeaderProofResponse,
+		blockIntervalCommitment, tracer)
+}
+
+// VerifyLightBlockHeader receives the fields comprising a light block header, a proof to compute the commitment
+// belonging to the light block header, and an expected commitment to compare to. The function verifies that the
+// computed commitment using the given proof is identical to the provided commitment. Unlike VerifyTransaction, it
+// requires no transaction, which allows verifying fields such as the block's seed on their own.
+// Parameters:
+// round - the round of the block to which the light block header belongs.
+// seed - the sortition seed of the block associated with the light block header.
+// transactionCommitment - the sha256 vector commitment root for the transactions in the block to which the light block header belongs.
+// genesisHash - the hash of the genesis block.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// firstAttestedRound - the first round to which a state proof message attests, as used by the Oracle.
+// intervalSize - the number of rounds each state proof message attests to, as used by the Oracle.
+func VerifyLightBlockHeader(round types.Round, seed types.Seed, transactionCommitment types.Digest, genesisHash types.Digest,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, blockIntervalCommitment types.Digest,
+	firstAttestedRound uint64, intervalSize uint64) error {
+	// We make sure the light block header proof belongs to the given round before doing any hashing.
+	err := verifyLightBlockHeaderProofPosition(round, lightBlockHeaderProofResponse, firstAttestedRound, intervalSize)
+	if err != nil {
+		return err
+	}
+
+	return verifyLightBlockHeaderRoot(round, seed, transactionCommitment, genesisHash, lightBlockHeaderProofResponse,
+		blockIntervalCommitment, nil)
+}
+
+// verifyLightBlockHeaderRoot computes the light block header's leaf from the given fields, uses the given proof to
+// compute the root of the vector commitment over the interval's light block headers, and verifies that it is
+// identical to the provided commitment. It assumes the proof's position has already been verified.
+// Parameters:
+// round - the round of the block to which the light block header belongs.
+// seed - the sortition seed of the block associated with the light block header.
+// transactionCommitment - the sha256 vector commitment root for the transactions in the block to which the light block header belongs.
+// genesisHash - the hash of the genesis block.
+// lightBlockHeaderProofResponse - the response returned by an Algorand node when queried using the GetLightBlockHeaderProof.
+// blockIntervalCommitment - the commitment to compare to, provided by the Oracle.
+// tracer - records the leaf, internal nodes and root comparison. May be nil.
+func verifyLightBlockHeaderRoot(round types.Round, seed types.Seed, transactionCommitment types.Digest, genesisHash types.Digest,
+	lightBlockHeaderProofResponse models.LightBlockHeaderProof, blockIntervalCommitment types.Digest, tracer *Tracer) error {
+	// We use the given data to calculate the leaf in the vector commitment that attests to the light block headers.
+	candidateLightBlockHeaderLeaf := computeLightBlockHeaderLeaf(round, transactionCommitment, genesisHash, seed, tracer)
 	// We use the candidateLightBlockHeaderLeaf and the given lightBlockHeaderProofResponse to compute the root of the vector
 	// commitment that attests to the candidateLightBlockHeaderLeaf.
-	lightBlockHeaderProofRoot, err := computeVectorCommitmentRoot(candidateLightBlockHeaderLeaf, lightBlockHeaderProofResponse.Index, lightBlockHeaderProofResponse.Proof,
-		lightBlockHeaderProofResponse.Treedepth)
+	// Light block headers are designed for environments where only Sha256 exists, so their vector commitment always uses it.
+	lightBlockHeaderProofRoot, err := computeVectorCommitmentRoot(vectorcommitment.VectorCommitmentLayout,
+		Sha256HashFunction, candidateLightBlockHeaderLeaf, lightBlockHeaderProofResponse.Index,
+		lightBlockHeaderProofResponse.Proof, lightBlockHeaderProofResponse.Treedepth, tracer)
 
 	if err != nil {
 		return err
 	}
 
 	// We verify that the given commitment, provided by the Oracle, is identical to the computed commitment
-	if bytes.Equal(lightBlockHeaderProofRoot[:], blockIntervalCommitment[:]) != true {
+	match := bytes.Equal(lightBlockHeaderProofRoot[:], blockIntervalCommitment[:])
+	if tracer != nil {
+		tracer.record(TraceStep{
+			Kind:     RootComparisonStep,
+			Hash:     append([]byte{}, lightBlockHeaderProofRoot[:]...),
+			Expected: append([]byte{}, blockIntervalCommitment[:]...),
+			Match:    match,
+		})
+	}
+	if match != true {
 		return ErrRootMismatch
 	}
 	return nil
 }
+
+// VerifyTransactionWithTransactionCommitment receives a hashed transaction, a proof to compute the transaction's
+// commitment, and a trusted transaction commitment to compare to. The function verifies that the computed commitment
+// using the given proof is identical to the provided commitment. Unlike VerifyTransaction, it supports both sha256
+// and sha512_256 proofs, as it does not rely on light block headers, which only commit to the sha256 transaction
+// commitment. This makes it suitable for callers who already know the full, verified block header.
+// Parameters:
+// transactionHash - the hash of the canonical msgpack encoded transaction, computed using the proof's hash function.
+// For sha512_256 proofs, this is the transaction ID.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// transactionCommitment - the commitment to compare to, taken from a verified block header. This should be the
+// header's Sha256TxnCommitment for sha256 proofs, and its TxnCommitment for sha512_256 proofs. The TxnCommitment is a
+// merkle array rather than a vector commitment, see getTransactionCommitmentLayout.
+func VerifyTransactionWithTransactionCommitment(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	transactionCommitment types.Digest) error {
+	transactionProofRoot, err := computeTransactionProofRoot(transactionHash, transactionProofResponse, nil)
+	if err != nil {
+		return err
+	}
+
+	// We verify that the given commitment, taken from a verified block header, is identical to the computed commitment
+	if bytes.Equal(transactionProofRoot[:], transactionCommitment[:]) != true {
+		return ErrRootMismatch
+	}
+	return nil
+}
+
+// VerifyTransactionWithLightBlockHeader receives a sha256 hashed transaction, a proof to compute the transaction's
+// commitment, and a light block header that was already verified. The function verifies that the computed commitment
+// using the given proof is identical to the light block header's transaction commitment. This allows verifying
+// transactions without a light block header proof, once the light block header itself was verified.
+// Parameters:
+// transactionHash - the result of invoking Sha256 on the canonical msgpack encoded transaction.
+// transactionProofResponse - the response returned by an Algorand node when queried using GetTransactionProof.
+// lightBlockHeader - the verified light block header of the round in which the given transaction was confirmed.
+func VerifyTransactionWithLightBlockHeader(transactionHash types.Digest, transactionProofResponse models.TransactionProofResponse,
+	lightBlockHeader types.LightBlockHeader) error {
+	// Light block headers only commit to the sha256 transaction commitment.
+	if transactionProofResponse.Hashtype != Sha256HashType {
+		return ErrUnsupportedHashFunction
+	}
+
+	return VerifyTransactionWithTransactionCommitment(transactionHash, transactionProofResponse, lightBlockHeader.Sha256TxnCommitment)
+}
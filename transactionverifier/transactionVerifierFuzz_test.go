@@ -0,0 +1,86 @@
+//go:build go1.18
+// +build go1.18
+
+package transactionverifier
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+// The seed corpora in testdata/fuzz hold proofs of the boundary depths 0, 1, 63 and 64.
+
+func FuzzComputeVectorCommitmentRoot(f *testing.F) {
+	for _, rootCase := range append(getFixtureRootCases(f), getBuiltRootCases(f)...) {
+		f.Add(rootCase.hashFunction == Sha512_256HashFunction, rootCase.leaf[:], rootCase.index, rootCase.proof,
+			rootCase.treeDepth)
+	}
+
+	f.Fuzz(func(t *testing.T, useSha512_256 bool, leafBytes []byte, leafIndex uint64, proof []byte, treeDepth uint64) {
+		// Proofs using each hash function belong to trees of the layout an Algorand node uses for that hash function.
+		hashFunction, layout := Sha256HashFunction, vectorcommitment.VectorCommitmentLayout
+		if useSha512_256 {
+			hashFunction, layout = Sha512_256HashFunction, vectorcommitment.MerkleArrayLayout
+		}
+
+		var leaf types.Digest
+		copy(leaf[:], leafBytes)
+
+		root, err := computeVectorCommitmentRoot(layout, hashFunction, leaf, leafIndex, proof, treeDepth, nil)
+		expectedRoot, expectedErr := referenceComputeVectorCommitmentRoot(layout, hashFunction, leaf, leafIndex, proof,
+			treeDepth)
+		if err != expectedErr || root != expectedRoot {
+			t.Fatalf("computed (%x, %v), reference computed (%x, %v)", root, err, expectedRoot, expectedErr)
+		}
+		if err != nil {
+			return
+		}
+
+		// A successfully computed root must be reproduced when tracing, with a node recorded for every level.
+		tracer := &Tracer{}
+		tracedRoot, err := computeVectorCommitmentRoot(layout, hashFunction, leaf, leafIndex, proof, treeDepth, tracer)
+		if err != nil || tracedRoot != root {
+			t.Fatalf("traced computation returned (%x, %v), expected (%x, nil)", tracedRoot, err, root)
+		}
+		if uint64(len(tracer.Steps)) != treeDepth {
+			t.Fatalf("expected %d trace steps, got %d", treeDepth, len(tracer.Steps))
+		}
+	})
+}
+
+func FuzzVerifyTransaction(f *testing.F) {
+	transaction := loadRecordedTransaction(f)
+	transactionProof := transaction.TransactionProofResponse
+	lightBlockHeaderProof := transaction.LightBlockHeaderProofResponse
+
+	f.Add(transactionProof.Proof, transactionProof.Idx, transactionProof.Treedepth, lightBlockHeaderProof.Proof,
+		lightBlockHeaderProof.Index, lightBlockHeaderProof.Treedepth)
+
+	f.Fuzz(func(t *testing.T, transactionProofBytes []byte, transactionIndex uint64, transactionTreeDepth uint64,
+		lightBlockHeaderProofBytes []byte, lightBlockHeaderIndex uint64, lightBlockHeaderTreeDepth uint64) {
+		mutated := transaction
+		mutated.TransactionProofResponse.Proof = transactionProofBytes
+		mutated.TransactionProofResponse.Idx = transactionIndex
+		mutated.TransactionProofResponse.Treedepth = transactionTreeDepth
+		mutated.LightBlockHeaderProofResponse.Proof = lightBlockHeaderProofBytes
+		mutated.LightBlockHeaderProofResponse.Index = lightBlockHeaderIndex
+		mutated.LightBlockHeaderProofResponse.Treedepth = lightBlockHeaderTreeDepth
+
+		err := mutated.verify(nil)
+
+		// Finding other proofs verifying the transaction requires finding a sha256 collision, so verification must
+		// succeed exactly when the proofs are the recorded ones.
+		unmodified := bytes.Equal(transactionProofBytes, transactionProof.Proof) &&
+			transactionIndex == transactionProof.Idx && transactionTreeDepth == transactionProof.Treedepth &&
+			bytes.Equal(lightBlockHeaderProofBytes, lightBlockHeaderProof.Proof) &&
+			lightBlockHeaderIndex == lightBlockHeaderProof.Index &&
+			lightBlockHeaderTreeDepth == lightBlockHeaderProof.Treedepth
+		if unmodified != (err == nil) {
+			t.Fatalf("unmodified proofs: %v, verification error: %v", unmodified, err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package transactionverifier
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+)
+
+// generatedTransaction holds every input of VerifyTransaction for a transaction of a generated block.
+type generatedTransaction struct {
+	transactionHash         types.Digest
+	transactionProof        models.TransactionProofResponse
+	lightBlockHeaderProof   models.LightBlockHeaderProof
+	round                   types.Round
+	genesisHash             types.Digest
+	seed                    types.Seed
+	blockIntervalCommitment types.Digest
+	firstAttestedRound      uint64
+	intervalSize            uint64
+}
+
+func (g generatedTransaction) verify() error {
+	return VerifyTransaction(g.transactionHash, g.transactionProof, g.lightBlockHeaderProof, g.round, g.genesisHash,
+		g.seed, g.blockIntervalCommitment, g.firstAttestedRound, g.intervalSize)
+}
+
+// generateTransactions generates an interval of blocks holding random transactions, and returns the inputs verifying
+// every transaction of one of the blocks.
+func generateTransactions(t testing.TB, random *rand.Rand) []generatedTransaction {
+	t.Helper()
+
+	// Interval sizes are powers of 2, so that every light block header proof's depth matches the interval size.
+	intervalSize := uint64(1) << random.Intn(5)
+	firstAttestedRound := intervalSize * uint64(1+random.Intn(4))
+	interval := uint64(random.Intn(3))
+	offset := uint64(random.Intn(int(intervalSize)))
+
+	var genesisHash types.Digest
+	random.Read(genesisHash[:])
+
+	transactions := make([]BlockTransaction, 1+random.Intn(40))
+	for i := range transactions {
+		random.Read(transactions[i].TransactionHash[:])
+		random.Read(transactions[i].StibHash[:])
+	}
+	transactionProofBuilder, err := BuildTransactionProofs(Sha256HashType, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lightBlockHeaders := make([]types.LightBlockHeader, intervalSize)
+	for i := range lightBlockHeaders {
+		lightBlockHeaders[i] = types.LightBlockHeader{
+			RoundNumber: types.Round(firstAttestedRound + interval*intervalSize + uint64(i)),
+			GenesisHash: genesisHash,
+		}
+		random.Read(lightBlockHeaders[i].Seed[:])
+		random.Read(lightBlockHeaders[i].Sha256TxnCommitment[:])
+	}
+	lightBlockHeaders[offset].Sha256TxnCommitment = transactionProofBuilder.Root()
+
+	lightBlockHeaderProofBuilder, err := BuildLightBlockHeaderProofs(lightBlockHeaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lightBlockHeaderProof, err := lightBlockHeaderProofBuilder.GetLightBlockHeaderProof(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generatedTransactions := make([]generatedTransaction, len(transactions))
+	for i, transaction := range transactions {
+		transactionProof, err := transactionProofBuilder.GetTransactionProof(uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		generatedTransactions[i] = generatedTransaction{
+			transactionHash:         transaction.TransactionHash,
+			transactionProof:        transactionProof,
+			lightBlockHeaderProof:   lightBlockHeaderProof,
+			round:                   lightBlockHeaders[offset].RoundNumber,
+			genesisHash:             genesisHash,
+			seed:                    lightBlockHeaders[offset].Seed,
+			blockIntervalCommitment: lightBlockHeaderProofBuilder.Root(),
+			firstAttestedRound:      firstAttestedRound,
+			intervalSize:            intervalSize,
+		}
+	}
+
+	return generatedTransactions
+}
+
+// flipBits calls verify once for every bit of data, with the bit flipped, and restores data afterwards.
+func flipBits(t *testing.T, name string, data []byte, verify func() error) {
+	t.Helper()
+
+	for i := 0; i < len(data)*8; i++ {
+		data[i/8] ^= 1 << (i % 8)
+		err := verify()
+		data[i/8] ^= 1 << (i % 8)
+
+		if err == nil {
+			t.Fatalf("verification succeeded with bit %d of the %s flipped", i, name)
+		}
+	}
+}
+
+func TestGeneratedProofsVerify(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		for _, transaction := range generateTransactions(t, random) {
+			err := transaction.verify()
+			if err != nil {
+				t.Fatalf("interval size %d, round %d, transaction %d: %v", transaction.intervalSize, transaction.round,
+					transaction.transactionProof.Idx, err)
+			}
+		}
+	}
+}
+
+func TestSingleBitFlipsFail(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for i := 0; i < 5; i++ {
+		generatedTransactions := generateTransactions(t, random)
+		transaction := generatedTransactions[random.Intn(len(generatedTransactions))]
+		// Proofs are copied, as the transactions of a block share their light block header proof.
+		transaction.transactionProof.Proof = append([]byte{}, transaction.transactionProof.Proof...)
+		transaction.transactionProof.Stibhash = append([]byte{}, transaction.transactionProof.Stibhash...)
+		transaction.lightBlockHeaderProof.Proof = append([]byte{}, transaction.lightBlockHeaderProof.Proof...)
+
+		// The transaction is captured by reference, as verify's value receiver would otherwise copy the flipped digests.
+		verify := func() error {
+			return transaction.verify()
+		}
+
+		var indices [24]byte
+		flipBits(t, "transaction hash", transaction.transactionHash[:], verify)
+		flipBits(t, "transaction proof", transaction.transactionProof.Proof, verify)
+		flipBits(t, "stib hash", transaction.transactionProof.Stibhash, verify)
+		flipBits(t, "light block header proof", transaction.lightBlockHeaderProof.Proof, verify)
+		flipBits(t, "genesis hash", transaction.genesisHash[:], verify)
+		flipBits(t, "seed", transaction.seed[:], verify)
+		flipBits(t, "block interval commitment", transaction.blockIntervalCommitment[:], verify)
+		flipBits(t, "indices and round", indices[:], func() error {
+			modified := transaction
+			modified.transactionProof.Idx ^= bytesToUint64(indices[0:8])
+			modified.lightBlockHeaderProof.Index ^= bytesToUint64(indices[8:16])
+			modified.round ^= types.Round(bytesToUint64(indices[16:24]))
+			return modified.verify()
+		})
+	}
+}
+
+// bytesToUint64 interprets 8 bytes as a little-endian uint64.
+func bytesToUint64(data []byte) uint64 {
+	var value uint64
+	for i := 7; i >= 0; i-- {
+		value = value<<8 | uint64(data[i])
+	}
+	return value
+}
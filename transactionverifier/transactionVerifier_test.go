@@ -0,0 +1,410 @@
+package transactionverifier
+
+import (
+	"crypto/sha512"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/vectorcommitment"
+)
+
+// recordedTransaction is the fixture bundle's valid transaction case, which was recorded from an Algorand node, along
+// with the commitment attested to by the bundle's state proof and the bundle's network parameters.
+type recordedTransaction struct {
+	encodedassets.FixtureTransactionCase
+	blockIntervalCommitment types.Digest
+	firstAttestedRound      uint64
+	intervalSize            uint64
+}
+
+func loadFixtureBundle(t testing.TB) *encodedassets.FixtureBundle {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtureBundle
+}
+
+func loadRecordedTransaction(t testing.TB) recordedTransaction {
+	t.Helper()
+
+	fixtureBundle := loadFixtureBundle(t)
+	var blockIntervalCommitment types.Digest
+	copy(blockIntervalCommitment[:], fixtureBundle.StateProofs[0].Message.Blockheaderscommitment)
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError == "" {
+			return recordedTransaction{
+				FixtureTransactionCase:  transactionCase,
+				blockIntervalCommitment: blockIntervalCommitment,
+				firstAttestedRound:      fixtureBundle.Network.FirstAttestedRound,
+				intervalSize:            fixtureBundle.Network.IntervalSize,
+			}
+		}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return recordedTransaction{}
+}
+
+func (r recordedTransaction) verify(tracer *Tracer) error {
+	return VerifyTransactionWithTrace(r.TransactionID, r.TransactionProofResponse, r.LightBlockHeaderProofResponse,
+		r.Round, r.GenesisHash, r.Seed, r.blockIntervalCommitment, r.firstAttestedRound, r.intervalSize, tracer)
+}
+
+func TestVerifyRecordedTransaction(t *testing.T) {
+	transaction := loadRecordedTransaction(t)
+
+	tracer := &Tracer{}
+	err := transaction.verify(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The trace holds both leaves, a node for every level of both proofs and the root comparison.
+	expectedSteps := 2 + int(transaction.TransactionProofResponse.Treedepth+transaction.LightBlockHeaderProofResponse.Treedepth) + 1
+	if len(tracer.Steps) != expectedSteps {
+		t.Fatalf("expected %d trace steps, got %d", expectedSteps, len(tracer.Steps))
+	}
+}
+
+// verifyLightBlockHeader verifies the recorded light block header, using the given transaction commitment.
+func (r recordedTransaction) verifyLightBlockHeader(transactionCommitment types.Digest) error {
+	return VerifyLightBlockHeader(r.Round, r.Seed, transactionCommitment, r.GenesisHash,
+		r.LightBlockHeaderProofResponse, r.blockIntervalCommitment, r.firstAttestedRound, r.intervalSize)
+}
+
+func TestVerifyLightBlockHeader(t *testing.T) {
+	transaction := loadRecordedTransaction(t)
+
+	transactionCommitment, err := ComputeTransactionCommitment(transaction.TransactionID,
+		transaction.TransactionProofResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = transaction.verifyLightBlockHeader(transactionCommitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		tamper      func(transaction *recordedTransaction, transactionCommitment *types.Digest)
+		expectedErr error
+	}{
+		"wrong seed": {func(transaction *recordedTransaction, _ *types.Digest) { transaction.Seed[0] ^= 1 },
+			ErrRootMismatch},
+		"wrong transaction commitment": {func(_ *recordedTransaction, commitment *types.Digest) { commitment[0] ^= 1 },
+			ErrRootMismatch},
+		"proof of another round": {func(transaction *recordedTransaction, _ *types.Digest) { transaction.Round++ },
+			ErrLightBlockHeaderIndexMismatch},
+		"round before the first attested round": {func(transaction *recordedTransaction, _ *types.Digest) {
+			transaction.Round = 1
+		}, ErrRoundNotAttested},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tampered, tamperedCommitment := transaction, transactionCommitment
+			testCase.tamper(&tampered, &tamperedCommitment)
+
+			err := tampered.verifyLightBlockHeader(tamperedCommitment)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+// hashMerkleArrayNode computes Sha512_256("MA" || leftChild || rightChild), without relying on the vectorcommitment
+// package.
+func hashMerkleArrayNode(leftChild []byte, rightChild []byte) []byte {
+	node := sha512.Sum512_256(append(append([]byte("MA"), leftChild...), rightChild...))
+	return node[:]
+}
+
+func TestVerifyTransactionWithMerkleArrayCommitment(t *testing.T) {
+	// An Algorand node builds a block's TxnCommitment as a merkle array: the leaves of the block's 3 transactions keep
+	// their order, and the node missing a right child is hashed with zero bytes in its place.
+	var leaves [3][]byte
+	var transactions [3]BlockTransaction
+	for i := range transactions {
+		transactions[i] = BlockTransaction{TransactionHash: types.Digest{byte(i)}, StibHash: types.Digest{0, byte(i)}}
+		leaf := sha512.Sum512_256(append(append([]byte("TL"), transactions[i].TransactionHash[:]...),
+			transactions[i].StibHash[:]...))
+		leaves[i] = leaf[:]
+	}
+
+	zeroBytes := make([]byte, len(types.Digest{}))
+	firstPair := hashMerkleArrayNode(leaves[0], leaves[1])
+	secondPair := hashMerkleArrayNode(leaves[2], zeroBytes)
+	var transactionCommitment types.Digest
+	copy(transactionCommitment[:], hashMerkleArrayNode(firstPair, secondPair))
+
+	proofs := [][]byte{
+		append(append([]byte{}, leaves[1]...), secondPair...),
+		append(append([]byte{}, leaves[0]...), secondPair...),
+		append(append([]byte{}, zeroBytes...), firstPair...),
+	}
+
+	for i, proof := range proofs {
+		transactionProofResponse := models.TransactionProofResponse{
+			Hashtype:  Sha512_256HashType,
+			Idx:       uint64(i),
+			Proof:     proof,
+			Stibhash:  transactions[i].StibHash[:],
+			Treedepth: 2,
+		}
+
+		err := VerifyTransactionWithTransactionCommitment(transactions[i].TransactionHash, transactionProofResponse,
+			transactionCommitment)
+		if err != nil {
+			t.Fatalf("index %d: expected no error, got %v", i, err)
+		}
+
+		// The proofs do not verify transactions at other positions.
+		transactionProofResponse.Idx = uint64(i+1) % uint64(len(proofs))
+		err = VerifyTransactionWithTransactionCommitment(transactions[i].TransactionHash, transactionProofResponse,
+			transactionCommitment)
+		if !errors.Is(err, ErrRootMismatch) {
+			t.Fatalf("index %d: expected %v, got %v", i, ErrRootMismatch, err)
+		}
+	}
+}
+
+func TestVectorCommitmentErrorsAreShared(t *testing.T) {
+	transaction := loadRecordedTransaction(t)
+
+	truncated := transaction
+	truncated.TransactionProofResponse.Proof = truncated.TransactionProofResponse.Proof[1:]
+	err := truncated.verify(nil)
+	if !errors.Is(err, ErrProofLengthTreeDepthMismatch) ||
+		!errors.Is(err, vectorcommitment.ErrProofLengthTreeDepthMismatch) {
+		t.Fatalf("expected %v, got %v", ErrProofLengthTreeDepthMismatch, err)
+	}
+
+	tampered := transaction
+	tampered.Seed[0] ^= 1
+	err = tampered.verify(nil)
+	if !errors.Is(err, ErrRootMismatch) || !errors.Is(err, vectorcommitment.ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", ErrRootMismatch, err)
+	}
+
+	outOfRange := transaction
+	outOfRange.TransactionProofResponse.Idx = 1 << outOfRange.TransactionProofResponse.Treedepth
+	err = outOfRange.verify(nil)
+	if !errors.Is(err, ErrIndexDepthMismatch) || !errors.Is(err, vectorcommitment.ErrIndexDepthMismatch) {
+		t.Fatalf("expected %v, got %v", ErrIndexDepthMismatch, err)
+	}
+}
+
+// referenceComputeVectorCommitmentRoot computes a vector commitment root as computeVectorCommitmentRoot did before
+// nodes were computed using pooled NodeHashers: every node is hashed using a new hash.Hash, and the node's position is
+// read directly from the index. Vector commitments are read starting from the index's most-significant bit, while
+// merkle arrays are read starting from its least-significant bit.
+func referenceComputeVectorCommitmentRoot(layout vectorcommitment.Layout, hashFunction HashFunction, leaf types.Digest,
+	leafIndex uint64, proof []byte, treeDepth uint64) (types.Digest, error) {
+	if len(proof) == 0 && treeDepth == 0 {
+		if leafIndex != 0 {
+			return types.Digest{}, ErrIndexDepthMismatch
+		}
+		return leaf, nil
+	}
+
+	if treeDepth == 0 || treeDepth > 64 {
+		return types.Digest{}, ErrInvalidTreeDepth
+	}
+	if treeDepth < 64 && leafIndex >= 1<<treeDepth {
+		return types.Digest{}, ErrIndexDepthMismatch
+	}
+	if hashFunction.New().Size() != len(types.Digest{}) {
+		return types.Digest{}, ErrUnsupportedHashFunction
+	}
+	if treeDepth*uint64(len(types.Digest{})) != uint64(len(proof)) {
+		return types.Digest{}, ErrProofLengthTreeDepthMismatch
+	}
+
+	currentNode := leaf
+	for distanceFromLeaf := uint64(0); distanceFromLeaf < treeDepth; distanceFromLeaf++ {
+		siblingHash := proof[distanceFromLeaf*32 : (distanceFromLeaf+1)*32]
+
+		positionBit := (leafIndex >> (treeDepth - 1 - distanceFromLeaf)) & 1
+		if layout == vectorcommitment.MerkleArrayLayout {
+			positionBit = (leafIndex >> distanceFromLeaf) & 1
+		}
+
+		internalNodeData := append([]byte{}, MerkleArrayNode...)
+		if positionBit == 0 {
+			internalNodeData = append(append(internalNodeData, currentNode[:]...), siblingHash...)
+		} else {
+			internalNodeData = append(append(internalNodeData, siblingHash...), currentNode[:]...)
+		}
+
+		currentNode = hashWithFunction(hashFunction, internalNodeData)
+	}
+
+	return currentNode, nil
+}
+
+// vectorCommitmentRootCase is an input to computeVectorCommitmentRoot.
+type vectorCommitmentRootCase struct {
+	name         string
+	layout       vectorcommitment.Layout
+	hashFunction HashFunction
+	leaf         types.Digest
+	index        uint64
+	proof        []byte
+	treeDepth    uint64
+}
+
+// getFixtureRootCases returns the proofs of every transaction case of the fixture bundle, including the cases whose
+// proofs are malformed.
+func getFixtureRootCases(t testing.TB) []vectorCommitmentRootCase {
+	t.Helper()
+
+	transactionCases := loadFixtureBundle(t).TransactionCases
+
+	var rootCases []vectorCommitmentRootCase
+	for _, transactionCase := range transactionCases {
+		transactionProof := transactionCase.TransactionProofResponse
+		lightBlockHeaderProof := transactionCase.LightBlockHeaderProofResponse
+		hashFunction, err := getHashFunction(transactionProof.Hashtype)
+		layout, layoutErr := getTransactionCommitmentLayout(transactionProof.Hashtype)
+		if err == nil && layoutErr == nil {
+			var stibHash types.Digest
+			copy(stibHash[:], transactionProof.Stibhash)
+			rootCases = append(rootCases, vectorCommitmentRootCase{
+				name:         transactionCase.Name + " (transaction proof)",
+				layout:       layout,
+				hashFunction: hashFunction,
+				leaf:         computeTransactionLeaf(hashFunction, transactionCase.TransactionID, stibHash, nil),
+				index:        transactionProof.Idx,
+				proof:        transactionProof.Proof,
+				treeDepth:    transactionProof.Treedepth,
+			})
+		}
+
+		rootCases = append(rootCases, vectorCommitmentRootCase{
+			name:         transactionCase.Name + " (light block header proof)",
+			layout:       vectorcommitment.VectorCommitmentLayout,
+			hashFunction: Sha256HashFunction,
+			leaf:         types.Digest{byte(len(rootCases))},
+			index:        lightBlockHeaderProof.Index,
+			proof:        lightBlockHeaderProof.Proof,
+			treeDepth:    lightBlockHeaderProof.Treedepth,
+		})
+	}
+
+	return rootCases
+}
+
+// getBuiltRootCases returns proofs of every transaction in blocks of several sizes, built using both hash functions.
+func getBuiltRootCases(t testing.TB) []vectorCommitmentRootCase {
+	t.Helper()
+
+	var rootCases []vectorCommitmentRootCase
+	for _, hashType := range []string{Sha256HashType, Sha512_256HashType} {
+		hashFunction, err := getHashFunction(hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layout, err := getTransactionCommitmentLayout(hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for numberOfTransactions := 1; numberOfTransactions <= 17; numberOfTransactions += 4 {
+			transactions := make([]BlockTransaction, numberOfTransactions)
+			for i := range transactions {
+				transactions[i] = BlockTransaction{TransactionHash: types.Digest{byte(i)}, StibHash: types.Digest{0, byte(i)}}
+			}
+
+			builder, err := BuildTransactionProofs(hashType, transactions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, transaction := range transactions {
+				transactionProof, err := builder.GetTransactionProof(uint64(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rootCases = append(rootCases, vectorCommitmentRootCase{
+					name:         hashType,
+					layout:       layout,
+					hashFunction: hashFunction,
+					leaf:         computeTransactionLeaf(hashFunction, transaction.TransactionHash, transaction.StibHash, nil),
+					index:        transactionProof.Idx,
+					proof:        transactionProof.Proof,
+					treeDepth:    transactionProof.Treedepth,
+				})
+			}
+		}
+	}
+
+	return rootCases
+}
+
+func TestComputeVectorCommitmentRootMatchesReference(t *testing.T) {
+	rootCases := append(getFixtureRootCases(t), getBuiltRootCases(t)...)
+	for _, rootCase := range rootCases {
+		root, err := computeVectorCommitmentRoot(rootCase.layout, rootCase.hashFunction, rootCase.leaf, rootCase.index,
+			rootCase.proof, rootCase.treeDepth, nil)
+		expectedRoot, expectedErr := referenceComputeVectorCommitmentRoot(rootCase.layout, rootCase.hashFunction,
+			rootCase.leaf, rootCase.index, rootCase.proof, rootCase.treeDepth)
+
+		if err != expectedErr || root != expectedRoot {
+			t.Fatalf("%s, index %d: computed (%x, %v), reference computed (%x, %v)", rootCase.name, rootCase.index, root,
+				err, expectedRoot, expectedErr)
+		}
+	}
+}
+
+func TestComputeVectorCommitmentRootDoesNotAllocate(t *testing.T) {
+	for _, rootCase := range getBuiltRootCases(t) {
+		allocations := testing.AllocsPerRun(10, func() {
+			_, _ = computeVectorCommitmentRoot(rootCase.layout, rootCase.hashFunction, rootCase.leaf, rootCase.index,
+				rootCase.proof, rootCase.treeDepth, nil)
+		})
+		if allocations != 0 {
+			t.Fatalf("%s, index %d: expected no allocations, got %v", rootCase.name, rootCase.index, allocations)
+		}
+	}
+}
+
+func BenchmarkComputeVectorCommitmentRoot(b *testing.B) {
+	transaction := loadRecordedTransaction(b)
+	lightBlockHeaderProof := transaction.LightBlockHeaderProofResponse
+	leaf := computeLightBlockHeaderLeaf(transaction.Round, types.Digest{}, transaction.GenesisHash, transaction.Seed, nil)
+
+	deepProof := make([]byte, 64*len(types.Digest{}))
+	benchmarks := []vectorCommitmentRootCase{
+		{"recorded light block header proof", vectorcommitment.VectorCommitmentLayout, Sha256HashFunction, leaf,
+			lightBlockHeaderProof.Index, lightBlockHeaderProof.Proof, lightBlockHeaderProof.Treedepth},
+		{"depth 64 sha256", vectorcommitment.VectorCommitmentLayout, Sha256HashFunction, leaf, 1<<63 + 1, deepProof,
+			64},
+		{"depth 64 sha512_256", vectorcommitment.MerkleArrayLayout, Sha512_256HashFunction, leaf, 1<<63 + 1,
+			deepProof, 64},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := computeVectorCommitmentRoot(benchmark.layout, benchmark.hashFunction, benchmark.leaf,
+					benchmark.index, benchmark.proof, benchmark.treeDepth, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package vectorcommitment
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+var (
+	ErrNoIndicesToProve      = errors.New("multi-proof must prove at least one element")
+	ErrDuplicateIndex        = errors.New("multi-proof indices must be unique")
+	ErrLeavesIndicesMismatch = errors.New("number of leaves and number of multi-proof indices do not match")
+)
+
+// MultiProof proves that several leaves are committed to by the same tree. Siblings shared by the leaf-to-root paths
+// of the proven leaves, or computable from the proven leaves themselves, appear in the proof at most once.
+type MultiProof struct {
+	// Indices are the indices of the elements whose leaves are proven.
+	Indices []uint64
+	// TreeDepth is the length of the path from a leaf to the root.
+	TreeDepth uint64
+	// Path is the concatenation of the hashes of every sibling required to compute the root that can not be computed
+	// from the proven leaves. Siblings are ordered by level, starting from the leaves, and by position within each
+	// level. Missing siblings are replaced with zero bytes.
+	Path []byte
+}
+
+// getLeafPositions maps each index to the position of its leaf in the bottom level of the tree.
+// Parameters:
+// layout - the layout of the tree.
+// indices - the elements' indices.
+// depth - the length of the path from a leaf to the root.
+func getLeafPositions(layout Layout, indices []uint64, depth uint64) ([]uint64, error) {
+	if len(indices) == 0 {
+		return nil, ErrNoIndicesToProve
+	}
+
+	positions := make([]uint64, len(indices))
+	seen := make(map[uint64]bool, len(indices))
+	for i, index := range indices {
+		if seen[index] {
+			return nil, ErrDuplicateIndex
+		}
+		seen[index] = true
+
+		position, err := getLeafPosition(layout, index, depth)
+		if err != nil {
+			return nil, err
+		}
+		positions[i] = position
+	}
+
+	return positions, nil
+}
+
+// walkMultiProof climbs from the given leaves to the root, level by level. For every node whose sibling is not known,
+// it calls getSibling to retrieve the sibling, and then computes their parent.
+// Parameters:
+// hashFactory - the hash function used by the tree.
+// positions - the positions of the leaves in the bottom level of the tree.
+// leaves - the leaves, ordered as positions.
+// depth - the length of the path from a leaf to the root.
+// getSibling - receives a level and a position in that level, and returns the hash of the node in that position.
+func walkMultiProof(hashFactory HashFactory, positions []uint64, leaves [][]byte, depth uint64,
+	getSibling func(level uint64, position uint64) ([]byte, error)) ([]byte, error) {
+	knownNodes := make(map[uint64][]byte, len(positions))
+	for i, position := range positions {
+		knownNodes[position] = leaves[i]
+	}
+
+	for level := uint64(0); level < depth; level++ {
+		// Nodes are handled in ascending order of their positions, which determines the order of siblings in the proof.
+		levelPositions := make([]uint64, 0, len(knownNodes))
+		for position := range knownNodes {
+			levelPositions = append(levelPositions, position)
+		}
+		sort.Slice(levelPositions, func(i, j int) bool { return levelPositions[i] < levelPositions[j] })
+
+		parentNodes := make(map[uint64][]byte, (len(knownNodes)+1)/2)
+		for _, position := range levelPositions {
+			parentPosition := position >> 1
+			// A parent is computed once, when handling its first known child.
+			if _, computed := parentNodes[parentPosition]; computed {
+				continue
+			}
+
+			siblingPosition := position ^ 1
+			sibling, known := knownNodes[siblingPosition]
+			if !known {
+				var err error
+				sibling, err = getSibling(level, siblingPosition)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if position&1 == 0 {
+				parentNodes[parentPosition] = hashInternalNode(hashFactory, knownNodes[position], sibling)
+			} else {
+				parentNodes[parentPosition] = hashInternalNode(hashFactory, sibling, knownNodes[position])
+			}
+		}
+
+		knownNodes = parentNodes
+	}
+
+	return knownNodes[0], nil
+}
+
+// ProveMultiple creates a multi-proof for the elements in the given indices.
+// Parameters:
+// indices - the indices of the elements to prove.
+func (t *Tree) ProveMultiple(indices []uint64) (*MultiProof, error) {
+	for _, index := range indices {
+		if index >= t.NumberOfElements {
+			return nil, ErrIndexOutOfBounds
+		}
+	}
+
+	depth := t.Depth()
+	positions, err := getLeafPositions(t.Layout, indices, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := make([][]byte, len(positions))
+	for i, position := range positions {
+		leaves[i] = t.Levels[0][position]
+	}
+
+	hashSize := t.HashFactory().Size()
+	var path []byte
+	_, err = walkMultiProof(t.HashFactory, positions, leaves, depth, func(level uint64, position uint64) ([]byte, error) {
+		sibling := make([]byte, hashSize)
+		if position < uint64(len(t.Levels[level])) {
+			copy(sibling, t.Levels[level][position])
+		}
+		path = append(path, sibling...)
+		return sibling, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultiProof{
+		Indices:   append([]uint64{}, indices...),
+		TreeDepth: depth,
+		Path:      path,
+	}, nil
+}
+
+// ComputeRootFromMultiProof computes the root of a tree using several leaves and a multi-proof for them.
+// Parameters:
+// layout - the layout of the tree.
+// hashFactory - the hash function used by the tree.
+// leaves - the leaves to start computing the root from, ordered as the multi-proof's indices.
+// proof - the multi-proof for the leaves.
+func ComputeRootFromMultiProof(layout Layout, hashFactory HashFactory, leaves [][]byte, proof *MultiProof) ([]byte, error) {
+	if len(leaves) != len(proof.Indices) {
+		return nil, ErrLeavesIndicesMismatch
+	}
+
+	hashSize := uint64(hashFactory().Size())
+	for _, leaf := range leaves {
+		if uint64(len(leaf)) != hashSize {
+			return nil, ErrLeafSizeMismatch
+		}
+	}
+
+	positions, err := getLeafPositions(layout, proof.Indices, proof.TreeDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Siblings are consumed from the proof in the same order in which ProveMultiple appended them.
+	remainingPath := proof.Path
+	root, err := walkMultiProof(hashFactory, positions, leaves, proof.TreeDepth, func(uint64, uint64) ([]byte, error) {
+		if uint64(len(remainingPath)) < hashSize {
+			return nil, ErrProofLengthTreeDepthMismatch
+		}
+		sibling := remainingPath[:hashSize]
+		remainingPath = remainingPath[hashSize:]
+		return sibling, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every sibling in the proof must have been used.
+	if len(remainingPath) != 0 {
+		return nil, ErrProofLengthTreeDepthMismatch
+	}
+
+	return root, nil
+}
+
+// VerifyMultiProof verifies that the given leaves are committed to by the given root, using the given multi-proof.
+// Parameters:
+// layout - the layout of the tree.
+// hashFactory - the hash function used by the tree.
+// root - the root of the tree, as retrieved from a trusted source.
+// leaves - the leaves to verify, ordered as the multi-proof's indices.
+// proof - the multi-proof for the leaves.
+func VerifyMultiProof(layout Layout, hashFactory HashFactory, root []byte, leaves [][]byte, proof *MultiProof) error {
+	computedRoot, err := ComputeRootFromMultiProof(layout, hashFactory, leaves, proof)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(computedRoot, root) {
+		return ErrRootMismatch
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package vectorcommitment
+
+import (
+	"errors"
+	"testing"
+)
+
+// getTestIndexSets returns several sets of indices of a tree holding the given number of elements, each in the order
+// in which it is proven.
+func getTestIndexSets(numberOfElements uint64) map[string][]uint64 {
+	last := numberOfElements - 1
+	indexSets := map[string][]uint64{
+		"first": {0},
+		"last":  {last},
+	}
+	if numberOfElements < 2 {
+		return indexSets
+	}
+
+	indexSets["first and last"] = []uint64{0, last}
+	indexSets["last and first"] = []uint64{last, 0}
+	indexSets["siblings"] = []uint64{0, 1}
+	for index := uint64(0); index < numberOfElements; index++ {
+		indexSets["all"] = append(indexSets["all"], index)
+		if index%2 == 1 {
+			indexSets["odd"] = append(indexSets["odd"], index)
+		}
+	}
+	return indexSets
+}
+
+func TestProveMultipleRoundTrip(t *testing.T) {
+	hashFactories := map[string]HashFactory{"sha256": Sha256HashFactory, "sha512_256": Sha512_256HashFactory}
+	layouts := map[string]Layout{"merkle array": MerkleArrayLayout, "vector commitment": VectorCommitmentLayout}
+
+	for hashName, hashFactory := range hashFactories {
+		for layoutName, layout := range layouts {
+			for _, numberOfElements := range []int{1, 2, 3, 5, 8, 9} {
+				leaves := getTestLeaves(hashFactory, numberOfElements)
+				tree, err := Build(layout, hashFactory, leaves)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for setName, indices := range getTestIndexSets(uint64(numberOfElements)) {
+					name := hashName + " " + layoutName + ", " + setName
+					provenLeaves := make([][]byte, len(indices))
+					singleProofsLength := 0
+					for i, index := range indices {
+						provenLeaves[i] = leaves[index]
+
+						proof, err := tree.Prove(index)
+						if err != nil {
+							t.Fatal(err)
+						}
+						singleProofsLength += len(proof.Path)
+					}
+
+					multiProof, err := tree.ProveMultiple(indices)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					err = VerifyMultiProof(layout, hashFactory, tree.Root(), provenLeaves, multiProof)
+					if err != nil {
+						t.Fatalf("%s, %d elements: %v", name, numberOfElements, err)
+					}
+
+					// The top-most siblings of several leaves can always be computed from the leaves themselves.
+					if len(indices) > 1 && len(multiProof.Path) >= singleProofsLength {
+						t.Fatalf("%s, %d elements: expected a path shorter than %d bytes, got %d bytes", name,
+							numberOfElements, singleProofsLength, len(multiProof.Path))
+					}
+					if len(indices) == 1 && len(multiProof.Path) != singleProofsLength {
+						t.Fatalf("%s, %d elements: expected a path of %d bytes, got %d bytes", name, numberOfElements,
+							singleProofsLength, len(multiProof.Path))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyMultiProofTampered(t *testing.T) {
+	leaves := getTestLeaves(Sha256HashFactory, 9)
+	tree, err := Build(VectorCommitmentLayout, Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indices := []uint64{2, 5, 8}
+	multiProof, err := tree.ProveMultiple(indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenLeaves := [][]byte{leaves[2], leaves[5], leaves[8]}
+
+	for i := range multiProof.Path {
+		tamperedPath := append([]byte{}, multiProof.Path...)
+		tamperedPath[i] ^= 1
+
+		err = VerifyMultiProof(VectorCommitmentLayout, Sha256HashFactory, tree.Root(), provenLeaves,
+			&MultiProof{Indices: indices, TreeDepth: multiProof.TreeDepth, Path: tamperedPath})
+		if !errors.Is(err, ErrRootMismatch) {
+			t.Fatalf("byte %d of the path: expected %v, got %v", i, ErrRootMismatch, err)
+		}
+	}
+
+	for i := range provenLeaves {
+		tamperedLeaves := append([][]byte{}, provenLeaves...)
+		tamperedLeaves[i] = append([]byte{}, provenLeaves[i]...)
+		tamperedLeaves[i][0] ^= 1
+
+		err = VerifyMultiProof(VectorCommitmentLayout, Sha256HashFactory, tree.Root(), tamperedLeaves, multiProof)
+		if !errors.Is(err, ErrRootMismatch) {
+			t.Fatalf("leaf %d: expected %v, got %v", i, ErrRootMismatch, err)
+		}
+	}
+
+	// Leaves must be ordered as the proof's indices.
+	reorderedLeaves := [][]byte{leaves[5], leaves[2], leaves[8]}
+	err = VerifyMultiProof(VectorCommitmentLayout, Sha256HashFactory, tree.Root(), reorderedLeaves, multiProof)
+	if !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("reordered leaves: expected %v, got %v", ErrRootMismatch, err)
+	}
+}
+
+func TestProveMultipleErrors(t *testing.T) {
+	tree, err := Build(VectorCommitmentLayout, Sha256HashFactory, getTestLeaves(Sha256HashFactory, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		indices     []uint64
+		expectedErr error
+	}{
+		"no indices":        {nil, ErrNoIndicesToProve},
+		"duplicate index":   {[]uint64{1, 3, 1}, ErrDuplicateIndex},
+		"index beyond tree": {[]uint64{0, 5}, ErrIndexOutOfBounds},
+	}
+
+	for name, testCase := range testCases {
+		_, err := tree.ProveMultiple(testCase.indices)
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
+
+func TestComputeRootFromMultiProofErrors(t *testing.T) {
+	leaves := getTestLeaves(Sha256HashFactory, 5)
+	tree, err := Build(VectorCommitmentLayout, Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	multiProof, err := tree.ProveMultiple([]uint64{1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provenLeaves := [][]byte{leaves[1], leaves[3]}
+	withPath := func(path []byte) *MultiProof {
+		return &MultiProof{Indices: multiProof.Indices, TreeDepth: multiProof.TreeDepth, Path: path}
+	}
+
+	testCases := map[string]struct {
+		leaves      [][]byte
+		proof       *MultiProof
+		expectedErr error
+	}{
+		"no indices": {nil, &MultiProof{TreeDepth: multiProof.TreeDepth, Path: multiProof.Path}, ErrNoIndicesToProve},
+		"duplicate index": {provenLeaves, &MultiProof{Indices: []uint64{1, 1}, TreeDepth: multiProof.TreeDepth,
+			Path: multiProof.Path}, ErrDuplicateIndex},
+		"fewer leaves than indices": {provenLeaves[:1], multiProof, ErrLeavesIndicesMismatch},
+		"more leaves than indices":  {append(provenLeaves, leaves[0]), multiProof, ErrLeavesIndicesMismatch},
+		"short leaf":                {[][]byte{leaves[1], leaves[3][:31]}, multiProof, ErrLeafSizeMismatch},
+		"trailing path bytes": {provenLeaves, withPath(append(append([]byte{}, multiProof.Path...), 0)),
+			ErrProofLengthTreeDepthMismatch},
+		"trailing sibling": {provenLeaves, withPath(append(append([]byte{}, multiProof.Path...), leaves[0]...)),
+			ErrProofLengthTreeDepthMismatch},
+		"missing path bytes": {provenLeaves, withPath(multiProof.Path[:len(multiProof.Path)-1]),
+			ErrProofLengthTreeDepthMismatch},
+		"missing sibling": {provenLeaves, withPath(multiProof.Path[:len(multiProof.Path)-32]),
+			ErrProofLengthTreeDepthMismatch},
+	}
+
+	for name, testCase := range testCases {
+		_, err := ComputeRootFromMultiProof(VectorCommitmentLayout, Sha256HashFactory, testCase.leaves, testCase.proof)
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("%s: expected %v, got %v", name, testCase.expectedErr, err)
+		}
+	}
+}
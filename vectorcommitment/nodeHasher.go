@@ -0,0 +1,53 @@
+package vectorcommitment
+
+import (
+	"hash"
+)
+
+// NodeHasher computes internal nodes using a reusable hash.Hash and fixed buffers, so that computing a node does not
+// allocate. Callers computing many roots, such as verifiers, may reuse a NodeHasher in order to compute roots without
+// allocating. A NodeHasher must not be used concurrently.
+type NodeHasher struct {
+	// hasher is reset before computing each node.
+	hasher hash.Hash
+	// nodeData holds the hashed data of the node being computed: "MA" || left child || right child. The domain
+	// separator is copied once, so the package-level MerkleArrayNode is never written to.
+	nodeData []byte
+	// sum holds the node computed last.
+	sum []byte
+}
+
+// NewNodeHasher creates a NodeHasher for the given hash function.
+// Parameters:
+// hashFactory - the hash function used to compute nodes.
+func NewNodeHasher(hashFactory HashFactory) *NodeHasher {
+	hasher := hashFactory()
+	nodeData := make([]byte, len(MerkleArrayNode)+2*hasher.Size())
+	copy(nodeData, MerkleArrayNode)
+
+	return &NodeHasher{
+		hasher:   hasher,
+		nodeData: nodeData,
+		sum:      make([]byte, 0, hasher.Size()),
+	}
+}
+
+// Size returns the length of the nodes computed by the NodeHasher.
+func (h *NodeHasher) Size() int {
+	return h.hasher.Size()
+}
+
+// hashInternalNode computes an internal node, which is of the form Hash("MA" || left child || right child), into
+// sum. Either child may be sum itself, as the children are copied before sum is overwritten.
+// Parameters:
+// leftChild - the hash of the node's left child.
+// rightChild - the hash of the node's right child.
+func (h *NodeHasher) hashInternalNode(leftChild []byte, rightChild []byte) {
+	childrenData := h.nodeData[len(MerkleArrayNode):]
+	copy(childrenData, leftChild)
+	copy(childrenData[h.hasher.Size():], rightChild)
+
+	h.hasher.Reset()
+	h.hasher.Write(h.nodeData)
+	h.sum = h.hasher.Sum(h.sum[:0])
+}
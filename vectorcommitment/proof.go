@@ -0,0 +1,147 @@
+package vectorcommitment
+
+import (
+	"bytes"
+)
+
+// Proof proves that a leaf is committed to by a tree at a given index. Its format matches the proofs returned by an
+// Algorand node, which allows verifying these proofs directly.
+type Proof struct {
+	// Index is the index of the element whose leaf is proven. For trees using VectorCommitmentLayout, this is the
+	// element's index, rather than the index of its leaf.
+	Index uint64
+	// TreeDepth is the length of the path from the leaf to the root.
+	TreeDepth uint64
+	// Path is the concatenation of the hashes of the siblings of each node on the leaf-to-root path, starting from
+	// the leaf's sibling. Missing siblings are replaced with zero bytes.
+	Path []byte
+}
+
+// Prove creates a proof for the element in the given index.
+// Parameters:
+// index - the index of the element to prove.
+func (t *Tree) Prove(index uint64) (*Proof, error) {
+	if index >= t.NumberOfElements {
+		return nil, ErrIndexOutOfBounds
+	}
+
+	depth := t.Depth()
+	position, err := getLeafPosition(t.Layout, index, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	hashSize := t.HashFactory().Size()
+	path := make([]byte, 0, depth*uint64(hashSize))
+	for level := uint64(0); level < depth; level++ {
+		// The sibling of a left child is to its right, and vice versa.
+		siblingPosition := position ^ 1
+		if siblingPosition < uint64(len(t.Levels[level])) {
+			path = append(path, t.Levels[level][siblingPosition]...)
+		} else {
+			path = append(path, make([]byte, hashSize)...)
+		}
+
+		// The parent of a node at the current level is found by dropping the bit that was just used.
+		position >>= 1
+	}
+
+	return &Proof{
+		Index:     index,
+		TreeDepth: depth,
+		Path:      path,
+	}, nil
+}
+
+// LevelFunc is called by ComputeRootWithNodeHasher after computing each node on the leaf-to-root path.
+// Parameters:
+// level - the distance of the computed node's child from the leaf.
+// isRightChild - true if the computed node's child is its right child.
+// sibling - the sibling of the computed node's child, as retrieved from the proof.
+// node - the computed node. It is only valid until the call returns, and must be copied in order to be retained.
+type LevelFunc func(level uint64, isRightChild bool, sibling []byte, node []byte)
+
+// ComputeRoot computes the root of a tree using a leaf and a proof for it. This is done by computing internal nodes
+// using the proof, starting from the leaf, until we reach the root.
+// Parameters:
+// layout - the layout of the tree.
+// hashFactory - the hash function used by the tree.
+// leaf - the leaf to start computing the root from.
+// proof - the proof for the leaf.
+func ComputeRoot(layout Layout, hashFactory HashFactory, leaf []byte, proof *Proof) ([]byte, error) {
+	root, err := ComputeRootWithNodeHasher(layout, NewNodeHasher(hashFactory), leaf, proof, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, root...), nil
+}
+
+// ComputeRootWithNodeHasher computes the root of a tree, see ComputeRoot, using the given NodeHasher. It does not
+// allocate, which makes it suitable for verifiers that compute many roots. The returned root is held by the
+// NodeHasher, so it is only valid until the NodeHasher is used again.
+// Parameters:
+// layout - the layout of the tree.
+// nodeHasher - the NodeHasher of the hash function used by the tree.
+// leaf - the leaf to start computing the root from.
+// proof - the proof for the leaf.
+// onLevel - called after computing each node. May be nil.
+func ComputeRootWithNodeHasher(layout Layout, nodeHasher *NodeHasher, leaf []byte, proof *Proof,
+	onLevel LevelFunc) ([]byte, error) {
+	hashSize := uint64(nodeHasher.Size())
+	if uint64(len(leaf)) != hashSize {
+		return nil, ErrLeafSizeMismatch
+	}
+
+	position, err := getLeafPosition(layout, proof.Index, proof.TreeDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proof must hold exactly TreeDepth node hashes to allow us to compute enough nodes to reach the root.
+	// The depth was bounded above, so the multiplication does not overflow.
+	if proof.TreeDepth*hashSize != uint64(len(proof.Path)) {
+		return nil, ErrProofLengthTreeDepthMismatch
+	}
+
+	// The current node is kept in the NodeHasher's sum, which each computed node overwrites.
+	nodeHasher.sum = append(nodeHasher.sum[:0], leaf...)
+	for level := uint64(0); level < proof.TreeDepth; level++ {
+		sibling := proof.Path[level*hashSize : (level+1)*hashSize]
+
+		// The position's least-significant bit determines whether the current node is a left or a right child.
+		isRightChild := position&1 == 1
+		if isRightChild {
+			nodeHasher.hashInternalNode(sibling, nodeHasher.sum)
+		} else {
+			nodeHasher.hashInternalNode(nodeHasher.sum, sibling)
+		}
+
+		if onLevel != nil {
+			onLevel(level, isRightChild, sibling, nodeHasher.sum)
+		}
+
+		position >>= 1
+	}
+
+	return nodeHasher.sum, nil
+}
+
+// Verify verifies that the given leaf is committed to by the given root, using the given proof.
+// Parameters:
+// layout - the layout of the tree.
+// hashFactory - the hash function used by the tree.
+// root - the root of the tree, as retrieved from a trusted source.
+// leaf - the leaf to verify.
+// proof - the proof for the leaf.
+func Verify(layout Layout, hashFactory HashFactory, root []byte, leaf []byte, proof *Proof) error {
+	computedRoot, err := ComputeRoot(layout, hashFactory, leaf, proof)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(computedRoot, root) {
+		return ErrRootMismatch
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package vectorcommitment
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+var (
+	MerkleArrayNode = []byte("MA")
+)
+
+var (
+	ErrUnknownLayout                = errors.New("unknown tree layout")
+	ErrInvalidTreeDepth             = errors.New("invalid tree depth")
+	ErrIndexDepthMismatch           = errors.New("node index is not smaller than 2^depth")
+	ErrIndexOutOfBounds             = errors.New("element index is not smaller than the number of elements")
+	ErrProofLengthTreeDepthMismatch = errors.New("proof length and tree depth do not match")
+	ErrLeafSizeMismatch             = errors.New("leaf length and hash function output length do not match")
+	ErrRootMismatch                 = errors.New("root mismatch")
+)
+
+// maxTreeDepth is the maximum depth of a tree whose leaves are indexed using uint64 values.
+const maxTreeDepth = 64
+
+// HashFactory creates instances of the hash function used to hash a tree's leaves and internal nodes.
+type HashFactory func() hash.Hash
+
+var (
+	Sha256HashFactory     HashFactory = sha256.New
+	Sha512_256HashFactory HashFactory = sha512.New512_256
+)
+
+// Layout determines how the elements committed to are placed among a tree's leaves, and how a tree is padded.
+type Layout int
+
+const (
+	// MerkleArrayLayout places element i in the i-th leaf. Levels of odd length are not padded - instead, an internal
+	// node without a right child is computed using a right child consisting of zero bytes.
+	MerkleArrayLayout Layout = iota
+	// VectorCommitmentLayout places element i in the leaf whose index is i with its depth bits reversed, so that the
+	// path from the leaf to the root can be derived from the element index by reading it starting from the
+	// most-significant bit. The leaves are padded to the nearest power of 2 using the hash of an empty element.
+	VectorCommitmentLayout
+)
+
+// getTreeDepth returns the length of the path from a leaf to the root in a tree holding the given number of elements.
+// A tree holding either 0 or 1 elements has a depth of 0.
+// Parameters:
+// numberOfElements - the number of elements the tree commits to.
+func getTreeDepth(numberOfElements uint64) uint64 {
+	if numberOfElements == 0 {
+		return 0
+	}
+	return uint64(bits.Len64(numberOfElements - 1))
+}
+
+// reverseBits expresses the index in binary using exactly depth bits, and returns the result of reversing their order.
+// Parameters:
+// index - the index to reverse. It must be smaller than 2 ^ depth.
+// depth - the number of bits to reverse.
+func reverseBits(index uint64, depth uint64) uint64 {
+	if depth == 0 {
+		return 0
+	}
+	return bits.Reverse64(index) >> (maxTreeDepth - depth)
+}
+
+// getLeafPosition maps an element index to the index of the element's leaf in the bottom level of the tree.
+// Parameters:
+// layout - the layout of the tree.
+// index - the element's index.
+// depth - the length of the path from the leaf to the root.
+func getLeafPosition(layout Layout, index uint64, depth uint64) (uint64, error) {
+	if depth > maxTreeDepth {
+		return 0, ErrInvalidTreeDepth
+	}
+
+	// Since each level of the tree halves the number of nodes, a tree of the given depth has at most 2 ^ depth leaves.
+	if depth < maxTreeDepth && index >= 1<<depth {
+		return 0, ErrIndexDepthMismatch
+	}
+
+	switch layout {
+	case MerkleArrayLayout:
+		return index, nil
+	case VectorCommitmentLayout:
+		return reverseBits(index, depth), nil
+	default:
+		return 0, ErrUnknownLayout
+	}
+}
+
+// HashElement computes the leaf committing to an element, which is of the form Hash(prefix || data).
+// Parameters:
+// hashFactory - the hash function used by the tree.
+// prefix - the domain separator of the element's type, such as "TL" for transactions.
+// data - the canonical encoding of the element.
+func HashElement(hashFactory HashFactory, prefix []byte, data []byte) []byte {
+	hasher := hashFactory()
+	hasher.Write(prefix)
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// hashInternalNode computes an internal node, which is of the form Hash("MA" || left child || right child).
+// A missing right child is replaced with zero bytes.
+// Parameters:
+// hashFactory - the hash function used by the tree.
+// leftChild - the hash of the node's left child.
+// rightChild - the hash of the node's right child, or nil if it does not exist.
+func hashInternalNode(hashFactory HashFactory, leftChild []byte, rightChild []byte) []byte {
+	hasher := hashFactory()
+	hasher.Write(MerkleArrayNode)
+	hasher.Write(leftChild)
+	if rightChild == nil {
+		rightChild = make([]byte, hasher.Size())
+	}
+	hasher.Write(rightChild)
+	return hasher.Sum(nil)
+}
+
+// Tree is a tree of hashes, committing to an ordered list of elements using one of Algorand's layouts.
+type Tree struct {
+	// Layout is the layout used to place the elements among the tree's leaves.
+	Layout Layout
+	// HashFactory is the hash function used to hash the tree's internal nodes.
+	HashFactory HashFactory
+	// NumberOfElements is the number of elements the tree commits to.
+	NumberOfElements uint64
+	// Levels holds the tree's nodes, starting from the leaves and ending at the root.
+	Levels [][][]byte
+}
+
+// Build builds a tree committing to the given leaves using the given layout.
+// Parameters:
+// layout - the layout used to place the elements among the tree's leaves.
+// hashFactory - the hash function used to hash the tree's internal nodes. Leaves must be created using the same function.
+// leaves - the leaves committing to each element, in the order of the elements. See HashElement for more details.
+func Build(layout Layout, hashFactory HashFactory, leaves [][]byte) (*Tree, error) {
+	hashSize := hashFactory().Size()
+	for _, leaf := range leaves {
+		if len(leaf) != hashSize {
+			return nil, ErrLeafSizeMismatch
+		}
+	}
+
+	tree := &Tree{
+		Layout:           layout,
+		HashFactory:      hashFactory,
+		NumberOfElements: uint64(len(leaves)),
+	}
+
+	// A tree with no elements has no levels, and its root is empty.
+	if len(leaves) == 0 {
+		return tree, nil
+	}
+
+	depth := getTreeDepth(tree.NumberOfElements)
+	var bottomLevel [][]byte
+	switch layout {
+	case MerkleArrayLayout:
+		bottomLevel = make([][]byte, len(leaves))
+		copy(bottomLevel, leaves)
+	case VectorCommitmentLayout:
+		// The bottom level of a vector commitment always holds 2 ^ depth leaves. Positions that do not hold an
+		// element hold the hash of an empty element.
+		emptyElement := HashElement(hashFactory, nil, nil)
+		bottomLevel = make([][]byte, 1<<depth)
+		for position := range bottomLevel {
+			index := reverseBits(uint64(position), depth)
+			if index < tree.NumberOfElements {
+				bottomLevel[position] = leaves[index]
+			} else {
+				bottomLevel[position] = emptyElement
+			}
+		}
+	default:
+		return nil, ErrUnknownLayout
+	}
+
+	tree.Levels = append(tree.Levels, bottomLevel)
+	for currentLevel := bottomLevel; len(currentLevel) > 1; {
+		nextLevel := make([][]byte, (len(currentLevel)+1)/2)
+		for i := range nextLevel {
+			var rightChild []byte
+			if 2*i+1 < len(currentLevel) {
+				rightChild = currentLevel[2*i+1]
+			}
+			nextLevel[i] = hashInternalNode(hashFactory, currentLevel[2*i], rightChild)
+		}
+
+		tree.Levels = append(tree.Levels, nextLevel)
+		currentLevel = nextLevel
+	}
+
+	return tree, nil
+}
+
+// Root returns the root of the tree. The root of a tree with no elements is empty.
+func (t *Tree) Root() []byte {
+	if len(t.Levels) == 0 {
+		return []byte{}
+	}
+	return t.Levels[len(t.Levels)-1][0]
+}
+
+// Depth returns the length of the path from a leaf to the root.
+func (t *Tree) Depth() uint64 {
+	return getTreeDepth(t.NumberOfElements)
+}
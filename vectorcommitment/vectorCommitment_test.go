@@ -0,0 +1,257 @@
+package vectorcommitment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// proofVector is a proof, along with the root it must compute. Some proofs were recorded from an Algorand node, while
+// others were computed independently of this package. Their source describes where each of them comes from.
+type proofVector struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Layout   string `json:"layout"`
+	HashType string `json:"hash_type"`
+	// NumberOfElements is the number of elements committed to by independently computed vectors, which commit to the
+	// elements created by getTestLeaves. It is 0 for recorded vectors.
+	NumberOfElements int    `json:"number_of_elements,omitempty"`
+	Leaf             []byte `json:"leaf"`
+	Index            uint64 `json:"index"`
+	TreeDepth        uint64 `json:"tree_depth"`
+	Path             []byte `json:"path"`
+	Root             []byte `json:"root"`
+
+	layout      Layout
+	hashFactory HashFactory
+}
+
+var (
+	vectorLayouts       = map[string]Layout{"merkle_array": MerkleArrayLayout, "vector_commitment": VectorCommitmentLayout}
+	vectorHashFactories = map[string]HashFactory{"sha256": Sha256HashFactory, "sha512_256": Sha512_256HashFactory}
+)
+
+func loadProofVectors(t testing.TB) []proofVector {
+	t.Helper()
+
+	encodedVectors, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []proofVector
+	err = json.Unmarshal(encodedVectors, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) == 0 {
+		t.Fatal("no vectors found")
+	}
+
+	for i := range vectors {
+		var layoutFound, hashTypeFound bool
+		vectors[i].layout, layoutFound = vectorLayouts[vectors[i].Layout]
+		vectors[i].hashFactory, hashTypeFound = vectorHashFactories[vectors[i].HashType]
+		if !layoutFound || !hashTypeFound {
+			t.Fatalf("%s: unsupported layout %s or hash type %s", vectors[i].Name, vectors[i].Layout,
+				vectors[i].HashType)
+		}
+	}
+
+	return vectors
+}
+
+func (v proofVector) proof() *Proof {
+	return &Proof{Index: v.Index, TreeDepth: v.TreeDepth, Path: v.Path}
+}
+
+func TestComputeRootVectors(t *testing.T) {
+	for _, vector := range loadProofVectors(t) {
+		t.Run(vector.Name, func(t *testing.T) {
+			root, err := ComputeRoot(vector.layout, vector.hashFactory, vector.Leaf, vector.proof())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(root, vector.Root) {
+				t.Fatalf("root mismatch: computed %x, expected %x", root, vector.Root)
+			}
+
+			err = Verify(vector.layout, vector.hashFactory, vector.Root, vector.Leaf, vector.proof())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tamperedPath := append([]byte{}, vector.Path...)
+			tamperedPath[len(tamperedPath)-1] ^= 1
+			err = Verify(vector.layout, vector.hashFactory, vector.Root, vector.Leaf,
+				&Proof{Index: vector.Index, TreeDepth: vector.TreeDepth, Path: tamperedPath})
+			if !errors.Is(err, ErrRootMismatch) {
+				t.Fatalf("expected %v for a tampered path, got %v", ErrRootMismatch, err)
+			}
+		})
+	}
+}
+
+// TestProveVectors verifies that trees built by this package produce the independently computed vectors.
+func TestProveVectors(t *testing.T) {
+	for _, vector := range loadProofVectors(t) {
+		if vector.NumberOfElements == 0 {
+			continue
+		}
+
+		t.Run(vector.Name, func(t *testing.T) {
+			tree, err := Build(vector.layout, vector.hashFactory, getTestLeaves(vector.hashFactory,
+				vector.NumberOfElements))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(tree.Root(), vector.Root) {
+				t.Fatalf("root mismatch: built %x, expected %x", tree.Root(), vector.Root)
+			}
+
+			proof, err := tree.Prove(vector.Index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.TreeDepth != vector.TreeDepth || !bytes.Equal(proof.Path, vector.Path) {
+				t.Fatalf("proof mismatch: built %+v, expected %+v", proof, vector.proof())
+			}
+		})
+	}
+}
+
+func getTestLeaves(hashFactory HashFactory, numberOfElements int) [][]byte {
+	leaves := make([][]byte, numberOfElements)
+	for i := range leaves {
+		leaves[i] = HashElement(hashFactory, []byte("TE"), []byte(fmt.Sprintf("element %d", i)))
+	}
+	return leaves
+}
+
+func TestProveVerifyRoundTrip(t *testing.T) {
+	hashFactories := map[string]HashFactory{"sha256": Sha256HashFactory, "sha512_256": Sha512_256HashFactory}
+	layouts := map[string]Layout{"merkle array": MerkleArrayLayout, "vector commitment": VectorCommitmentLayout}
+
+	for hashName, hashFactory := range hashFactories {
+		for layoutName, layout := range layouts {
+			for numberOfElements := 1; numberOfElements <= 9; numberOfElements++ {
+				leaves := getTestLeaves(hashFactory, numberOfElements)
+				tree, err := Build(layout, hashFactory, leaves)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for index := range leaves {
+					proof, err := tree.Prove(uint64(index))
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					err = Verify(layout, hashFactory, tree.Root(), leaves[index], proof)
+					if err != nil {
+						t.Fatalf("%s %s, %d elements, index %d: %v", hashName, layoutName, numberOfElements, index, err)
+					}
+
+					// A leaf must not verify at any index other than its own.
+					otherIndex := (uint64(index) + 1) % uint64(numberOfElements)
+					if otherIndex != uint64(index) && !bytes.Equal(leaves[otherIndex], leaves[index]) {
+						err = Verify(layout, hashFactory, tree.Root(), leaves[otherIndex], proof)
+						if !errors.Is(err, ErrRootMismatch) {
+							t.Fatalf("%s %s, %d elements, index %d: expected %v for another leaf, got %v", hashName,
+								layoutName, numberOfElements, index, ErrRootMismatch, err)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBuildLayouts(t *testing.T) {
+	leaves := getTestLeaves(Sha256HashFactory, 3)
+	hashNode := func(leftChild []byte, rightChild []byte) []byte {
+		return HashElement(Sha256HashFactory, MerkleArrayNode, append(append([]byte{}, leftChild...), rightChild...))
+	}
+
+	// A merkle array pads a missing right child with zero bytes.
+	merkleArray, err := Build(MerkleArrayLayout, Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRoot := hashNode(hashNode(leaves[0], leaves[1]), hashNode(leaves[2], make([]byte, 32)))
+	if !bytes.Equal(merkleArray.Root(), expectedRoot) {
+		t.Fatalf("merkle array root mismatch: computed %x, expected %x", merkleArray.Root(), expectedRoot)
+	}
+
+	// A vector commitment places elements at bit-reversed positions, and pads using the hash of an empty element.
+	vectorCommitment, err := Build(VectorCommitmentLayout, Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyElement := HashElement(Sha256HashFactory, nil, nil)
+	expectedRoot = hashNode(hashNode(leaves[0], leaves[2]), hashNode(leaves[1], emptyElement))
+	if !bytes.Equal(vectorCommitment.Root(), expectedRoot) {
+		t.Fatalf("vector commitment root mismatch: computed %x, expected %x", vectorCommitment.Root(), expectedRoot)
+	}
+}
+
+func TestComputeRootErrors(t *testing.T) {
+	leaf := make([]byte, 32)
+	testCases := []struct {
+		name        string
+		layout      Layout
+		leaf        []byte
+		proof       *Proof
+		expectedErr error
+	}{
+		{"short leaf", VectorCommitmentLayout, leaf[:31], &Proof{TreeDepth: 1, Path: make([]byte, 32)}, ErrLeafSizeMismatch},
+		{"depth beyond 64", VectorCommitmentLayout, leaf, &Proof{TreeDepth: 65, Path: make([]byte, 65*32)}, ErrInvalidTreeDepth},
+		{"index beyond depth", VectorCommitmentLayout, leaf, &Proof{Index: 2, TreeDepth: 1, Path: make([]byte, 32)}, ErrIndexDepthMismatch},
+		{"short path", VectorCommitmentLayout, leaf, &Proof{TreeDepth: 2, Path: make([]byte, 63)}, ErrProofLengthTreeDepthMismatch},
+		{"long path", MerkleArrayLayout, leaf, &Proof{TreeDepth: 1, Path: make([]byte, 64)}, ErrProofLengthTreeDepthMismatch},
+		{"unknown layout", Layout(-1), leaf, &Proof{TreeDepth: 1, Path: make([]byte, 32)}, ErrUnknownLayout},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ComputeRoot(testCase.layout, Sha256HashFactory, testCase.leaf, testCase.proof)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("expected %v, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestComputeRootWithNodeHasherReportsLevels(t *testing.T) {
+	leaves := getTestLeaves(Sha256HashFactory, 5)
+	tree, err := Build(VectorCommitmentLayout, Sha256HashFactory, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.Prove(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var levels []uint64
+	var lastNode []byte
+	root, err := ComputeRootWithNodeHasher(VectorCommitmentLayout, NewNodeHasher(Sha256HashFactory), leaves[3], proof,
+		func(level uint64, isRightChild bool, sibling []byte, node []byte) {
+			levels = append(levels, level)
+			lastNode = append([]byte{}, node...)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uint64(len(levels)) != proof.TreeDepth {
+		t.Fatalf("expected %d levels, got %d", proof.TreeDepth, len(levels))
+	}
+	if !bytes.Equal(root, tree.Root()) || !bytes.Equal(lastNode, tree.Root()) {
+		t.Fatalf("root mismatch: computed %x, last node %x, expected %x", root, lastNode, tree.Root())
+	}
+}
@@ -0,0 +1,393 @@
+package verificationqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/algorand/go-stateproof-verification/stateproof"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+// QueueStateVersion is the version of the persisted queue format. It must be incremented whenever the format changes.
+const QueueStateVersion = 1
+
+var (
+	ErrVerificationExpired        = errors.New("no state proof covering the round was ingested before the deadline")
+	ErrUnsupportedQueueVersion    = errors.New("persisted verification queue version is unsupported")
+	ErrDeadlineInPast             = errors.New("deadline has already passed")
+	ErrPendingVerificationsLimit  = errors.New("too many verifications are pending")
+	ErrInvalidExpiryCheckInterval = errors.New("expiry check interval must be positive")
+)
+
+// Outcome is the result of a verification submitted to the Queue.
+type Outcome struct {
+	// ContentHash identifies the verified bundle, see InclusionProofBundle.ContentHash.
+	ContentHash types.Digest
+	Round       types.Round
+	// Err is nil if the bundle's transaction was verified, ErrVerificationExpired if no state proof covering the
+	// round was ingested before the deadline, and the verification error otherwise.
+	Err error
+}
+
+// Config holds the parameters of a Queue.
+type Config struct {
+	// StateFile is the file pending verifications are persisted to, or empty to keep them in memory only.
+	StateFile string
+	// MaxPending is the maximum number of pending verifications, or 0 for no limit.
+	MaxPending int
+	// ExpiryCheckInterval is how often Run expires verifications whose deadline passed. It must be positive.
+	ExpiryCheckInterval time.Duration
+	// OnResolve, if set, is called with the outcome of every verification, including verifications restored from
+	// StateFile, whose submitters are no longer waiting. It is called while the Queue is locked, so it must not call
+	// the Queue's methods.
+	OnResolve func(outcome Outcome)
+}
+
+// DefaultConfig returns the default Queue parameters, keeping up to 10000 pending verifications in memory only, and
+// expiring them once a second.
+func DefaultConfig() Config {
+	return Config{
+		MaxPending:          10000,
+		ExpiryCheckInterval: time.Second,
+	}
+}
+
+// pendingVerification is a bundle waiting for the state proof covering its round.
+type pendingVerification struct {
+	bundle      *inclusionbundle.InclusionProofBundle
+	submittedAt time.Time
+	deadline    time.Time
+	// waiters are the channels of every in-process submitter of the bundle. They are lost on restart.
+	waiters []chan Outcome
+}
+
+// persistedVerification is the persisted form of a pendingVerification.
+type persistedVerification struct {
+	// Bundle is the msgpack encoding of the bundle.
+	Bundle      []byte    `json:"bundle"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	Deadline    time.Time `json:"deadline"`
+}
+
+// persistedQueue is the persisted form of a Queue's pending verifications.
+type persistedQueue struct {
+	Version       uint64                  `json:"version"`
+	Verifications []persistedVerification `json:"verifications"`
+}
+
+// Queue holds inclusion proof bundles for rounds the Oracle has no commitment for yet, and verifies them once the
+// state proof covering their round is ingested. Submitters receive the outcome through a channel, acting as a future,
+// and through Config.OnResolve. Verifications expire if their round is not covered before their deadline.
+// Pending verifications are persisted, so that they survive restarts.
+// The Queue reads the Oracle while locked, so the Oracle must only be advanced using the Queue's AdvanceState while
+// the Queue is in use.
+type Queue struct {
+	mu      sync.Mutex
+	oracle  *oracle.Oracle
+	config  Config
+	pending map[types.Digest]*pendingVerification
+}
+
+// InitializeQueue initializes a Queue using an Oracle, restoring the verifications persisted to Config.StateFile, if
+// it exists. Restored verifications are resolved immediately if the Oracle already covers their round.
+// Parameters:
+// oracleInstance - the Oracle used for verification.
+// config - the Queue's parameters, see DefaultConfig.
+func InitializeQueue(oracleInstance *oracle.Oracle, config Config) (*Queue, error) {
+	// Run would otherwise fail to create its ticker.
+	if config.ExpiryCheckInterval <= 0 {
+		return nil, ErrInvalidExpiryCheckInterval
+	}
+
+	queue := &Queue{
+		oracle:  oracleInstance,
+		config:  config,
+		pending: make(map[types.Digest]*pendingVerification),
+	}
+
+	if config.StateFile == "" {
+		return queue, nil
+	}
+
+	encodedQueue, err := os.ReadFile(config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return queue, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var persisted persistedQueue
+	err = json.Unmarshal(encodedQueue, &persisted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode verification queue %s: %w", config.StateFile, err)
+	}
+	if persisted.Version != QueueStateVersion {
+		return nil, ErrUnsupportedQueueVersion
+	}
+
+	for _, verification := range persisted.Verifications {
+		bundle, err := inclusionbundle.DecodeMsgpack(verification.Bundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode pending bundle: %w", err)
+		}
+
+		queue.pending[bundle.ContentHash()] = &pendingVerification{
+			bundle:      bundle,
+			submittedAt: verification.SubmittedAt,
+			deadline:    verification.Deadline,
+		}
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	// The Oracle may have advanced while the queue was not running.
+	err = queue.resolveCovered()
+	if err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+// isPending returns true if the Oracle has no commitment for the round yet, but may have one in the future.
+// Parameters:
+// round - the round to check.
+func (q *Queue) isPending(round types.Round) bool {
+	history := q.oracle.BlockIntervalCommitmentHistory
+	interval, err := history.GetCoveringInterval(round)
+	return err == nil && interval >= history.NextInterval
+}
+
+// resolve delivers the outcome of a verification to its waiters and to OnResolve, and removes it from the Queue.
+// Parameters:
+// contentHash - the content hash of the verification's bundle.
+// verification - the verification to resolve.
+// verifyErr - the verification's outcome.
+func (q *Queue) resolve(contentHash types.Digest, verification *pendingVerification, verifyErr error) {
+	outcome := Outcome{ContentHash: contentHash, Round: verification.bundle.Round, Err: verifyErr}
+	for _, waiter := range verification.waiters {
+		// Every waiter's channel is buffered, so delivering never blocks.
+		waiter <- outcome
+		close(waiter)
+	}
+
+	if q.config.OnResolve != nil {
+		q.config.OnResolve(outcome)
+	}
+
+	delete(q.pending, contentHash)
+}
+
+// save persists the pending verifications to Config.StateFile. The file is replaced atomically, so that an
+// interrupted save never leaves a corrupted queue behind. The caller must hold the Queue's lock.
+func (q *Queue) save() error {
+	if q.config.StateFile == "" {
+		return nil
+	}
+
+	persisted := persistedQueue{Version: QueueStateVersion, Verifications: []persistedVerification{}}
+	for _, verification := range q.pending {
+		persisted.Verifications = append(persisted.Verifications, persistedVerification{
+			Bundle:      verification.bundle.EncodeMsgpack(),
+			SubmittedAt: verification.submittedAt,
+			Deadline:    verification.deadline,
+		})
+	}
+	// We order the verifications by submission, so that the persisted queue is deterministic.
+	sort.Slice(persisted.Verifications, func(i, j int) bool {
+		return persisted.Verifications[i].SubmittedAt.Before(persisted.Verifications[j].SubmittedAt)
+	})
+
+	encodedQueue, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryFile, err := os.CreateTemp(filepath.Dir(q.config.StateFile), filepath.Base(q.config.StateFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file fails harmlessly once it has been renamed.
+	defer os.Remove(temporaryFile.Name())
+
+	_, err = temporaryFile.Write(encodedQueue)
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	// The verification must be durable before its submitter is told it is pending.
+	err = temporaryFile.Sync()
+	if err != nil {
+		temporaryFile.Close()
+		return err
+	}
+
+	err = temporaryFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryFile.Name(), q.config.StateFile)
+}
+
+// Submit submits a bundle for verification. If the Oracle already has a commitment for the bundle's round, or never
+// will, the bundle is verified immediately. Otherwise, it is verified once the state proof covering its round is
+// ingested, or expires at the deadline. Submitting a bundle that is already pending joins its verification.
+// It returns a channel receiving the verification's outcome, after which it is closed.
+// Parameters:
+// bundle - the bundle to verify.
+// deadline - the time after which the verification expires if the bundle's round is still not covered.
+func (q *Queue) Submit(bundle *inclusionbundle.InclusionProofBundle, deadline time.Time) (<-chan Outcome, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	contentHash := bundle.ContentHash()
+	waiter := make(chan Outcome, 1)
+
+	if !q.isPending(bundle.Round) {
+		waiter <- Outcome{ContentHash: contentHash, Round: bundle.Round, Err: bundle.Verify(q.oracle)}
+		close(waiter)
+		return waiter, nil
+	}
+
+	verification, exists := q.pending[contentHash]
+	if exists {
+		verification.waiters = append(verification.waiters, waiter)
+		return waiter, nil
+	}
+
+	if !deadline.After(time.Now()) {
+		return nil, ErrDeadlineInPast
+	}
+	if q.config.MaxPending > 0 && len(q.pending) >= q.config.MaxPending {
+		return nil, ErrPendingVerificationsLimit
+	}
+
+	q.pending[contentHash] = &pendingVerification{
+		bundle:      bundle,
+		submittedAt: time.Now(),
+		deadline:    deadline,
+		waiters:     []chan Outcome{waiter},
+	}
+
+	err := q.save()
+	if err != nil {
+		delete(q.pending, contentHash)
+		return nil, err
+	}
+
+	return waiter, nil
+}
+
+// resolveCovered verifies every pending bundle whose round the Oracle now covers, or never will. The caller must hold
+// the Queue's lock.
+func (q *Queue) resolveCovered() error {
+	resolved := false
+	for contentHash, verification := range q.pending {
+		if q.isPending(verification.bundle.Round) {
+			continue
+		}
+
+		q.resolve(contentHash, verification, verification.bundle.Verify(q.oracle))
+		resolved = true
+	}
+
+	if !resolved {
+		return nil
+	}
+
+	return q.save()
+}
+
+// AdvanceState advances the Oracle's state, see Oracle.AdvanceState, and verifies every pending bundle whose round
+// the new state proof covers.
+// Parameters:
+// stateProof - the decoded state proof, retrieved using the Algorand SDK.
+// message - the message to which the state proof attests.
+func (q *Queue) AdvanceState(stateProof *stateproof.StateProof, message types.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	err := q.oracle.AdvanceState(stateProof, message)
+	if err != nil {
+		return err
+	}
+
+	return q.resolveCovered()
+}
+
+// Resolve verifies every pending bundle whose round the Oracle now covers. It is meant for callers that advance the
+// Oracle themselves, e.g. from a relayer's OnAdvance callback, in which case they must make sure the Oracle is never
+// advanced while Submit or Resolve run.
+func (q *Queue) Resolve() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.resolveCovered()
+}
+
+// ExpireDue expires every pending verification whose deadline passed.
+// Parameters:
+// now - the current time.
+func (q *Queue) ExpireDue(now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	expired := false
+	for contentHash, verification := range q.pending {
+		if now.Before(verification.deadline) {
+			continue
+		}
+
+		q.resolve(contentHash, verification, ErrVerificationExpired)
+		expired = true
+	}
+
+	if !expired {
+		return nil
+	}
+
+	return q.save()
+}
+
+// Pending returns the number of pending verifications.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+// Run expires verifications whose deadline passed every ExpiryCheckInterval, until the context is done.
+// Parameters:
+// ctx - the context whose cancellation stops expiring verifications. Its error is returned once it is done.
+func (q *Queue) Run(ctx context.Context) error {
+	ticker := time.NewTicker(q.config.ExpiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			err := q.ExpireDue(now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
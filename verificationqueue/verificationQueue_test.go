@@ -0,0 +1,326 @@
+package verificationqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// testFixtures holds an oracle that has not ingested the fixture bundle's state proof yet, along with that state proof
+// and bundles for rounds it covers.
+type testFixtures struct {
+	fixtureBundle *encodedassets.FixtureBundle
+	oracle        *oracle.Oracle
+	// bundle is the fixture bundle's valid transaction case, and tampered is a copy of it that fails verification.
+	bundle   *inclusionbundle.InclusionProofBundle
+	tampered *inclusionbundle.InclusionProofBundle
+}
+
+func loadTestFixtures(t *testing.T) testFixtures {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := testFixtures{
+		fixtureBundle: fixtureBundle,
+		oracle: oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound, fixtureBundle.Network.IntervalSize,
+			fixtureBundle.Genesis.VotersCommitment, fixtureBundle.Genesis.VotersLnProvenWeight, 1000),
+	}
+
+	for _, transactionCase := range fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError != "" {
+			continue
+		}
+
+		fixtures.bundle = inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID,
+			transactionCase.TransactionProofResponse, transactionCase.LightBlockHeaderProofResponse,
+			transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed)
+		tampered := *fixtures.bundle
+		tampered.Seed[0] ^= 1
+		fixtures.tampered = &tampered
+		return fixtures
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return testFixtures{}
+}
+
+// advance ingests the fixture bundle's state proof using the queue.
+func (f testFixtures) advance(t *testing.T, queue *Queue) {
+	t.Helper()
+
+	message, stateProof, err := encodedassets.ParseStateProofResponse(f.fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = queue.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receive returns the outcome delivered to a submitter, failing the test unless it was already delivered.
+func receive(t *testing.T, outcomes <-chan Outcome) Outcome {
+	t.Helper()
+
+	select {
+	case outcome, ok := <-outcomes:
+		if !ok {
+			t.Fatal("expected an outcome, got a closed channel")
+		}
+		return outcome
+	default:
+		t.Fatal("expected an outcome to be delivered")
+		return Outcome{}
+	}
+}
+
+// expectPending fails the test if an outcome was delivered to a submitter.
+func expectPending(t *testing.T, outcomes <-chan Outcome) {
+	t.Helper()
+
+	select {
+	case outcome := <-outcomes:
+		t.Fatalf("expected the verification to be pending, got %+v", outcome)
+	default:
+	}
+}
+
+// mustSubmit submits a bundle, failing the test if submitting fails.
+func mustSubmit(t *testing.T, queue *Queue, bundle *inclusionbundle.InclusionProofBundle,
+	deadline time.Time) <-chan Outcome {
+	t.Helper()
+
+	outcomes, err := queue.Submit(bundle, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outcomes
+}
+
+func TestInitializeQueueRequiresExpiryCheckInterval(t *testing.T) {
+	config := DefaultConfig()
+	config.ExpiryCheckInterval = 0
+
+	_, err := InitializeQueue(loadTestFixtures(t).oracle, config)
+	if !errors.Is(err, ErrInvalidExpiryCheckInterval) {
+		t.Fatalf("expected %v, got %v", ErrInvalidExpiryCheckInterval, err)
+	}
+}
+
+func TestSubmitResolvesOnAdvance(t *testing.T) {
+	fixtures := loadTestFixtures(t)
+	var resolved []Outcome
+	config := DefaultConfig()
+	config.OnResolve = func(outcome Outcome) {
+		resolved = append(resolved, outcome)
+	}
+
+	queue, err := InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	outcomes, err := queue.Submit(fixtures.bundle, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Submitting the same bundle again joins the pending verification.
+	joinedOutcomes, err := queue.Submit(fixtures.bundle, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamperedOutcomes, err := queue.Submit(fixtures.tampered, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPending(t, outcomes)
+	expectPending(t, tamperedOutcomes)
+	if queue.Pending() != 2 {
+		t.Fatalf("expected 2 pending verifications, got %d", queue.Pending())
+	}
+
+	fixtures.advance(t, queue)
+
+	for _, waiter := range []<-chan Outcome{outcomes, joinedOutcomes} {
+		outcome := receive(t, waiter)
+		if outcome.Err != nil || outcome.ContentHash != fixtures.bundle.ContentHash() {
+			t.Fatalf("expected the bundle to verify, got %+v", outcome)
+		}
+	}
+	outcome := receive(t, tamperedOutcomes)
+	if !errors.Is(outcome.Err, transactionverifier.ErrRootMismatch) {
+		t.Fatalf("expected %v, got %v", transactionverifier.ErrRootMismatch, outcome.Err)
+	}
+
+	if queue.Pending() != 0 || len(resolved) != 2 {
+		t.Fatalf("expected 2 resolved and no pending verifications, got %d and %d", len(resolved), queue.Pending())
+	}
+
+	// Bundles of covered rounds are verified immediately.
+	outcome = receive(t, mustSubmit(t, queue, fixtures.bundle, time.Now().Add(time.Hour)))
+	if outcome.Err != nil {
+		t.Fatal(outcome.Err)
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	fixtures := loadTestFixtures(t)
+	config := DefaultConfig()
+	config.MaxPending = 1
+	queue, err := InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = queue.Submit(fixtures.bundle, time.Now().Add(-time.Second))
+	if !errors.Is(err, ErrDeadlineInPast) {
+		t.Fatalf("expected %v, got %v", ErrDeadlineInPast, err)
+	}
+
+	mustSubmit(t, queue, fixtures.bundle, time.Now().Add(time.Hour))
+	_, err = queue.Submit(fixtures.tampered, time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrPendingVerificationsLimit) {
+		t.Fatalf("expected %v, got %v", ErrPendingVerificationsLimit, err)
+	}
+
+	// Bundles of rounds the oracle will never cover are verified, and fail, immediately.
+	tooEarly := *fixtures.bundle
+	tooEarly.Round = 1
+	outcome := receive(t, mustSubmit(t, queue, &tooEarly, time.Now().Add(time.Hour)))
+	if !errors.Is(outcome.Err, oracle.ErrTooEarlyRoundRequested) {
+		t.Fatalf("expected %v, got %v", oracle.ErrTooEarlyRoundRequested, outcome.Err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	fixtures := loadTestFixtures(t)
+	config := DefaultConfig()
+	config.ExpiryCheckInterval = time.Millisecond
+	queue, err := InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	outcomes := mustSubmit(t, queue, fixtures.bundle, deadline)
+
+	err = queue.ExpireDue(deadline.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPending(t, outcomes)
+
+	err = queue.ExpireDue(deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome := receive(t, outcomes)
+	if !errors.Is(outcome.Err, ErrVerificationExpired) {
+		t.Fatalf("expected %v, got %v", ErrVerificationExpired, outcome.Err)
+	}
+
+	// Run expires verifications as their deadline passes.
+	outcomes = mustSubmit(t, queue, fixtures.bundle, time.Now().Add(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- queue.Run(ctx)
+	}()
+
+	select {
+	case outcome = <-outcomes:
+		if !errors.Is(outcome.Err, ErrVerificationExpired) {
+			t.Fatalf("expected %v, got %v", ErrVerificationExpired, outcome.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to expire the verification")
+	}
+
+	cancel()
+	err = <-runErr
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	fixtures := loadTestFixtures(t)
+	config := DefaultConfig()
+	config.StateFile = filepath.Join(t.TempDir(), "queue.json")
+	queue, err := InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustSubmit(t, queue, fixtures.bundle, time.Now().Add(time.Hour))
+	mustSubmit(t, queue, fixtures.tampered, time.Now().Add(time.Hour))
+
+	restoredQueue, err := InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredQueue.Pending() != 2 {
+		t.Fatalf("expected 2 restored verifications, got %d", restoredQueue.Pending())
+	}
+
+	// Restored verifications are resolved immediately if the oracle advanced while the queue was not running.
+	message, stateProof, err := encodedassets.ParseStateProofResponse(fixtures.fixtureBundle.StateProofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fixtures.oracle.AdvanceState(stateProof, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := make(map[bool]int)
+	config.OnResolve = func(outcome Outcome) {
+		resolved[outcome.Err == nil]++
+	}
+	restoredQueue, err = InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredQueue.Pending() != 0 || resolved[true] != 1 || resolved[false] != 1 {
+		t.Fatalf("expected a verified and a failed verification, got %v with %d pending", resolved,
+			restoredQueue.Pending())
+	}
+
+	// Resolving the restored verifications was persisted as well.
+	restoredQueue, err = InitializeQueue(fixtures.oracle, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredQueue.Pending() != 0 {
+		t.Fatalf("expected no restored verifications, got %d", restoredQueue.Pending())
+	}
+}
+
+func TestInitializeQueueRejectsUnsupportedVersion(t *testing.T) {
+	config := DefaultConfig()
+	config.StateFile = filepath.Join(t.TempDir(), "queue.json")
+	err := os.WriteFile(config.StateFile, []byte(`{"version": 2, "verifications": []}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = InitializeQueue(loadTestFixtures(t).oracle, config)
+	if !errors.Is(err, ErrUnsupportedQueueVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedQueueVersion, err)
+	}
+}
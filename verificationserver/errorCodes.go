@@ -0,0 +1,78 @@
+package verificationserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/relayer"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+// ErrorCode is a stable, machine-readable identifier of an error returned by the Server. Error messages may change
+// between versions, but error codes never do, so clients should only ever branch on the code.
+type ErrorCode string
+
+const (
+	MalformedRequest                  ErrorCode = "malformed_request"
+	RequestTooLarge                   ErrorCode = "request_too_large"
+	NotFound                          ErrorCode = "not_found"
+	MethodNotAllowed                  ErrorCode = "method_not_allowed"
+	Unauthorized                      ErrorCode = "unauthorized"
+	StateProofPushDisabled            ErrorCode = "state_proof_push_disabled"
+	AttestationsDisabled              ErrorCode = "attestations_disabled"
+	InternalError                     ErrorCode = "internal_error"
+	UnsupportedBundleVersion          ErrorCode = "unsupported_bundle_version"
+	RoundNotAttested                  ErrorCode = "round_not_attested"
+	RoundNotCovered                   ErrorCode = "round_not_covered"
+	UnsupportedHashFunction           ErrorCode = "unsupported_hash_function"
+	ProofLengthTreeDepthMismatch      ErrorCode = "proof_length_tree_depth_mismatch"
+	InvalidTreeDepth                  ErrorCode = "invalid_tree_depth"
+	IndexDepthMismatch                ErrorCode = "index_depth_mismatch"
+	LightBlockHeaderTreeDepthMismatch ErrorCode = "light_block_header_tree_depth_mismatch"
+	LightBlockHeaderIndexMismatch     ErrorCode = "light_block_header_index_mismatch"
+	RootMismatch                      ErrorCode = "root_mismatch"
+	UnexpectedStateProofInterval      ErrorCode = "unexpected_state_proof_interval"
+	InvalidStateProof                 ErrorCode = "invalid_state_proof"
+)
+
+// errorMapping maps an error returned by the light client's packages to its code and HTTP status.
+type errorMapping struct {
+	err        error
+	code       ErrorCode
+	statusCode int
+}
+
+// errorMappings lists every error the Server reports with a dedicated code. Errors are matched using errors.Is, so
+// wrapped errors are reported with the code of the error they wrap.
+var errorMappings = []errorMapping{
+	{inclusionbundle.ErrUnsupportedBundleVersion, UnsupportedBundleVersion, http.StatusUnprocessableEntity},
+	{oracle.ErrTooEarlyRoundRequested, RoundNotAttested, http.StatusNotFound},
+	{transactionverifier.ErrRoundNotAttested, RoundNotAttested, http.StatusNotFound},
+	{oracle.ErrNoStateProofForRound, RoundNotCovered, http.StatusNotFound},
+	{transactionverifier.ErrUnsupportedHashFunction, UnsupportedHashFunction, http.StatusUnprocessableEntity},
+	{transactionverifier.ErrProofLengthTreeDepthMismatch, ProofLengthTreeDepthMismatch, http.StatusUnprocessableEntity},
+	{transactionverifier.ErrInvalidTreeDepth, InvalidTreeDepth, http.StatusUnprocessableEntity},
+	{transactionverifier.ErrIndexDepthMismatch, IndexDepthMismatch, http.StatusUnprocessableEntity},
+	{transactionverifier.ErrLightBlockHeaderTreeDepthMismatch, LightBlockHeaderTreeDepthMismatch,
+		http.StatusUnprocessableEntity},
+	{transactionverifier.ErrLightBlockHeaderIndexMismatch, LightBlockHeaderIndexMismatch, http.StatusUnprocessableEntity},
+	{transactionverifier.ErrRootMismatch, RootMismatch, http.StatusUnprocessableEntity},
+	{relayer.ErrUnexpectedStateProofInterval, UnexpectedStateProofInterval, http.StatusConflict},
+	{relayer.ErrInvalidStateProof, InvalidStateProof, http.StatusUnprocessableEntity},
+}
+
+// getErrorCode returns the code and HTTP status an error is reported with. Errors without a dedicated code are
+// reported as internal errors.
+// Parameters:
+// err - the error to report.
+func getErrorCode(err error) (ErrorCode, int) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.code, mapping.statusCode
+		}
+	}
+
+	return InternalError, http.StatusInternalServerError
+}
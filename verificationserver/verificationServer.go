@@ -0,0 +1,460 @@
+package verificationserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/attestation"
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+	"github.com/almog-t/light-client-poc/relayer"
+)
+
+// msgpackContentType is the content type of msgpack encoded request bodies. Any other content type is decoded as JSON.
+const msgpackContentType = "application/msgpack"
+
+// Config holds the parameters of a Server.
+type Config struct {
+	// RelayerToken is the bearer token a relayer must present to push state proofs. If empty, pushing state proofs is
+	// disabled, and the oracle's state can only be advanced by others.
+	RelayerToken string
+	// MaxRequestSize is the largest request body accepted, in bytes.
+	MaxRequestSize int64
+	// ShutdownTimeout is the time outstanding requests are given to complete once the Server is shut down.
+	ShutdownTimeout time.Duration
+	// OnAdvance, if set, is called after every state proof pushed and ingested, e.g. to persist the oracle's state.
+	// It is called while the oracle is locked, so the state it observes is exactly the one the state proof produced.
+	OnAdvance func(message types.Message) error
+	// Attester, if set, signs an attestation of every transaction verified, which is returned along with the
+	// verification's outcome.
+	Attester *attestation.Attester
+	// KeySet is the key set attestations are verified against, served for downstream systems to fetch. It must hold
+	// the Attester's key.
+	KeySet *attestation.KeySet
+}
+
+// DefaultConfig returns a Config accepting requests of up to 1MiB, state proofs included, and giving outstanding
+// requests 10 seconds to complete on shutdown.
+func DefaultConfig() Config {
+	return Config{
+		MaxRequestSize:  1 << 20,
+		ShutdownTimeout: 10 * time.Second,
+	}
+}
+
+// Server is an HTTP service answering whether transactions occurred, using a long-lived Oracle. Third parties submit
+// inclusion proof bundles to be verified, and an authenticated relayer pushes state proofs to advance the Oracle.
+// Every response is JSON, and every error carries a stable ErrorCode. The endpoints are:
+// POST /v1/verify - verifies an inclusion proof bundle, encoded using JSON or msgpack.
+// GET /v1/commitments/{round} - returns the block interval commitment attesting to a round.
+// GET /v1/status - describes the rounds covered by the Oracle.
+// POST /v1/stateproofs - advances the Oracle using a state proof response, as returned by algod.
+// GET /v1/attestationkeys - returns the key set attestations are verified against, if attestations are enabled.
+type Server struct {
+	// mu guards the oracle: verifications and queries share it, while state proofs pushed hold it exclusively.
+	mu     sync.RWMutex
+	oracle *oracle.Oracle
+	config Config
+	mux    *http.ServeMux
+}
+
+// InitializeServer initializes a Server using an Oracle.
+// Parameters:
+// oracleInstance - the Oracle used for verification. It must not be modified by others while the Server runs.
+// config - the Server's parameters, see DefaultConfig.
+func InitializeServer(oracleInstance *oracle.Oracle, config Config) *Server {
+	server := &Server{
+		oracle: oracleInstance,
+		config: config,
+		mux:    http.NewServeMux(),
+	}
+
+	server.mux.HandleFunc("/v1/verify", server.handleVerify)
+	server.mux.HandleFunc("/v1/commitments/", server.handleCommitment)
+	server.mux.HandleFunc("/v1/status", server.handleStatus)
+	server.mux.HandleFunc("/v1/stateproofs", server.handleStateProof)
+	server.mux.HandleFunc("/v1/attestationkeys", server.handleAttestationKeys)
+	server.mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		writeError(writer, NotFound, http.StatusNotFound, "unknown endpoint")
+	})
+
+	return server
+}
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error ErrorDetails `json:"error"`
+}
+
+// ErrorDetails describes an error. Code is stable, while Message is meant for humans and may change.
+type ErrorDetails struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// VerifyResponse is the body of a successful response to POST /v1/verify.
+type VerifyResponse struct {
+	Verified bool `json:"verified"`
+	// ContentHash identifies the bundle verified, see InclusionProofBundle.ContentHash.
+	ContentHash     []byte `json:"content_hash"`
+	Round           uint64 `json:"round"`
+	TransactionHash []byte `json:"transaction_hash"`
+	// Attestation is the canonical JSON encoding of the signed attestation of the transaction, if attestations are
+	// enabled, see attestation.SignedAttestation.
+	Attestation json.RawMessage `json:"attestation,omitempty"`
+}
+
+// CommitmentResponse is the body of a successful response to GET /v1/commitments/{round}.
+type CommitmentResponse struct {
+	Round uint64 `json:"round"`
+	// Interval is the index of the interval the round belongs to.
+	Interval uint64 `json:"interval"`
+	// Commitment is the block interval commitment attesting to the round.
+	Commitment []byte `json:"commitment"`
+}
+
+// StatusResponse is the body of a successful response to GET /v1/status, and to POST /v1/stateproofs.
+type StatusResponse struct {
+	FirstAttestedRound uint64 `json:"first_attested_round"`
+	IntervalSize       uint64 `json:"interval_size"`
+	Capacity           uint64 `json:"capacity"`
+	StoredCommitments  uint64 `json:"stored_commitments"`
+	// FirstCoveredRound and LastCoveredRound are the rounds transactions can currently be verified for. Both are 0 if
+	// no commitment is stored.
+	FirstCoveredRound uint64 `json:"first_covered_round"`
+	LastCoveredRound  uint64 `json:"last_covered_round"`
+	// NextRound is the first round of the interval the next state proof must attest to.
+	NextRound uint64 `json:"next_round"`
+}
+
+// writeJSON writes a JSON response.
+// Parameters:
+// writer - the response writer.
+// statusCode - the HTTP status of the response.
+// response - the response's body.
+func writeJSON(writer http.ResponseWriter, statusCode int, response interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	json.NewEncoder(writer).Encode(response)
+}
+
+// writeError writes an error response.
+// Parameters:
+// writer - the response writer.
+// code - the error's stable code.
+// statusCode - the HTTP status of the response.
+// message - a human readable description of the error.
+func writeError(writer http.ResponseWriter, code ErrorCode, statusCode int, message string) {
+	writeJSON(writer, statusCode, ErrorResponse{Error: ErrorDetails{Code: code, Message: message}})
+}
+
+// writeLightClientError writes an error response for an error returned by the light client's packages.
+// Parameters:
+// writer - the response writer.
+// err - the error.
+func writeLightClientError(writer http.ResponseWriter, err error) {
+	code, statusCode := getErrorCode(err)
+	writeError(writer, code, statusCode, err.Error())
+}
+
+// allowMethod writes an error response and returns false if a request does not use the given method.
+// Parameters:
+// writer - the response writer.
+// request - the request.
+// method - the only method the endpoint allows.
+func allowMethod(writer http.ResponseWriter, request *http.Request, method string) bool {
+	if request.Method == method {
+		return true
+	}
+
+	writer.Header().Set("Allow", method)
+	writeError(writer, MethodNotAllowed, http.StatusMethodNotAllowed, fmt.Sprintf("use %s", method))
+	return false
+}
+
+// readBody reads a request's body, writing an error response and returning false if it is too large or unreadable.
+// Parameters:
+// writer - the response writer.
+// request - the request.
+func (s *Server) readBody(writer http.ResponseWriter, request *http.Request) ([]byte, bool) {
+	// Reading a single byte beyond the limit is enough to tell whether the body exceeds it.
+	body, err := io.ReadAll(io.LimitReader(request.Body, s.config.MaxRequestSize+1))
+	if err != nil {
+		writeError(writer, MalformedRequest, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %s", err))
+		return nil, false
+	}
+	if int64(len(body)) > s.config.MaxRequestSize {
+		writeError(writer, RequestTooLarge, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", s.config.MaxRequestSize))
+		return nil, false
+	}
+
+	return body, true
+}
+
+// getRequestFormat returns the encoding format of a request's body, determined by its content type.
+// Parameters:
+// request - the request.
+func getRequestFormat(request *http.Request) encodedassets.EncodingFormat {
+	if strings.HasPrefix(request.Header.Get("Content-Type"), msgpackContentType) {
+		return encodedassets.MsgpackFormat
+	}
+
+	return encodedassets.JSONFormat
+}
+
+func (s *Server) handleVerify(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodPost) {
+		return
+	}
+
+	body, ok := s.readBody(writer, request)
+	if !ok {
+		return
+	}
+
+	var bundle *inclusionbundle.InclusionProofBundle
+	var err error
+	if getRequestFormat(request) == encodedassets.MsgpackFormat {
+		bundle, err = inclusionbundle.DecodeMsgpack(body)
+	} else {
+		bundle, err = inclusionbundle.DecodeJSON(body)
+	}
+	if err != nil {
+		// An unsupported version is reported using its own code, so that clients know to re-encode the bundle.
+		if errors.Is(err, inclusionbundle.ErrUnsupportedBundleVersion) {
+			writeLightClientError(writer, err)
+			return
+		}
+		writeError(writer, MalformedRequest, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Attesting verifies the bundle as well, so we verify it only once.
+	var signedAttestation *attestation.SignedAttestation
+	s.mu.RLock()
+	if s.config.Attester != nil {
+		signedAttestation, err = s.config.Attester.Attest(bundle)
+	} else {
+		err = bundle.Verify(s.oracle)
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		writeLightClientError(writer, err)
+		return
+	}
+
+	contentHash := bundle.ContentHash()
+	response := VerifyResponse{
+		Verified:        true,
+		ContentHash:     contentHash[:],
+		Round:           uint64(bundle.Round),
+		TransactionHash: bundle.TransactionHash[:],
+	}
+	if signedAttestation != nil {
+		response.Attestation = signedAttestation.EncodeJSON()
+	}
+
+	writeJSON(writer, http.StatusOK, response)
+}
+
+func (s *Server) handleAttestationKeys(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	if s.config.KeySet == nil {
+		writeError(writer, AttestationsDisabled, http.StatusNotFound, "attestations are disabled")
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, json.RawMessage(s.config.KeySet.EncodeJSON()))
+}
+
+func (s *Server) handleCommitment(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	roundParameter := strings.TrimPrefix(request.URL.Path, "/v1/commitments/")
+	round, err := strconv.ParseUint(roundParameter, 10, 64)
+	if err != nil {
+		writeError(writer, MalformedRequest, http.StatusBadRequest, fmt.Sprintf("invalid round %q", roundParameter))
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interval, err := s.oracle.BlockIntervalCommitmentHistory.GetCoveringInterval(types.Round(round))
+	if err != nil {
+		writeLightClientError(writer, err)
+		return
+	}
+
+	commitment, err := s.oracle.GetStateProofCommitment(types.Round(round))
+	if err != nil {
+		writeLightClientError(writer, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, CommitmentResponse{Round: round, Interval: interval, Commitment: commitment[:]})
+}
+
+// getStatus describes the oracle's state. The caller must hold the oracle's lock.
+func (s *Server) getStatus() StatusResponse {
+	history := s.oracle.BlockIntervalCommitmentHistory
+	status := StatusResponse{
+		FirstAttestedRound: history.FirstAttestedRound,
+		IntervalSize:       history.IntervalSize,
+		Capacity:           history.Capacity,
+		StoredCommitments:  uint64(len(history.Data)),
+		NextRound:          history.FirstAttestedRound + history.NextInterval*history.IntervalSize,
+	}
+
+	if history.NextInterval > history.EarliestInterval {
+		status.FirstCoveredRound = history.FirstAttestedRound + history.EarliestInterval*history.IntervalSize
+		status.LastCoveredRound = status.NextRound - 1
+	}
+
+	return status
+}
+
+func (s *Server) handleStatus(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	s.mu.RLock()
+	status := s.getStatus()
+	s.mu.RUnlock()
+
+	writeJSON(writer, http.StatusOK, status)
+}
+
+// authenticateRelayer writes an error response and returns false if a request does not carry the relayer's token.
+// Parameters:
+// writer - the response writer.
+// request - the request.
+func (s *Server) authenticateRelayer(writer http.ResponseWriter, request *http.Request) bool {
+	if s.config.RelayerToken == "" {
+		writeError(writer, StateProofPushDisabled, http.StatusForbidden, "pushing state proofs is disabled")
+		return false
+	}
+
+	// We compare in constant time, so that response times reveal nothing about the token.
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.RelayerToken)) != 1 {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(writer, Unauthorized, http.StatusUnauthorized, "missing or invalid relayer token")
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) handleStateProof(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodPost) || !s.authenticateRelayer(writer, request) {
+		return
+	}
+
+	body, ok := s.readBody(writer, request)
+	if !ok {
+		return
+	}
+
+	var stateProofResponse models.StateProof
+	err := encodedassets.DecodeResponse(body, getRequestFormat(request), &stateProofResponse)
+	if err != nil {
+		writeError(writer, MalformedRequest, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, err := relayer.IngestStateProof(s.oracle, stateProofResponse)
+	if err != nil {
+		writeLightClientError(writer, err)
+		return
+	}
+
+	if s.config.OnAdvance != nil {
+		err = s.config.OnAdvance(message)
+		if err != nil {
+			writeError(writer, InternalError, http.StatusInternalServerError,
+				fmt.Sprintf("state proof was ingested, but OnAdvance failed: %s", err))
+			return
+		}
+	}
+
+	writeJSON(writer, http.StatusOK, s.getStatus())
+}
+
+// ServeHTTP routes a request to its endpoint.
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.mux.ServeHTTP(writer, request)
+}
+
+// Serve serves requests on a listener until the context is done, then shuts down gracefully: the listener is closed,
+// and outstanding requests are given ShutdownTimeout to complete. It returns nil once shut down gracefully.
+// Parameters:
+// ctx - the context whose cancellation shuts the Server down.
+// listener - the listener to accept connections on. It is closed by Serve.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{Handler: s}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(shutdownCtx)
+	if err != nil {
+		return err
+	}
+
+	// Serve returns http.ErrServerClosed as soon as Shutdown is called, which is exactly what we expect here.
+	err = <-serveErr
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// ListenAndServe listens on a TCP address and serves requests until the context is done, see Serve.
+// Parameters:
+// ctx - the context whose cancellation shuts the Server down.
+// address - the TCP address to listen on, e.g. 127.0.0.1:8080.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
@@ -0,0 +1,388 @@
+package verificationserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/client/v2/common/models"
+	sdkjson "github.com/algorand/go-algorand-sdk/encoding/json"
+	"github.com/algorand/go-algorand-sdk/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/types"
+
+	"github.com/almog-t/light-client-poc/attestation"
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/oracle"
+)
+
+const testRelayerToken = "relayer-token"
+
+// testServer is a Server along with the fixture bundle it verifies transactions of.
+type testServer struct {
+	*Server
+	fixtureBundle *encodedassets.FixtureBundle
+}
+
+// initializeTestServer initializes a Server using an Oracle initialized from the fixture bundle's genesis data, which
+// has not ingested any state proof yet.
+func initializeTestServer(t *testing.T, config Config) testServer {
+	t.Helper()
+
+	fixtureBundle, err := encodedassets.LoadFixtureBundle(os.DirFS("../encodedassets/fixturebundle"), "fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracleInstance := oracle.InitializeOracle(fixtureBundle.Network.FirstAttestedRound,
+		fixtureBundle.Network.IntervalSize, fixtureBundle.Genesis.VotersCommitment,
+		fixtureBundle.Genesis.VotersLnProvenWeight, 1000)
+	return testServer{Server: InitializeServer(oracleInstance, config), fixtureBundle: fixtureBundle}
+}
+
+// getTestConfig returns the DefaultConfig, with pushing state proofs enabled.
+func getTestConfig() Config {
+	config := DefaultConfig()
+	config.RelayerToken = testRelayerToken
+	return config
+}
+
+// serve serves a request, and returns the response.
+func (s testServer) serve(method string, path string, contentType string, authorization string,
+	body []byte) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// pushStateProof pushes a state proof response as the relayer would.
+func (s testServer) pushStateProof(stateProofResponse models.StateProof) *httptest.ResponseRecorder {
+	return s.serve(http.MethodPost, "/v1/stateproofs", "application/json", "Bearer "+testRelayerToken,
+		sdkjson.Encode(stateProofResponse))
+}
+
+// getBundle returns an inclusion proof bundle of the fixture bundle's valid transaction case.
+func (s testServer) getBundle(t *testing.T) *inclusionbundle.InclusionProofBundle {
+	t.Helper()
+
+	for _, transactionCase := range s.fixtureBundle.TransactionCases {
+		if transactionCase.ExpectedError == "" {
+			return inclusionbundle.InitializeInclusionProofBundle(transactionCase.TransactionID,
+				transactionCase.TransactionProofResponse, transactionCase.LightBlockHeaderProofResponse,
+				transactionCase.Round, transactionCase.GenesisHash, transactionCase.Seed)
+		}
+	}
+
+	t.Fatal("fixture bundle holds no valid transaction case")
+	return nil
+}
+
+// expectError fails the test unless a response is an error response with the given code and HTTP status.
+func expectError(t *testing.T, recorder *httptest.ResponseRecorder, expectedCode ErrorCode, expectedStatusCode int) {
+	t.Helper()
+
+	var response ErrorResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("failed to decode error response %q: %v", recorder.Body.String(), err)
+	}
+	if response.Error.Code != expectedCode || recorder.Code != expectedStatusCode {
+		t.Fatalf("expected %s (%d), got %s (%d): %s", expectedCode, expectedStatusCode, response.Error.Code,
+			recorder.Code, response.Error.Message)
+	}
+}
+
+// decodeResponse fails the test unless a response succeeded, and decodes its body.
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder, response interface{}) {
+	t.Helper()
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	err := json.Unmarshal(recorder.Body.Bytes(), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateProofIngestion(t *testing.T) {
+	var advancedMessages []types.Message
+	config := getTestConfig()
+	config.OnAdvance = func(message types.Message) error {
+		advancedMessages = append(advancedMessages, message)
+		return nil
+	}
+	server := initializeTestServer(t, config)
+	network := server.fixtureBundle.Network
+
+	var status StatusResponse
+	decodeResponse(t, server.serve(http.MethodGet, "/v1/status", "", "", nil), &status)
+	if status.NextRound != network.FirstAttestedRound || status.StoredCommitments != 0 || status.LastCoveredRound != 0 {
+		t.Fatalf("unexpected status before ingestion: %+v", status)
+	}
+
+	decodeResponse(t, server.pushStateProof(server.fixtureBundle.StateProofs[0]), &status)
+	expectedStatus := StatusResponse{
+		FirstAttestedRound: network.FirstAttestedRound,
+		IntervalSize:       network.IntervalSize,
+		Capacity:           1000,
+		StoredCommitments:  1,
+		FirstCoveredRound:  network.FirstAttestedRound,
+		LastCoveredRound:   network.FirstAttestedRound + network.IntervalSize - 1,
+		NextRound:          network.FirstAttestedRound + network.IntervalSize,
+	}
+	if status != expectedStatus || len(advancedMessages) != 1 {
+		t.Fatalf("expected status %+v after a single advance, got %+v after %d", expectedStatus, status,
+			len(advancedMessages))
+	}
+
+	// The state proof no longer attests to the next expected interval.
+	expectError(t, server.pushStateProof(server.fixtureBundle.StateProofs[0]), UnexpectedStateProofInterval,
+		http.StatusConflict)
+
+	var commitment CommitmentResponse
+	decodeResponse(t, server.serve(http.MethodGet, "/v1/commitments/10", "", "", nil), &commitment)
+	expectedCommitment := server.fixtureBundle.StateProofs[0].Message.Blockheaderscommitment
+	if commitment.Interval != 0 || !bytes.Equal(commitment.Commitment, expectedCommitment) {
+		t.Fatalf("unexpected commitment: %+v", commitment)
+	}
+}
+
+func TestStateProofIngestionUsingMsgpack(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+
+	recorder := server.serve(http.MethodPost, "/v1/stateproofs", msgpackContentType, "Bearer "+testRelayerToken,
+		msgpack.Encode(server.fixtureBundle.StateProofs[0]))
+	var status StatusResponse
+	decodeResponse(t, recorder, &status)
+	if status.StoredCommitments != 1 {
+		t.Fatalf("expected a single stored commitment, got %d", status.StoredCommitments)
+	}
+}
+
+func TestStateProofErrors(t *testing.T) {
+	fixtureBundle := initializeTestServer(t, getTestConfig()).fixtureBundle
+	tampered := fixtureBundle.StateProofs[0]
+	tampered.Message.Blockheaderscommitment = append([]byte{}, tampered.Message.Blockheaderscommitment...)
+	tampered.Message.Blockheaderscommitment[0] ^= 1
+	encodedStateProof := sdkjson.Encode(fixtureBundle.StateProofs[0])
+
+	testCases := map[string]struct {
+		config             func(config *Config)
+		method             string
+		authorization      string
+		body               []byte
+		expectedCode       ErrorCode
+		expectedStatusCode int
+	}{
+		"push disabled": {func(config *Config) { config.RelayerToken = "" }, http.MethodPost,
+			"Bearer " + testRelayerToken, encodedStateProof, StateProofPushDisabled, http.StatusForbidden},
+		"missing token": {nil, http.MethodPost, "", encodedStateProof, Unauthorized, http.StatusUnauthorized},
+		"invalid token": {nil, http.MethodPost, "Bearer " + testRelayerToken + "x", encodedStateProof, Unauthorized,
+			http.StatusUnauthorized},
+		"token without scheme": {nil, http.MethodPost, testRelayerToken + "x", encodedStateProof, Unauthorized,
+			http.StatusUnauthorized},
+		"wrong method": {nil, http.MethodGet, "Bearer " + testRelayerToken, nil, MethodNotAllowed,
+			http.StatusMethodNotAllowed},
+		"malformed body": {nil, http.MethodPost, "Bearer " + testRelayerToken, []byte("{"), MalformedRequest,
+			http.StatusBadRequest},
+		"oversized body": {func(config *Config) { config.MaxRequestSize = 16 }, http.MethodPost,
+			"Bearer " + testRelayerToken, encodedStateProof, RequestTooLarge, http.StatusRequestEntityTooLarge},
+		"body at the size limit": {func(config *Config) { config.MaxRequestSize = 1 }, http.MethodPost,
+			"Bearer " + testRelayerToken, []byte("{"), MalformedRequest, http.StatusBadRequest},
+		"body a byte over the size limit": {func(config *Config) { config.MaxRequestSize = 1 }, http.MethodPost,
+			"Bearer " + testRelayerToken, []byte("{}"), RequestTooLarge, http.StatusRequestEntityTooLarge},
+		"invalid state proof": {nil, http.MethodPost, "Bearer " + testRelayerToken, sdkjson.Encode(tampered),
+			InvalidStateProof, http.StatusUnprocessableEntity},
+		"on advance failed": {func(config *Config) {
+			config.OnAdvance = func(message types.Message) error { return errors.New("disk full") }
+		}, http.MethodPost, "Bearer " + testRelayerToken, encodedStateProof, InternalError,
+			http.StatusInternalServerError},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config := getTestConfig()
+			if testCase.config != nil {
+				testCase.config(&config)
+			}
+			server := initializeTestServer(t, config)
+
+			recorder := server.serve(testCase.method, "/v1/stateproofs", "application/json", testCase.authorization,
+				testCase.body)
+			expectError(t, recorder, testCase.expectedCode, testCase.expectedStatusCode)
+		})
+	}
+}
+
+// failingReader is a request body whose reading fails.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestUnreadableBodyIsMalformed(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/verify", failingReader{})
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	expectError(t, recorder, MalformedRequest, http.StatusBadRequest)
+}
+
+func TestVerify(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+	bundle := server.getBundle(t)
+
+	// The oracle holds no commitment for the bundle's round yet.
+	recorder := server.serve(http.MethodPost, "/v1/verify", "application/json", "", bundle.EncodeJSON())
+	expectError(t, recorder, RoundNotCovered, http.StatusNotFound)
+
+	decodeResponse(t, server.pushStateProof(server.fixtureBundle.StateProofs[0]), &StatusResponse{})
+
+	contentHash := bundle.ContentHash()
+	for contentType, encodedBundle := range map[string][]byte{"application/json": bundle.EncodeJSON(),
+		msgpackContentType: bundle.EncodeMsgpack()} {
+		var response VerifyResponse
+		decodeResponse(t, server.serve(http.MethodPost, "/v1/verify", contentType, "", encodedBundle), &response)
+		if !response.Verified || !bytes.Equal(response.ContentHash, contentHash[:]) ||
+			response.Round != uint64(bundle.Round) || response.Attestation != nil {
+			t.Fatalf("%s: unexpected response %+v", contentType, response)
+		}
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+	decodeResponse(t, server.pushStateProof(server.fixtureBundle.StateProofs[0]), &StatusResponse{})
+
+	tampered := *server.getBundle(t)
+	tampered.Seed[0] ^= 1
+	unsupportedVersion := *server.getBundle(t)
+	unsupportedVersion.Version++
+	tooEarly := *server.getBundle(t)
+	tooEarly.Round = 1
+
+	testCases := map[string]struct {
+		method             string
+		body               []byte
+		expectedCode       ErrorCode
+		expectedStatusCode int
+	}{
+		"wrong method":       {http.MethodGet, nil, MethodNotAllowed, http.StatusMethodNotAllowed},
+		"malformed body":     {http.MethodPost, []byte("{"), MalformedRequest, http.StatusBadRequest},
+		"tampered bundle":    {http.MethodPost, tampered.EncodeJSON(), RootMismatch, http.StatusUnprocessableEntity},
+		"round not attested": {http.MethodPost, tooEarly.EncodeJSON(), RoundNotAttested, http.StatusNotFound},
+		"unsupported version": {http.MethodPost, unsupportedVersion.EncodeJSON(), UnsupportedBundleVersion,
+			http.StatusUnprocessableEntity},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			recorder := server.serve(testCase.method, "/v1/verify", "application/json", "", testCase.body)
+			expectError(t, recorder, testCase.expectedCode, testCase.expectedStatusCode)
+		})
+	}
+
+	expectError(t, server.serve(http.MethodGet, "/v1/commitments/round", "", "", nil), MalformedRequest,
+		http.StatusBadRequest)
+	expectError(t, server.serve(http.MethodGet, "/v1/commitments/17", "", "", nil), RoundNotCovered,
+		http.StatusNotFound)
+	expectError(t, server.serve(http.MethodGet, "/v1/unknown", "", "", nil), NotFound, http.StatusNotFound)
+}
+
+func TestVerifyWithAttestations(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+	expectError(t, server.serve(http.MethodGet, "/v1/attestationkeys", "", "", nil), AttestationsDisabled,
+		http.StatusNotFound)
+
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attester := attestation.InitializeAttester(server.oracle, signingKey)
+	server.config.Attester = attester
+	server.config.KeySet = &attestation.KeySet{
+		Version: attestation.KeySetVersion,
+		Keys:    []attestation.PublishedKey{{KeyID: attester.KeyID(), PublicKey: attester.PublicKey()}},
+	}
+	decodeResponse(t, server.pushStateProof(server.fixtureBundle.StateProofs[0]), &StatusResponse{})
+
+	var response VerifyResponse
+	decodeResponse(t, server.serve(http.MethodPost, "/v1/verify", "application/json", "",
+		server.getBundle(t).EncodeJSON()), &response)
+	signedAttestation, err := attestation.DecodeJSON(response.Attestation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Downstream systems verify the attestation using the published key set.
+	recorder := server.serve(http.MethodGet, "/v1/attestationkeys", "", "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	keySet, err := attestation.DecodeKeySet(bytes.TrimSpace(recorder.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = attestation.VerifyAttestation(signedAttestation, keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	server := initializeTestServer(t, getTestConfig())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, listener)
+	}()
+
+	response, err := http.Get("http://" + listener.Addr().String() + "/v1/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err = <-serveErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after its context was done")
+	}
+
+	_, err = http.Get("http://" + listener.Addr().String() + "/v1/status")
+	if err == nil {
+		t.Fatalf("expected the listener to be closed, got %v", err)
+	}
+}
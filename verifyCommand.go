@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/almog-t/light-client-poc/encodedassets"
+	"github.com/almog-t/light-client-poc/inclusionbundle"
+	"github.com/almog-t/light-client-poc/nullifierstore"
+	"github.com/almog-t/light-client-poc/transactionverifier"
+)
+
+var (
+	errNoBundles          = errors.New("no inclusion proof bundles given, use -fixtures or pass bundle files")
+	errVerificationFailed = errors.New("at least one verification did not produce its expected outcome")
+)
+
+// verification describes the outcome of verifying a single inclusion proof bundle or fixture transaction case.
+type verification struct {
+	Source string `json:"source"`
+	// ContentHash identifies an inclusion proof bundle. It is empty for fixture transaction cases.
+	ContentHash []byte `json:"content_hash,omitempty"`
+	Round       uint64 `json:"round"`
+	// Error is the error verification produced, or empty if verification succeeded.
+	Error string `json:"error,omitempty"`
+	// ExpectedError is the name of the error a fixture transaction case must produce, or empty if it must verify.
+	ExpectedError string `json:"expected_error,omitempty"`
+	// Passed is true if verification produced its expected outcome: success for inclusion proof bundles, and the
+	// expected error for fixture transaction cases.
+	Passed bool `json:"passed"`
+	// Trace holds the verification's steps for fixture transaction cases that did not pass.
+	Trace *transactionverifier.Tracer `json:"trace,omitempty"`
+}
+
+// verifyResult describes every verification performed by the verify subcommand.
+type verifyResult struct {
+	Verifications []verification `json:"verifications"`
+	Passed        bool           `json:"passed"`
+}
+
+func (r *verifyResult) writeText(output io.Writer) {
+	for _, verification := range r.Verifications {
+		outcome := "verified"
+		if verification.Error != "" {
+			outcome = "failed: " + verification.Error
+		}
+
+		if verification.Passed {
+			fmt.Fprintf(output, "PASS %s (round %d): %s\n", verification.Source, verification.Round, outcome)
+		} else {
+			fmt.Fprintf(output, "FAIL %s (round %d): %s\n", verification.Source, verification.Round, outcome)
+		}
+
+		if verification.Trace != nil {
+			fmt.Fprint(output, verification.Trace)
+		}
+	}
+}
+
+// readInclusionProofBundle reads an inclusion proof bundle, whose format is determined by its file extension.
+// Parameters:
+// bundleFile - the path of the inclusion proof bundle.
+func readInclusionProofBundle(bundleFile string) (*inclusionbundle.InclusionProofBundle, error) {
+	format, err := encodedassets.GetEncodingFormat(bundleFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inclusion proof bundle %s: %w", bundleFile, err)
+	}
+
+	encodedBundle, err := os.ReadFile(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle *inclusionbundle.InclusionProofBundle
+	switch format {
+	case encodedassets.MsgpackFormat:
+		bundle, err = inclusionbundle.DecodeMsgpack(encodedBundle)
+	default:
+		bundle, err = inclusionbundle.DecodeJSON(encodedBundle)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode inclusion proof bundle %s: %w", bundleFile, err)
+	}
+
+	return bundle, nil
+}
+
+// setupVerifyCommand sets up the verify subcommand, which verifies the transaction cases in a fixture bundle and the
+// inclusion proof bundles given as arguments using the oracle's state. The oracle's state is never modified. With
+// -nullifiers, the bundles' transactions are consumed as well, so that verifying any of them again fails.
+func setupVerifyCommand(flags *flag.FlagSet, config *cliConfig) func(arguments []string) (commandResult, error) {
+	flags.StringVar(&config.FixturesFile, "fixtures", "", "path of a fixture bundle whose transaction cases to verify")
+	flags.StringVar(&config.NullifiersFile, "nullifiers", "",
+		"path of a file recording consumed transactions, to consume the bundles' transactions in")
+
+	return func(arguments []string) (commandResult, error) {
+		if config.FixturesFile == "" && len(arguments) == 0 {
+			return nil, errNoBundles
+		}
+
+		oracleInstance, err := loadOracle(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var nullifierStore *nullifierstore.NullifierStore
+		if config.NullifiersFile != "" {
+			nullifierConfig := nullifierstore.DefaultConfig()
+			nullifierConfig.StateFile = config.NullifiersFile
+			nullifierStore, err = nullifierstore.InitializeNullifierStore(oracleInstance, nullifierConfig)
+			if err != nil {
+				return nil, err
+			}
+
+			// We prune before consuming, so that the store does not grow indefinitely as the oracle advances.
+			_, err = nullifierStore.Prune()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result := &verifyResult{Passed: true}
+		if config.FixturesFile != "" {
+			fixtureBundle, err := loadFixtureBundle(config.FixturesFile)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, fixtureCase := range fixtureBundle.TransactionCases {
+				tracer := &transactionverifier.Tracer{}
+				verifyErr := verifyFixtureCase(oracleInstance, fixtureCase, tracer)
+				passed, err := matchesExpectedError(verifyErr, fixtureCase.ExpectedError)
+				if err != nil {
+					return nil, fmt.Errorf("%s#%s: %w", config.FixturesFile, fixtureCase.Name, err)
+				}
+
+				caseVerification := verification{
+					Source:        fmt.Sprintf("%s#%s", config.FixturesFile, fixtureCase.Name),
+					Round:         uint64(fixtureCase.Round),
+					ExpectedError: fixtureCase.ExpectedError,
+					Passed:        passed,
+				}
+				if verifyErr != nil {
+					caseVerification.Error = verifyErr.Error()
+				}
+				// We attach the trace of unexpected outcomes, so that their intermediate hashes can be inspected.
+				if !caseVerification.Passed {
+					caseVerification.Trace = tracer
+				}
+
+				result.Verifications = append(result.Verifications, caseVerification)
+			}
+		}
+
+		for _, bundleFile := range arguments {
+			bundle, err := readInclusionProofBundle(bundleFile)
+			if err != nil {
+				return nil, err
+			}
+
+			var verifyErr error
+			if nullifierStore != nil {
+				verifyErr = nullifierStore.VerifyAndConsume(bundle)
+			} else {
+				verifyErr = bundle.Verify(oracleInstance)
+			}
+
+			contentHash := bundle.ContentHash()
+			bundleVerification := verification{
+				Source:      bundleFile,
+				ContentHash: contentHash[:],
+				Round:       uint64(bundle.Round),
+				Passed:      verifyErr == nil,
+			}
+			if verifyErr != nil {
+				bundleVerification.Error = verifyErr.Error()
+			}
+
+			result.Verifications = append(result.Verifications, bundleVerification)
+		}
+
+		for _, verification := range result.Verifications {
+			if !verification.Passed {
+				result.Passed = false
+				return result, errVerificationFailed
+			}
+		}
+
+		return result, nil
+	}
+}